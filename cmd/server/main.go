@@ -1,14 +1,22 @@
 package main
 
 import (
+	"fmt"
 	"log/slog"
+	"net"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	bolt "go.etcd.io/bbolt"
 
 	"github.com/phasecurve/sway_rm/internal/api"
+	"github.com/phasecurve/sway_rm/internal/input"
+	"github.com/phasecurve/sway_rm/internal/mpv"
+	"github.com/phasecurve/sway_rm/internal/publish"
 	"github.com/phasecurve/sway_rm/internal/security"
+	"github.com/phasecurve/sway_rm/internal/sway"
 )
 
 func main() {
@@ -20,19 +28,144 @@ func main() {
 		slogger.Error("failed to open database", "error", err)
 		os.Exit(1)
 	}
-	keyStore := security.NewKeyStore(db)
-	scg := security.GenerateShortCode
+	keyStore, err := security.NewKeyStore(db,
+		security.WithSecretFile("apikeys.secret"),
+		security.WithLogger(logger),
+	)
+	if err != nil {
+		slogger.Error("failed to open key store", "error", err)
+		os.Exit(1)
+	}
+	keyStore.Start()
+	defer keyStore.Close()
+	publishers, err := publish.Parse(publish.Spec(), os.Stdout)
+	if err != nil {
+		slogger.Error("invalid pairing code publishers", "error", err)
+		os.Exit(1)
+	}
+	pairingScopes, err := security.ParseScopes(os.Getenv("PAIRING_SCOPES"))
+	if err != nil {
+		slogger.Error("invalid pairing scopes", "error", err)
+		os.Exit(1)
+	}
+	lifetimes, err := sessionLifetimes()
+	if err != nil {
+		slogger.Error("invalid session lifetimes", "error", err)
+		os.Exit(1)
+	}
+	codeLength, err := pairingCodeLength()
+	if err != nil {
+		slogger.Error("invalid pairing code length", "error", err)
+		os.Exit(1)
+	}
+	scg := security.NewShortCodeGenerator(codeLength)
 	acg := security.GenerateAPIKey
+	swayClient := sway.NewClient(sway.SocketPath())
+	defer swayClient.Close()
+	swayEvents := sway.NewEventStream(sway.SocketPath(), logger)
+	swayEvents.Start()
+	defer swayEvents.Close()
+	mpvRegistry := mpv.NewRegistry(mpv.SocketPath(), mpv.SocketGlob())
+	mpvRegistry.Start()
+	defer mpvRegistry.Close()
+	nowPlaying := mpv.NewTracker(mpvRegistry)
+	nowPlaying.Start()
+	defer nowPlaying.Close()
 
-	server := api.NewServer(
+	options := []api.ServerOption{
 		api.WithKeyStore(keyStore),
 		api.WithShortCodeGenerator(scg),
 		api.WithAPICodeGenerator(acg),
-		api.WithOutput(os.Stdout),
+		api.WithPairingURL(pairingURL("8080")),
+		api.WithLocalURL("http://localhost:8080"),
+		api.WithPairingScopes(pairingScopes...),
+		api.WithSessionLifetimes(lifetimes),
+		api.WithSway(swayClient),
+		api.WithSwayEvents(swayEvents),
+		api.WithMPV(mpvRegistry),
+		api.WithMPVInstances(mpvRegistry),
+		api.WithNowPlaying(nowPlaying),
+		api.WithPublishers(publishers...),
 		api.WithLogger(logger),
-	)
+	}
+
+	injector, err := input.NewUInputInjector()
+	if err != nil {
+		slogger.Warn("virtual input disabled", "error", err)
+	} else {
+		defer injector.Close()
+		options = append(options, api.WithInput(injector))
+	}
+
+	server := api.NewServer(options...)
 
 	r := gin.Default()
+	if err := r.SetTrustedProxies(nil); err != nil {
+		slogger.Error("failed to configure trusted proxies", "error", err)
+		os.Exit(1)
+	}
 	server.SetupRoutes(r)
 	r.Run("0.0.0.0:8080")
 }
+
+// pairingURL is the address put in QR pairing links: $PAIRING_URL if set,
+// otherwise the first private IPv4 address of this machine.
+func pairingURL(port string) string {
+	if url := os.Getenv("PAIRING_URL"); url != "" {
+		return url
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return ""
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.To4() == nil || !ipNet.IP.IsPrivate() {
+			continue
+		}
+		return "http://" + net.JoinHostPort(ipNet.IP.String(), port)
+	}
+	return ""
+}
+
+// pairingCodeLength reads PAIRING_CODE_LENGTH, defaulting to
+// security.DefaultShortCodeLength. Codes shorter than 4 characters are too
+// easy to guess.
+func pairingCodeLength() (int, error) {
+	raw := os.Getenv("PAIRING_CODE_LENGTH")
+	if raw == "" {
+		return security.DefaultShortCodeLength, nil
+	}
+	length, err := strconv.Atoi(raw)
+	if err != nil || length < 4 || length > 32 {
+		return 0, fmt.Errorf("PAIRING_CODE_LENGTH must be a number from 4 to 32, got %q", raw)
+	}
+	return length, nil
+}
+
+// sessionLifetimes reads SESSION_TTL, SESSION_REFRESH, SESSION_MAX_LIFETIME
+// and PAIRING_CODE_LIFETIME as durations like 2h or 45m. Unset ones keep
+// their defaults.
+func sessionLifetimes() (api.SessionLifetimes, error) {
+	var lifetimes api.SessionLifetimes
+	for _, setting := range []struct {
+		name  string
+		value *time.Duration
+	}{
+		{"SESSION_TTL", &lifetimes.SessionTTL},
+		{"SESSION_REFRESH", &lifetimes.RefreshExtension},
+		{"SESSION_MAX_LIFETIME", &lifetimes.MaxSession},
+		{"PAIRING_CODE_LIFETIME", &lifetimes.PairingCode},
+	} {
+		raw := os.Getenv(setting.name)
+		if raw == "" {
+			continue
+		}
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			return api.SessionLifetimes{}, fmt.Errorf("%s must be a positive duration like 1h or 30m, got %q", setting.name, raw)
+		}
+		*setting.value = d
+	}
+	return lifetimes, nil
+}
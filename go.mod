@@ -4,6 +4,7 @@ go 1.24.1
 
 require (
 	github.com/a-h/templ v0.3.960
+	github.com/gorilla/websocket v1.5.3
 	github.com/stretchr/testify v1.11.1
 	go.etcd.io/bbolt v1.4.3
 )
@@ -1,89 +1,268 @@
 package api
 
 import (
+	"fmt"
+	"math"
+	"net"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/phasecurve/sway_rm/internal"
 	"github.com/phasecurve/sway_rm/internal/components"
 	"github.com/phasecurve/sway_rm/internal/middleware"
+	"github.com/phasecurve/sway_rm/internal/security"
 	"github.com/phasecurve/sway_rm/templates"
 )
 
 const (
 	apiKeyCookieName = "api-key"
 	shortCodeFormID  = "short-code"
+	deviceNameFormID = "device-name"
+	// maxDeviceNameLength caps the name a device gives itself, in runes.
+	maxDeviceNameLength = 64
+	// pairingTokenQueryID is the query parameter carrying a QR pairing token.
+	pairingTokenQueryID = "token"
+	htmxRequestHeader   = "HX-Request"
 )
 
-type ShortCodeGenerator func() string
-type APICodeGenerator func() string
+type ShortCodeGenerator func() (string, error)
+type APICodeGenerator func() (string, error)
+type PairingTokenGenerator func() (string, error)
 
 func (s *Server) SetupRoutes(router *gin.Engine) {
 	api := router.Group("/")
 
-	api.Use(middleware.PairRefresh(s.KeyStore, s.Logger))
+	api.Use(middleware.PairRefresh(s.KeyStore, s.getClock(), s.Logger, s.getLifetimes().refreshPolicy()))
 
 	api.GET("/", s.getRoot)
 	api.GET("/api/status", s.getStatus)
 	api.POST("/api/pair", s.postPair)
+	api.GET("/pair", s.getPair)
+	api.POST("/api/pair/request", s.postPairRequest)
+	api.GET("/api/pair/request/:id", s.getPairRequest)
+
+	local := api.Group("/", middleware.LocalOnly())
+	local.GET("/pair/scopes", s.getPairScopes)
+	local.POST("/pair/scopes", s.postPairScopes)
+	local.GET("/pair/requests", s.getPairRequests)
+	local.POST("/pair/requests/:id/approve", s.postApprovePairRequest)
+	local.POST("/pair/requests/:id/deny", s.postDenyPairRequest)
+
+	paired := api.Group("/", middleware.RequirePaired(s.KeyStore, s.getClock()))
+
+	windows := paired.Group("/", middleware.RequireScope(security.ScopeWindows))
+	windows.GET("/api/workspaces", s.getWorkspaces)
+	windows.GET("/api/workspaces/events", s.getWorkspaceEvents)
+	windows.POST("/api/workspaces/:name/focus", s.postFocusWorkspace)
+	windows.GET("/api/tree", s.getTree)
+	windows.POST("/api/windows/:con_id/:action", s.postWindowAction)
+	windows.POST("/api/containers/:con_id/layout", s.postContainerLayout)
+
+	media := paired.Group("/", middleware.RequireScope(security.ScopeMedia))
+	media.GET("/api/mpv/events", s.getMPVEvents)
+	media.GET("/api/mpv/instances", s.getMPVInstances)
+	media.POST("/api/mpv/instances/:id/activate", s.postActivateMPVInstance)
+	media.POST("/api/mpv/seek", s.postMPVSeek)
+	media.POST("/api/mpv/volume", s.postMPVVolume)
+	media.POST("/api/mpv/speed", s.postMPVSpeed)
+	media.POST("/api/mpv/:action", s.postMPVTransport)
+
+	// Devices without admin only see and revoke themselves.
+	paired.GET("/devices", s.getDevicesPage)
+	paired.GET("/api/devices", s.getDevices)
+	paired.DELETE("/api/devices/:id", s.deleteDevice)
+
+	admin := paired.Group("/", middleware.RequireScope(security.ScopeAdmin))
+	admin.POST("/api/tokens", s.postToken)
+
+	input := paired.Group("/", middleware.RequireScope(security.ScopeInput))
+	input.GET("/api/input/ws", s.getInputSocket)
+	input.POST("/api/input/text", s.postInputText)
+	input.POST("/api/input/keys", s.postInputKeys)
 }
 
 func (s *Server) getRoot(c *gin.Context) {
 	state := internal.StateUnpaired
-	for _, cookie := range c.Request.Cookies() {
-		if cookie.Name == apiKeyCookieName {
-			valid := s.KeyStore.ValidateAPIKey(cookie.Value)
-			if valid {
-				state = internal.StatePaired
-			} else {
-				state = internal.StateExpired
-			}
-			break
-		}
+	key, presented := middleware.PairedKey(c, s.KeyStore, s.getClock())
+	if key != nil {
+		state = internal.StatePaired
+	} else if presented {
+		state = internal.StateExpired
 	}
-	if state != internal.StatePaired && (!s.isShortCodeSet() || s.hasShortCodeExpired()) {
-		s.setNewShortCode()
-		s.setNewShortCodeExpiry()
-		s.publishShortCode()
+	if state != internal.StatePaired {
+		if err := s.ensurePairingCode(); err != nil {
+			s.Logger.Printf("failed to generate pairing code: %v", err)
+			c.Status(http.StatusInternalServerError)
+			return
+		}
 	}
-	component := templates.Launch(state)
+	component := templates.Launch(state, key)
 	component.Render(c.Request.Context(), c.Writer)
 }
 
 func (s *Server) getStatus(c *gin.Context) {
-	for _, cookie := range c.Request.Cookies() {
-		if cookie.Name == apiKeyCookieName {
-			if s.KeyStore.ValidateAPIKey(cookie.Value) {
-				c.Status(http.StatusOK)
-				return
-			}
-			break
-		}
+	if s.isPaired(c) {
+		c.Status(http.StatusOK)
+		return
 	}
 	c.Status(http.StatusUnauthorized)
 }
 
 func (s *Server) postPair(c *gin.Context) {
-	code := c.PostForm(shortCodeFormID)
+	guard := s.getPairGuard()
+	ip := remoteIP(c)
+	if retryAfter := guard.reserve(ip, s.getClock().Now()); retryAfter > 0 {
+		message := setRetryAfter(c, retryAfter)
+		c.Header("Content-Type", "text/html")
+		c.Status(http.StatusTooManyRequests)
+		component := components.PairFormWithError(message)
+		component.Render(c.Request.Context(), c.Writer)
+		return
+	}
 
-	if code != s.getCurrentPairingCode() {
+	apiKey, err := s.APICodeGenerator()
+	if err != nil {
+		s.Logger.Printf("failed to generate api key: %v", err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	scopes, ok := s.pairing().ConsumeIfMatches(normalizeShortCode(c.PostForm(shortCodeFormID)))
+	if !ok {
+		if guard.fail() {
+			s.rotatePairingCode()
+		}
 		c.Header("Content-Type", "text/html")
 		component := components.PairFormWithError("Invalid pairing code. Please try again.")
 		component.Render(c.Request.Context(), c.Writer)
 		return
 	}
+	guard.succeed(ip)
 
-	apiKey := s.APICodeGenerator()
+	if err := s.saveDeviceKey(c, apiKey, formDeviceName(c), scopes); err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Header("Content-Type", "text/html")
+	c.String(http.StatusOK, "<p>Paired</p>")
+}
 
-	if err := s.KeyStore.StoreAPIKey(apiKey, time.Now().Add(1*time.Hour)); err != nil {
+// getPair pairs the device that opened a QR pairing link, then sends it on
+// to the remote. Bad tokens count towards the same limits as bad codes.
+func (s *Server) getPair(c *gin.Context) {
+	guard := s.getPairGuard()
+	ip := remoteIP(c)
+	if retryAfter := guard.reserve(ip, s.getClock().Now()); retryAfter > 0 {
+		s.renderPairLinkError(c, http.StatusTooManyRequests, setRetryAfter(c, retryAfter))
+		return
+	}
+
+	apiKey, err := s.APICodeGenerator()
+	if err != nil {
+		s.Logger.Printf("failed to generate api key: %v", err)
 		c.Status(http.StatusInternalServerError)
 		return
 	}
 
-	c.SetCookie(apiKeyCookieName, apiKey, 3600, "/", "", false, true)
+	scopes, ok := s.pairing().ConsumeTokenIfMatches(c.Query(pairingTokenQueryID))
+	if !ok {
+		if guard.fail() {
+			s.rotatePairingCode()
+		}
+		s.renderPairLinkError(c, http.StatusUnauthorized, "This pairing link has expired or was already used. Enter the code shown on your computer instead.")
+		return
+	}
+	guard.succeed(ip)
+
+	if err := s.saveDeviceKey(c, apiKey, "", scopes); err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/")
+}
+
+func (s *Server) renderPairLinkError(c *gin.Context, status int, message string) {
+	if err := s.ensurePairingCode(); err != nil {
+		s.Logger.Printf("failed to generate pairing code: %v", err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
 	c.Header("Content-Type", "text/html")
-	c.String(http.StatusOK, "<p>Paired</p>")
-	s.resetPairingCode()
+	c.Status(status)
+	component := templates.PairLink(message)
+	component.Render(c.Request.Context(), c.Writer)
+}
+
+func (s *Server) saveDeviceKey(c *gin.Context, apiKey, deviceName string, scopes []string) error {
+	now := s.getClock().Now()
+	lifetimes := s.getLifetimes()
+	key := &security.APIKey{
+		Key:        apiKey,
+		TTL:        lifetimes.refreshPolicy().Cap(now, now.Add(lifetimes.SessionTTL)),
+		DeviceName: deviceName,
+		UserAgent:  c.Request.UserAgent(),
+		PairedAt:   now,
+		LastSeen:   now,
+		RemoteIP:   remoteIP(c),
+		Scopes:     scopes,
+	}
+	if err := s.KeyStore.SaveAPIKey(key); err != nil {
+		return err
+	}
+	middleware.SetAPIKeyCookie(c, apiKey, key.TTL, now)
+	return nil
+}
+
+// setRetryAfter sets the Retry-After header for a locked out client and
+// returns the message to show them.
+func setRetryAfter(c *gin.Context, retryAfter time.Duration) string {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	return fmt.Sprintf("Too many pairing attempts. Try again in %s.", time.Duration(seconds)*time.Second)
+}
+
+func (s *Server) isPaired(c *gin.Context) bool {
+	key, _ := middleware.PairedKey(c, s.KeyStore, s.getClock())
+	return key != nil
+}
+
+// remoteIP is the address of the peer that sent the request. Unlike
+// ClientIP it ignores X-Forwarded-For and X-Real-IP, which any client can
+// set, so it can be trusted for rate limiting and device records.
+func remoteIP(c *gin.Context) string {
+	host, _, err := net.SplitHostPort(c.Request.RemoteAddr)
+	if err != nil {
+		return c.Request.RemoteAddr
+	}
+	return host
+}
+
+// formDeviceName is the device name posted with a pairing, without control
+// characters and cut to maxDeviceNameLength, so a client can't send escape
+// sequences to the terminal or fill the devices page or the desktop
+// notification.
+func formDeviceName(c *gin.Context) string {
+	name := []rune(c.PostForm(deviceNameFormID))
+	name = slices.DeleteFunc(name, func(r rune) bool { return !unicode.IsPrint(r) })
+	name = []rune(strings.TrimSpace(string(name)))
+	if len(name) > maxDeviceNameLength {
+		name = name[:maxDeviceNameLength]
+	}
+	return strings.TrimSpace(string(name))
+}
+
+// normalizeShortCode accepts codes typed in lower case or pasted with
+// whitespace around them; generated codes are upper case only.
+func normalizeShortCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func isHTMXRequest(c *gin.Context) bool {
+	return c.GetHeader(htmxRequestHeader) == "true"
 }
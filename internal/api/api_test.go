@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -17,14 +18,19 @@ import (
 	"github.com/stretchr/testify/assert"
 	bolt "go.etcd.io/bbolt"
 
+	"github.com/phasecurve/sway_rm/internal/clock/clocktest"
+	"github.com/phasecurve/sway_rm/internal/mpv"
+	"github.com/phasecurve/sway_rm/internal/mpv/mpvtest"
 	"github.com/phasecurve/sway_rm/internal/security"
+	"github.com/phasecurve/sway_rm/internal/sway"
+	"github.com/phasecurve/sway_rm/internal/sway/swaytest"
 )
 
-func fakeShortCodeGenerator() func() string {
-	return func() string { return "123456" }
+func fakeShortCodeGenerator() ShortCodeGenerator {
+	return func() (string, error) { return "123456", nil }
 }
 
-func createTestKeyStore(t *testing.T) (*security.KeyStore, *bolt.DB) {
+func createTestKeyStore(t *testing.T, opts ...security.KeyStoreOption) (*security.KeyStore, *bolt.DB) {
 	tempDir := t.TempDir()
 	dbPath := filepath.Join(tempDir, "test.db")
 	db, err := bolt.Open(dbPath, 0600, nil)
@@ -34,13 +40,27 @@ func createTestKeyStore(t *testing.T) (*security.KeyStore, *bolt.DB) {
 	t.Cleanup(func() {
 		db.Close()
 	})
-	return security.NewKeyStore(db), db
+	keyStore, err := security.NewKeyStore(db, opts...)
+	if err != nil {
+		t.Fatalf("failed to create key store: %v", err)
+	}
+	return keyStore, db
 }
 
 func createTestLogger() *log.Logger {
 	return log.New(os.Stderr, "", 0)
 }
 
+// issuePairingCode has the server generate a live pairing code from its
+// ShortCodeGenerator, as loading the page unpaired would.
+func issuePairingCode(t *testing.T, server *Server) string {
+	code, _, err := server.pairing().GenerateIfExpired()
+	if err != nil {
+		t.Fatalf("failed to generate pairing code: %v", err)
+	}
+	return code.Code
+}
+
 func TestStatus_NotPaired_Unauthorized(t *testing.T) {
 	router := gin.Default()
 	keyStore, _ := createTestKeyStore(t)
@@ -120,14 +140,15 @@ func TestPair_ValidShortCode_ReturnsAPIKey(t *testing.T) {
 	router := gin.Default()
 	keyStore, _ := createTestKeyStore(t)
 	server := &Server{
-		Logger:   createTestLogger(),
-		KeyStore: keyStore,
-		APICodeGenerator: func() string {
-			return expectedKey
+		Logger:             createTestLogger(),
+		KeyStore:           keyStore,
+		ShortCodeGenerator: fakeShortCodeGenerator(),
+		APICodeGenerator: func() (string, error) {
+			return expectedKey, nil
 		},
 	}
 	server.SetupRoutes(router)
-	server.currentPairingCode = "123456"
+	issuePairingCode(t, server)
 
 	w := httptest.NewRecorder()
 	shortCode := url.Values{}
@@ -157,12 +178,11 @@ func TestRoot_Paired_ShowPairedMessage(t *testing.T) {
 		Logger:             createTestLogger(),
 		ShortCodeGenerator: fakeShortCodeGenerator(),
 		KeyStore:           keyStore,
-		pairingCodeExpiry:  time.Now().Add(1 * time.Hour),
 	}
 	server.SetupRoutes(router)
 
 	apiKey := "some-valid-key"
-	keyStore.StoreAPIKey(apiKey, time.Now().Add(1*time.Hour))
+	keyStore.StoreAPIKey(apiKey, time.Now().Add(1*time.Hour), []string{security.ScopeAdmin})
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/", nil)
@@ -191,7 +211,7 @@ func TestRoot_ServerCookieTTLReached_ShowExpiredMessage(t *testing.T) {
 	server.SetupRoutes(router)
 
 	apiKey := "expired-key"
-	keyStore.StoreAPIKey(apiKey, time.Now().Add(-1*time.Hour))
+	keyStore.StoreAPIKey(apiKey, time.Now().Add(-1*time.Hour), []string{security.ScopeAdmin})
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/", nil)
@@ -214,7 +234,7 @@ func TestPair_InvalidShortCode_ShowsError(t *testing.T) {
 	server := &Server{
 		Logger:           createTestLogger(),
 		KeyStore:         keyStore,
-		APICodeGenerator: func() string { return "test-key" },
+		APICodeGenerator: func() (string, error) { return "test-key", nil },
 	}
 	server.SetupRoutes(router)
 
@@ -236,9 +256,9 @@ func TestPair_InvalidShortCode_ShowsError(t *testing.T) {
 func TestValidateAndSaveAPIKey_ValidShortCode_CookieMatchesKeyStore(t *testing.T) {
 	apiKey := "an-api-code"
 	ks, _ := createTestKeyStore(t)
-	validShortCode := "a-valid-short-code"
-	scg := func() string { return validShortCode }
-	acg := func() string { return apiKey }
+	validShortCode := "A-VALID-SHORT-CODE"
+	scg := func() (string, error) { return validShortCode, nil }
+	acg := func() (string, error) { return apiKey, nil }
 	router := gin.Default()
 	testLogger := log.New(os.Stderr, "", 0)
 	server := NewServer(
@@ -248,7 +268,7 @@ func TestValidateAndSaveAPIKey_ValidShortCode_CookieMatchesKeyStore(t *testing.T
 		WithOutput(os.Stdout),
 		WithLogger(testLogger),
 	)
-	server.currentPairingCode = validShortCode
+	issuePairingCode(t, server)
 	server.SetupRoutes(router)
 
 	shortCode := url.Values{}
@@ -282,58 +302,58 @@ func TestRoot_NotPaired_DoesNotRegeneratePairingCodeIfAlreadySet(t *testing.T) {
 	keyStore, _ := createTestKeyStore(t)
 	server := &Server{
 		Logger: createTestLogger(),
-		ShortCodeGenerator: func() string {
+		ShortCodeGenerator: func() (string, error) {
 			callCount++
-			return fmt.Sprintf("code-%d", callCount)
+			return fmt.Sprintf("code-%d", callCount), nil
 		},
 		KeyStore: keyStore,
 	}
-	server.currentPairingCode = "existing-code"
-	server.pairingCodeExpiry = time.Now().Add(1 * time.Hour)
+	issuePairingCode(t, server)
 	server.SetupRoutes(router)
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/", nil)
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, "existing-code", server.currentPairingCode, "should not regenerate pairing code if already set")
-	assert.Equal(t, 0, callCount, "generator should not be called if code already exists")
+	assert.Equal(t, "code-1", server.pairing().Code(), "should not regenerate pairing code if already set")
+	assert.Equal(t, 1, callCount, "generator should not be called again if a code already exists")
 }
 
 func TestRoot_NotPaired_RegeneratesPairingCodeIfExpired(t *testing.T) {
 	router := gin.Default()
 	callCount := 0
 	keyStore, _ := createTestKeyStore(t)
+	fakeClock := clocktest.NewFake(testClockStart)
 	server := &Server{
 		Logger: createTestLogger(),
-		ShortCodeGenerator: func() string {
+		ShortCodeGenerator: func() (string, error) {
 			callCount++
-			return fmt.Sprintf("code-%d", callCount)
+			return fmt.Sprintf("code-%d", callCount), nil
 		},
 		KeyStore: keyStore,
+		Clock:    fakeClock,
 	}
-	server.currentPairingCode = "expired-code"
-	server.pairingCodeExpiry = time.Now().Add(-10 * time.Minute)
+	issuePairingCode(t, server)
+	fakeClock.Advance(10 * time.Minute)
 	server.SetupRoutes(router)
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/", nil)
 	router.ServeHTTP(w, req)
 
-	assert.NotEqual(t, "expired-code", server.currentPairingCode, "should regenerate expired pairing code")
-	assert.Equal(t, "code-1", server.currentPairingCode, "should generate new code")
-	assert.Equal(t, 1, callCount, "generator should be called once for expired code")
-	assert.True(t, server.pairingCodeExpiry.After(time.Now()), "new code should have future expiry")
+	assert.Equal(t, "code-2", server.pairing().Code(), "should regenerate expired pairing code")
+	assert.Equal(t, 2, callCount, "generator should be called once more for expired code")
+	assert.True(t, server.pairing().Expiry().After(fakeClock.Now()), "new code should have future expiry")
 }
 
 func TestRoot_NotPaired_PrintsPairingCodeWhenGenerated(t *testing.T) {
 	router := gin.Default()
 	keyStore, _ := createTestKeyStore(t)
 
-	var output bytes.Buffer
+	var output syncBuffer
 	server := &Server{
 		Logger:             createTestLogger(),
-		ShortCodeGenerator: func() string { return "987654" },
+		ShortCodeGenerator: func() (string, error) { return "987654", nil },
 		KeyStore:           keyStore,
 		Output:             &output,
 	}
@@ -343,8 +363,7 @@ func TestRoot_NotPaired_PrintsPairingCodeWhenGenerated(t *testing.T) {
 	req, _ := http.NewRequest("GET", "/", nil)
 	router.ServeHTTP(w, req)
 
-	printed := output.String()
-	assert.Contains(t, printed, "987654", "should print the generated pairing code")
+	printed := waitForOutput(t, &output, "987654")
 	assert.Contains(t, printed, "Pairing code", "should include descriptive text")
 }
 
@@ -352,17 +371,17 @@ func TestPair_ValidShortCode_InvalidatesPairingCode(t *testing.T) {
 	router := gin.Default()
 	keyStore, _ := createTestKeyStore(t)
 	server := &Server{
-		ShortCodeGenerator: fakeShortCodeGenerator(),
-		APICodeGenerator:   func() string { return "test-api-key" },
+		ShortCodeGenerator: func() (string, error) { return "SINGLE-USE", nil },
+		APICodeGenerator:   func() (string, error) { return "test-api-key", nil },
 		KeyStore:           keyStore,
 		Output:             os.Stdout,
 		Logger:             createTestLogger(),
 	}
-	server.currentPairingCode = "single-use"
+	issuePairingCode(t, server)
 	server.SetupRoutes(router)
 
 	shortCode := url.Values{}
-	shortCode.Set("short-code", "single-use")
+	shortCode.Set("short-code", "SINGLE-USE")
 	encodedBody := strings.NewReader(shortCode.Encode())
 
 	w := httptest.NewRecorder()
@@ -371,7 +390,7 @@ func TestPair_ValidShortCode_InvalidatesPairingCode(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusOK, w.Code, "first pairing should succeed")
-	assert.Empty(t, server.currentPairingCode, "pairing code should be cleared after successful use")
+	assert.Empty(t, server.pairing().Code(), "pairing code should be cleared after successful use")
 
 	w2 := httptest.NewRecorder()
 	encodedBody2 := strings.NewReader(shortCode.Encode())
@@ -383,39 +402,19 @@ func TestPair_ValidShortCode_InvalidatesPairingCode(t *testing.T) {
 	assert.Contains(t, body, "Invalid pairing code", "second attempt with same code should fail")
 }
 
-func TestServer_IsShortCodeSet_ReturnsTrueWhenCodeExists(t *testing.T) {
-	server := &Server{}
-
-	assert.False(t, server.isShortCodeSet(), "should return false when code is empty")
-
-	server.currentPairingCode = "test-code"
-	assert.True(t, server.isShortCodeSet(), "should return true when code is set")
-}
-
-func TestServer_HasShortCodeExpired_ReturnsTrueWhenExpired(t *testing.T) {
-	server := &Server{}
-
-	server.pairingCodeExpiry = time.Now().Add(-1 * time.Minute)
-	assert.True(t, server.hasShortCodeExpired(), "should return true when expired")
-
-	server.pairingCodeExpiry = time.Now().Add(1 * time.Minute)
-	assert.False(t, server.hasShortCodeExpired(), "should return false when not expired")
-}
-
-func TestPairRefreshMiddleware_ValidAPIKey_ExtendsExpiryBy30Minutes(t *testing.T) {
+func TestPairRefreshMiddleware_ValidAPIKey_SlidesExpiryTo30MinutesFromNow(t *testing.T) {
 	router := gin.Default()
 	keyStore, _ := createTestKeyStore(t)
 	server := &Server{
 		ShortCodeGenerator: fakeShortCodeGenerator(),
 		KeyStore:           keyStore,
-		pairingCodeExpiry:  time.Now().Add(1 * time.Hour),
 		Logger:             createTestLogger(),
 	}
 	server.SetupRoutes(router)
 
 	apiKey := "sliding-window-key"
-	initialExpiry := time.Now().Add(1 * time.Hour)
-	keyStore.StoreAPIKey(apiKey, initialExpiry)
+	initialExpiry := time.Now().Add(5 * time.Minute)
+	keyStore.StoreAPIKey(apiKey, initialExpiry, []string{security.ScopeAdmin})
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/api/status", nil)
@@ -432,9 +431,114 @@ func TestPairRefreshMiddleware_ValidAPIKey_ExtendsExpiryBy30Minutes(t *testing.T
 	assert.NoError(t, err, "should retrieve API key from store")
 	assert.NotNil(t, updatedKey)
 
-	expectedExpiry := time.Now().Add(90 * time.Minute)
+	expectedExpiry := time.Now().Add(30 * time.Minute)
 	timeDelta := updatedKey.TTL.Sub(expectedExpiry).Abs()
-	assert.True(t, timeDelta < 2*time.Second, "expiry should be ~1.5 hours from now (initial 1h + refresh 30min)")
+	assert.True(t, timeDelta < 2*time.Second, "expiry should be ~30 minutes from now")
+}
+
+func TestPair_ValidShortCode_StoresDeviceMetadata(t *testing.T) {
+	router := gin.Default()
+	keyStore, _ := createTestKeyStore(t)
+	server := &Server{
+		Logger:             createTestLogger(),
+		KeyStore:           keyStore,
+		ShortCodeGenerator: fakeShortCodeGenerator(),
+		APICodeGenerator:   func() (string, error) { return "device-key", nil },
+	}
+	server.SetupRoutes(router)
+	issuePairingCode(t, server)
+
+	w := httptest.NewRecorder()
+	form := url.Values{"short-code": {"123456"}, "device-name": {" Couch phone "}}
+	req, _ := http.NewRequest("POST", "/api/pair", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Android 14)")
+	req.RemoteAddr = "192.168.1.20:51234"
+	before := time.Now()
+	router.ServeHTTP(w, req)
+
+	stored, err := keyStore.GetAPIKey("device-key")
+	assert.NoError(t, err)
+	assert.Equal(t, "Couch phone", stored.DeviceName, "device name should be stored trimmed")
+	assert.Equal(t, "Mozilla/5.0 (Android 14)", stored.UserAgent)
+	assert.Equal(t, "192.168.1.20", stored.RemoteIP)
+	assert.False(t, stored.PairedAt.Before(before), "paired-at should be set at pairing time")
+	assert.True(t, stored.LastSeen.Equal(stored.PairedAt), "last-seen should start at paired-at")
+}
+
+func TestPairRefreshMiddleware_ValidAPIKey_UpdatesLastSeenAndKeepsMetadata(t *testing.T) {
+	router := gin.Default()
+	keyStore, _ := createTestKeyStore(t)
+	server := &Server{
+		KeyStore: keyStore,
+		Logger:   createTestLogger(),
+	}
+	server.SetupRoutes(router)
+	pairedAt := time.Now().Add(-time.Hour)
+	keyStore.SaveAPIKey(&security.APIKey{
+		Key:        "seen-key",
+		TTL:        time.Now().Add(time.Hour),
+		DeviceName: "Tablet",
+		PairedAt:   pairedAt,
+		LastSeen:   pairedAt,
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/status", nil)
+	req.AddCookie(&http.Cookie{Name: apiKeyCookieName, Value: "seen-key"})
+	router.ServeHTTP(w, req)
+
+	stored, err := keyStore.GetAPIKey("seen-key")
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), stored.LastSeen, 2*time.Second, "refresh should record when the device was last seen")
+	assert.Equal(t, "Tablet", stored.DeviceName, "refresh should keep device metadata")
+	assert.True(t, pairedAt.Equal(stored.PairedAt), "refresh should keep paired-at")
+}
+
+func TestRoot_ShortCodeGeneratorFails_ReturnsInternalServerError(t *testing.T) {
+	router := gin.Default()
+	keyStore, _ := createTestKeyStore(t)
+	var output bytes.Buffer
+	server := &Server{
+		Logger:             createTestLogger(),
+		ShortCodeGenerator: func() (string, error) { return "", errors.New("entropy exhausted") },
+		KeyStore:           keyStore,
+		Output:             &output,
+	}
+	server.SetupRoutes(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code, "should return 500 when the pairing code cannot be generated")
+	assert.Empty(t, server.pairing().Code(), "no pairing code should be set")
+	assert.Empty(t, output.String(), "nothing should be published")
+}
+
+func TestPair_APICodeGeneratorFails_ReturnsInternalServerError(t *testing.T) {
+	router := gin.Default()
+	keyStore, _ := createTestKeyStore(t)
+	server := &Server{
+		KeyStore:           keyStore,
+		ShortCodeGenerator: func() (string, error) { return "VALID-CODE", nil },
+		APICodeGenerator:   func() (string, error) { return "", errors.New("entropy exhausted") },
+		Logger:             createTestLogger(),
+	}
+	issuePairingCode(t, server)
+	server.SetupRoutes(router)
+
+	w := httptest.NewRecorder()
+	form := url.Values{"short-code": {"VALID-CODE"}}
+	req, _ := http.NewRequest("POST", "/api/pair", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	router.ServeHTTP(w, req)
+
+	keys, _ := keyStore.ListAPIKeys()
+	assert.Equal(t, http.StatusInternalServerError, w.Code, "should return 500 when the api key cannot be generated")
+	assert.Empty(t, w.Result().Cookies(), "no cookie should be set")
+	assert.Empty(t, keys, "no key should be stored")
+	assert.Equal(t, "VALID-CODE", server.pairing().Code(), "pairing code should remain usable")
 }
 
 func TestPair_StoreAPIKeyFails_ReturnsInternalServerError(t *testing.T) {
@@ -442,17 +546,18 @@ func TestPair_StoreAPIKeyFails_ReturnsInternalServerError(t *testing.T) {
 	keyStore, db := createTestKeyStore(t)
 
 	server := &Server{
-		KeyStore:         keyStore,
-		APICodeGenerator: func() string { return "test-key" },
-		Logger:           createTestLogger(),
+		KeyStore:           keyStore,
+		ShortCodeGenerator: func() (string, error) { return "VALID-CODE", nil },
+		APICodeGenerator:   func() (string, error) { return "test-key", nil },
+		Logger:             createTestLogger(),
 	}
-	server.currentPairingCode = "valid-code"
+	issuePairingCode(t, server)
 	server.SetupRoutes(router)
 
 	db.Close()
 
 	shortCode := url.Values{}
-	shortCode.Set("short-code", "valid-code")
+	shortCode.Set("short-code", "VALID-CODE")
 	encodedBody := strings.NewReader(shortCode.Encode())
 
 	w := httptest.NewRecorder()
@@ -470,14 +575,13 @@ func TestPairRefreshMiddleware_DatabaseClosed_ContinuesGracefully(t *testing.T)
 	server := &Server{
 		ShortCodeGenerator: fakeShortCodeGenerator(),
 		KeyStore:           keyStore,
-		pairingCodeExpiry:  time.Now().Add(1 * time.Hour),
 		Logger:             createTestLogger(),
 	}
 	server.SetupRoutes(router)
 
 	apiKey := "test-key"
 	initialExpiry := time.Now().Add(1 * time.Hour)
-	keyStore.StoreAPIKey(apiKey, initialExpiry)
+	keyStore.StoreAPIKey(apiKey, initialExpiry, []string{security.ScopeAdmin})
 
 	db.Close()
 
@@ -502,11 +606,11 @@ func TestPairRefreshMiddleware_StoreAPIKeyFails_LogsError(t *testing.T) {
 
 	apiKey := "test-key"
 	initialExpiry := time.Now().Add(1 * time.Hour)
-	realKeyStore.StoreAPIKey(apiKey, initialExpiry)
+	realKeyStore.StoreAPIKey(apiKey, initialExpiry, []string{security.ScopeAdmin})
 
 	wrappedKeyStore := &testKeyStoreWrapper{
 		KeyStore: realKeyStore,
-		onStoreAPIKey: func() {
+		onSaveAPIKey: func() {
 			db.Close()
 		},
 	}
@@ -515,7 +619,6 @@ func TestPairRefreshMiddleware_StoreAPIKeyFails_LogsError(t *testing.T) {
 		ShortCodeGenerator: fakeShortCodeGenerator(),
 		KeyStore:           wrappedKeyStore,
 		Logger:             testLogger,
-		pairingCodeExpiry:  time.Now().Add(1 * time.Hour),
 	}
 	server.SetupRoutes(router)
 
@@ -534,12 +637,103 @@ func TestPairRefreshMiddleware_StoreAPIKeyFails_LogsError(t *testing.T) {
 
 type testKeyStoreWrapper struct {
 	*security.KeyStore
-	onStoreAPIKey func()
+	onSaveAPIKey func()
 }
 
-func (w *testKeyStoreWrapper) StoreAPIKey(apiKey string, expiresAt time.Time) error {
-	if w.onStoreAPIKey != nil {
-		w.onStoreAPIKey()
+func (w *testKeyStoreWrapper) SaveAPIKey(key *security.APIKey) error {
+	if w.onSaveAPIKey != nil {
+		w.onSaveAPIKey()
 	}
-	return w.KeyStore.StoreAPIKey(apiKey, expiresAt)
+	return w.KeyStore.SaveAPIKey(key)
+}
+
+func createTestSway(t *testing.T) (*sway.Client, *swaytest.Server) {
+	fake := swaytest.NewServer(t)
+	client := sway.NewClient(fake.SocketPath)
+	t.Cleanup(func() {
+		client.Close()
+	})
+	return client, fake
+}
+
+func TestNewServer_WithSway_UsesSwayClient(t *testing.T) {
+	swayClient, fake := createTestSway(t)
+	fake.SetReply(uint32(sway.MessageGetVersion), `{"major":1,"human_readable":"1.10"}`)
+
+	server := NewServer(WithSway(swayClient))
+
+	version, err := server.Sway.GetVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, "1.10", version.HumanReadable, "server should talk to the injected sway client")
+}
+
+func TestNewServer_WithSwayEvents_HandlersShareStream(t *testing.T) {
+	fake := swaytest.NewServer(t)
+	stream := sway.NewEventStream(fake.SocketPath, createTestLogger())
+	t.Cleanup(stream.Close)
+	server := NewServer(WithSwayEvents(stream))
+	events, unsubscribe := server.SwayEvents.Subscribe()
+	defer unsubscribe()
+	stream.Start()
+	assert.Eventually(t, func() bool { return fake.Subscribers() == 1 }, time.Second, 5*time.Millisecond)
+
+	fake.Emit(uint32(sway.EventWorkspace), `{"change":"focus","current":{"name":"4"}}`)
+
+	select {
+	case event := <-events:
+		assert.Equal(t, "4", event.Workspace.Current.Name, "handler should see events from the shared stream")
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for sway event")
+	}
+}
+
+func addPairedCookie(t *testing.T, keyStore security.KeyStorer, req *http.Request) {
+	apiKey := "paired-test-key"
+	if err := keyStore.StoreAPIKey(apiKey, time.Now().Add(1*time.Hour), []string{security.ScopeAdmin}); err != nil {
+		t.Fatalf("failed to store api key: %v", err)
+	}
+	req.AddCookie(&http.Cookie{
+		Name:  apiKeyCookieName,
+		Value: apiKey,
+	})
+}
+
+func TestRoot_Paired_ShowsWorkspacePanel(t *testing.T) {
+	router := gin.Default()
+	keyStore, _ := createTestKeyStore(t)
+	server := &Server{
+		Logger:   createTestLogger(),
+		KeyStore: keyStore,
+	}
+	server.SetupRoutes(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	addPairedCookie(t, keyStore, req)
+	router.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	assert.Contains(t, body, `id="workspace-panel"`, "paired state should show the workspace panel")
+	assert.Contains(t, body, `hx-get="/api/workspaces"`, "workspace panel should load via HTMX")
+}
+
+func createTestMPV(t *testing.T) (*mpv.Client, *mpvtest.Server) {
+	fake := mpvtest.NewServer(t)
+	client := mpv.NewClient(fake.SocketPath)
+	t.Cleanup(func() {
+		client.Close()
+	})
+	return client, fake
+}
+
+func TestNewServer_WithMPV_UsesMPVClient(t *testing.T) {
+	mpvClient, fake := createTestMPV(t)
+	fake.SetProperty("media-title", "Big Buck Bunny")
+
+	server := NewServer(WithMPV(mpvClient))
+
+	var title string
+	err := server.MPV.GetProperty("media-title", &title)
+	assert.NoError(t, err)
+	assert.Equal(t, "Big Buck Bunny", title, "server should talk to the injected mpv client")
 }
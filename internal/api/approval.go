@@ -0,0 +1,193 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/phasecurve/sway_rm/internal"
+	"github.com/phasecurve/sway_rm/internal/components"
+	"github.com/phasecurve/sway_rm/internal/security"
+	"github.com/phasecurve/sway_rm/templates"
+)
+
+type pairRequestResponse struct {
+	ID        string                     `json:"id"`
+	CheckCode string                     `json:"check_code"`
+	Status    internal.PairRequestStatus `json:"status"`
+	ExpiresAt time.Time                  `json:"expires_at"`
+}
+
+func newPairRequestResponse(request internal.PairRequest) pairRequestResponse {
+	return pairRequestResponse{
+		ID:        request.ID,
+		CheckCode: request.CheckCode,
+		Status:    request.Status,
+		ExpiresAt: request.Expiry,
+	}
+}
+
+// postPairRequest asks the desktop user to let this device pair instead of
+// typing a code. The device then waits on getPairRequest for the outcome.
+func (s *Server) postPairRequest(c *gin.Context) {
+	ip := remoteIP(c)
+	if retryAfter := s.getPairGuard().reserve(ip, s.getClock().Now()); retryAfter > 0 {
+		s.renderPairRequestError(c, http.StatusTooManyRequests, setRetryAfter(c, retryAfter))
+		return
+	}
+
+	id, err := s.newPairRequestID()
+	if err != nil {
+		s.Logger.Printf("failed to generate pairing request id: %v", err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	checkCode, err := s.ShortCodeGenerator()
+	if err != nil {
+		s.Logger.Printf("failed to generate pairing check code: %v", err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	request, err := s.pairRequests().Add(internal.PairRequest{
+		ID:         id,
+		CheckCode:  checkCode,
+		DeviceName: formDeviceName(c),
+		RemoteIP:   ip,
+		UserAgent:  c.Request.UserAgent(),
+	})
+	if errors.Is(err, ErrTooManyPairRequests) {
+		s.renderPairRequestError(c, http.StatusTooManyRequests, "Too many devices are waiting for approval. Try again in a minute.")
+		return
+	}
+	if err != nil {
+		s.Logger.Printf("failed to queue pairing request: %v", err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	s.publishPairRequest(request)
+	s.renderPairRequestPending(c, request)
+}
+
+// getPairRequest long-polls a pairing request. Once approved, the first
+// poll gets the api-key cookie and the request is gone.
+func (s *Server) getPairRequest(c *gin.Context) {
+	id := c.Param("id")
+	request, ok := s.pairRequests().Wait(c.Request.Context(), id, s.getPairRequestWait())
+	if !ok {
+		s.renderPairRequestError(c, http.StatusNotFound, "This pairing request has expired. Ask again or enter the pairing code.")
+		return
+	}
+	if request.Status == internal.RequestPending {
+		s.renderPairRequestPending(c, request)
+		return
+	}
+
+	apiKey, err := s.APICodeGenerator()
+	if err != nil {
+		s.Logger.Printf("failed to generate api key: %v", err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	if request, ok = s.pairRequests().Claim(id); !ok {
+		s.renderPairRequestError(c, http.StatusNotFound, "This pairing request has expired. Ask again or enter the pairing code.")
+		return
+	}
+	if request.Status == internal.RequestDenied {
+		s.renderPairRequestError(c, http.StatusForbidden, "The computer declined this device.")
+		return
+	}
+
+	if err := s.saveDeviceKey(c, apiKey, request.DeviceName, request.Scopes); err != nil {
+		s.Logger.Printf("failed to save api key: %v", err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	if isHTMXRequest(c) {
+		c.Header("HX-Redirect", "/")
+		c.Status(http.StatusOK)
+		return
+	}
+	c.JSON(http.StatusOK, newPairRequestResponse(request))
+}
+
+func (s *Server) renderPairRequestPending(c *gin.Context, request internal.PairRequest) {
+	if isHTMXRequest(c) {
+		c.Header("Content-Type", "text/html")
+		c.Status(http.StatusAccepted)
+		component := components.PairRequestPending(request.ID, request.CheckCode)
+		component.Render(c.Request.Context(), c.Writer)
+		return
+	}
+	c.JSON(http.StatusAccepted, newPairRequestResponse(request))
+}
+
+// renderPairRequestError sends HTMX clients back to the pair form with a
+// message, like a wrong code does.
+func (s *Server) renderPairRequestError(c *gin.Context, status int, message string) {
+	if isHTMXRequest(c) {
+		c.Header("Content-Type", "text/html")
+		if status == http.StatusTooManyRequests {
+			c.Status(status)
+		}
+		component := components.PairFormWithError(message)
+		component.Render(c.Request.Context(), c.Writer)
+		return
+	}
+	c.JSON(status, gin.H{"error": message})
+}
+
+func (s *Server) newPairRequestID() (string, error) {
+	if s.PairingTokenGenerator != nil {
+		return s.PairingTokenGenerator()
+	}
+	return security.GeneratePairingToken()
+}
+
+// getPairRequests lists devices waiting for approval on this computer.
+func (s *Server) getPairRequests(c *gin.Context) {
+	s.renderPairRequests(c)
+}
+
+func (s *Server) postApprovePairRequest(c *gin.Context) {
+	scopes, err := security.ParseScopes(c.PostFormArray(pairScopeFormID)...)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(scopes) == 0 {
+		c.String(http.StatusBadRequest, "choose at least one scope")
+		return
+	}
+	s.decidePairRequest(c, internal.RequestApproved, scopes)
+}
+
+func (s *Server) postDenyPairRequest(c *gin.Context) {
+	s.decidePairRequest(c, internal.RequestDenied, nil)
+}
+
+func (s *Server) decidePairRequest(c *gin.Context, status internal.PairRequestStatus, scopes []string) {
+	if !s.pairRequests().Decide(c.Param("id"), status, scopes) {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if isHTMXRequest(c) {
+		s.renderPairRequests(c)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) renderPairRequests(c *gin.Context) {
+	pending := s.pairRequests().Pending()
+	defaults := s.pairing().DefaultScopes()
+	c.Header("Content-Type", "text/html")
+	if isHTMXRequest(c) {
+		component := components.PairRequestList(pending, defaults)
+		component.Render(c.Request.Context(), c.Writer)
+		return
+	}
+	component := templates.PairRequests(pending, defaults)
+	component.Render(c.Request.Context(), c.Writer)
+}
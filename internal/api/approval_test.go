@@ -0,0 +1,358 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phasecurve/sway_rm/internal/clock/clocktest"
+	"github.com/phasecurve/sway_rm/internal/publish"
+	"github.com/phasecurve/sway_rm/internal/security"
+)
+
+const (
+	phoneAddr   = "192.168.1.30:50000"
+	desktopAddr = "127.0.0.1:50000"
+	// localHost is the Host header of a browser on the laptop.
+	localHost = "localhost:8080"
+)
+
+func createApprovalTestServer(t *testing.T, opts ...ServerOption) (*gin.Engine, *Server, *security.KeyStore, *recordingPublisher) {
+	keyStore, _ := createTestKeyStore(t)
+	publisher := &recordingPublisher{}
+	server := NewServer(append([]ServerOption{
+		WithKeyStore(keyStore),
+		WithClock(clocktest.NewFake(testClockStart)),
+		WithShortCodeGenerator(func() (string, error) { return "CHK234", nil }),
+		WithPairingTokenGenerator(func() (string, error) { return "req-1", nil }),
+		WithAPICodeGenerator(func() (string, error) { return "approved-key", nil }),
+		WithPublishers(publisher),
+		WithOutput(io.Discard),
+		WithLogger(createTestLogger()),
+	}, opts...)...)
+	server.pairRequestWait = 20 * time.Millisecond
+	router := gin.New()
+	server.SetupRoutes(router)
+	return router, server, keyStore, publisher
+}
+
+func sendFrom(router *gin.Engine, method, path, remoteAddr string, form url.Values, htmx bool) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = remoteAddr
+	req.Host = localHost
+	if htmx {
+		req.Header.Set("HX-Request", "true")
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func requestPairing(router *gin.Engine) *httptest.ResponseRecorder {
+	return sendFrom(router, "POST", "/api/pair/request", phoneAddr, url.Values{deviceNameFormID: {"Guest phone"}}, false)
+}
+
+func pollPairRequest(router *gin.Engine) *httptest.ResponseRecorder {
+	return sendFrom(router, "GET", "/api/pair/request/req-1", phoneAddr, nil, false)
+}
+
+func apiKeyCookie(w *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == apiKeyCookieName {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func TestPairRequest_Created_PendingAndPublished(t *testing.T) {
+	router, _, _, publisher := createApprovalTestServer(t, WithLocalURL("http://localhost:8080/"))
+
+	w := requestPairing(router)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var body pairRequestResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, pairRequestResponse{
+		ID:        "req-1",
+		CheckCode: "CHK234",
+		Status:    "pending",
+		ExpiresAt: testClockStart.Add(defaultPairRequestLifetime),
+	}, body)
+	assert.Eventually(t, func() bool { return len(publisher.Requests()) > 0 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []publish.Request{{
+		DeviceName: "Guest phone",
+		RemoteIP:   "192.168.1.30",
+		CheckCode:  "CHK234",
+		ReviewURL:  "http://localhost:8080/pair/requests",
+		Expiry:     testClockStart.Add(defaultPairRequestLifetime),
+	}}, publisher.Requests())
+}
+
+func TestPairRequest_Approved_SetsCookieWithChosenScopes(t *testing.T) {
+	router, _, keyStore, _ := createApprovalTestServer(t)
+	requestPairing(router)
+
+	approve := sendFrom(router, "POST", "/pair/requests/req-1/approve", desktopAddr, url.Values{"scope": {"media"}}, false)
+	w := pollPairRequest(router)
+
+	assert.Equal(t, http.StatusNoContent, approve.Code)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id":"req-1","check_code":"CHK234","status":"approved","expires_at":"2025-03-01T20:02:00Z"}`, w.Body.String())
+	if cookie := apiKeyCookie(w); assert.NotNil(t, cookie) {
+		assert.Equal(t, "approved-key", cookie.Value)
+	}
+	key, err := keyStore.GetAPIKey("approved-key")
+	assert.NoError(t, err)
+	assert.Equal(t, "Guest phone", key.DeviceName)
+	assert.Equal(t, "192.168.1.30", key.RemoteIP)
+	assert.Equal(t, []string{"media"}, key.Scopes)
+
+	again := pollPairRequest(router)
+	assert.Equal(t, http.StatusNotFound, again.Code, "an approval should only hand out one key")
+	assert.Nil(t, apiKeyCookie(again))
+}
+
+func TestPairRequest_Denied_NoKey(t *testing.T) {
+	router, _, keyStore, _ := createApprovalTestServer(t)
+	requestPairing(router)
+
+	deny := sendFrom(router, "POST", "/pair/requests/req-1/deny", desktopAddr, nil, false)
+	w := pollPairRequest(router)
+
+	assert.Equal(t, http.StatusNoContent, deny.Code)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Nil(t, apiKeyCookie(w))
+	_, err := keyStore.GetAPIKey("approved-key")
+	assert.ErrorIs(t, err, security.ErrKeyNotFound)
+	assert.Equal(t, http.StatusNotFound, pollPairRequest(router).Code)
+}
+
+func TestPairRequest_Undecided_PollReturnsPending(t *testing.T) {
+	router, _, _, _ := createApprovalTestServer(t)
+	requestPairing(router)
+
+	w := pollPairRequest(router)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"pending"`)
+	assert.Nil(t, apiKeyCookie(w))
+}
+
+func TestPairRequest_LongPoll_AnsweredWhenApproved(t *testing.T) {
+	router, server, _, _ := createApprovalTestServer(t)
+	server.pairRequestWait = time.Minute
+	requestPairing(router)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- pollPairRequest(router) }()
+	assert.Eventually(t, func() bool {
+		return sendFrom(router, "POST", "/pair/requests/req-1/approve", desktopAddr, url.Values{"scope": {"admin"}}, false).Code == http.StatusNoContent
+	}, 5*time.Second, 10*time.Millisecond)
+
+	select {
+	case w := <-done:
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotNil(t, apiKeyCookie(w))
+	case <-time.After(5 * time.Second):
+		t.Fatal("the waiting phone was not told about the approval")
+	}
+}
+
+func TestPairRequest_Expired_NotFound(t *testing.T) {
+	router, server, _, _ := createApprovalTestServer(t)
+	requestPairing(router)
+	server.getClock().(*clocktest.Fake).Advance(defaultPairRequestLifetime)
+
+	approve := sendFrom(router, "POST", "/pair/requests/req-1/approve", desktopAddr, url.Values{"scope": {"admin"}}, false)
+	w := pollPairRequest(router)
+
+	assert.Equal(t, http.StatusNotFound, approve.Code)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestPairRequest_DecisionFromNetwork_Forbidden(t *testing.T) {
+	router, server, _, _ := createApprovalTestServer(t)
+	requestPairing(router)
+
+	for _, path := range []string{"/pair/requests", "/pair/requests/req-1/approve", "/pair/requests/req-1/deny"} {
+		method := "POST"
+		if path == "/pair/requests" {
+			method = "GET"
+		}
+		w := sendFrom(router, method, path, phoneAddr, url.Values{"scope": {"admin"}}, false)
+
+		assert.Equal(t, http.StatusForbidden, w.Code, path)
+	}
+	assert.Len(t, server.pairRequests().Pending(), 1, "the phone should not be able to approve itself")
+}
+
+func TestPairRequest_CrossOriginApprove_Forbidden(t *testing.T) {
+	router, server, _, _ := createApprovalTestServer(t)
+	requestPairing(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/pair/requests/req-1/approve", strings.NewReader(url.Values{"scope": {"admin"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Origin", "https://evil.example.com")
+	req.Header.Set("Sec-Fetch-Site", "cross-site")
+	req.RemoteAddr = desktopAddr
+	req.Host = localHost
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Len(t, server.pairRequests().Pending(), 1, "another site open on the laptop should not be able to approve")
+}
+
+func TestPairRequest_ApproveInvalidScopes_BadRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		form url.Values
+	}{
+		{"nothing selected", url.Values{}},
+		{"unknown scope", url.Values{"scope": {"network"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, server, _, _ := createApprovalTestServer(t)
+			requestPairing(router)
+
+			w := sendFrom(router, "POST", "/pair/requests/req-1/approve", desktopAddr, tt.form, false)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Len(t, server.pairRequests().Pending(), 1)
+		})
+	}
+}
+
+func TestPairRequest_UnknownRequest_NotFound(t *testing.T) {
+	router, _, _, _ := createApprovalTestServer(t)
+
+	approve := sendFrom(router, "POST", "/pair/requests/nope/approve", desktopAddr, url.Values{"scope": {"admin"}}, false)
+	deny := sendFrom(router, "POST", "/pair/requests/nope/deny", desktopAddr, nil, false)
+
+	assert.Equal(t, http.StatusNotFound, approve.Code)
+	assert.Equal(t, http.StatusNotFound, deny.Code)
+}
+
+func TestPairRequest_LockedOutAddress_TooManyRequests(t *testing.T) {
+	router, server, _, publisher := createApprovalTestServer(t)
+	for i := 0; i < DefaultPairingLimits.MaxAttemptsPerIP; i++ {
+		server.getPairGuard().reserve("192.168.1.30", testClockStart)
+	}
+
+	w := requestPairing(router)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.Empty(t, publisher.Requests(), "locked out devices should not bother the desktop")
+}
+
+func TestPairRequest_Repeated_CountsTowardsLockout(t *testing.T) {
+	router, _, _, _ := createApprovalTestServer(t)
+	for i := 0; i < DefaultPairingLimits.MaxAttemptsPerIP; i++ {
+		assert.Equal(t, http.StatusAccepted, requestPairing(router).Code)
+	}
+
+	w := requestPairing(router)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "asking for approval over and over should lock the device out")
+}
+
+func TestPairRequest_LongDeviceName_Capped(t *testing.T) {
+	router, server, _, _ := createApprovalTestServer(t)
+
+	sendFrom(router, "POST", "/api/pair/request", phoneAddr, url.Values{deviceNameFormID: {strings.Repeat("é", 100)}}, false)
+
+	pending := server.pairRequests().Pending()
+	if assert.Len(t, pending, 1) {
+		assert.Equal(t, strings.Repeat("é", maxDeviceNameLength), pending[0].DeviceName)
+	}
+}
+
+func TestPairRequest_ControlCharactersInName_NotPublished(t *testing.T) {
+	var output syncBuffer
+	recording := &recordingPublisher{}
+	router, _, _, _ := createApprovalTestServer(t, WithPublishers(recording, publish.NewTerminal(&output)))
+
+	sendFrom(router, "POST", "/api/pair/request", phoneAddr, url.Values{deviceNameFormID: {"\x1b]0;pwned\x07Guest\x1b[2J phone\r\n"}}, false)
+
+	printed := waitForOutput(t, &output, "wants to pair")
+	assert.NotContains(t, printed, "\x1b", "escape sequences in the name should not reach the terminal")
+	assert.Contains(t, printed, "]0;pwnedGuest[2J phone at 192.168.1.30 wants to pair")
+	if assert.Len(t, recording.Requests(), 1) {
+		assert.Equal(t, "]0;pwnedGuest[2J phone", recording.Requests()[0].DeviceName, "every publisher should get the cleaned name")
+	}
+}
+
+func TestPairRequest_SlowPublisher_RespondsWithoutWaiting(t *testing.T) {
+	slow := &blockingPublisher{release: make(chan struct{})}
+	defer close(slow.release)
+	router, _, _, _ := createApprovalTestServer(t, WithPublishers(slow))
+
+	done := make(chan int)
+	go func() { done <- requestPairing(router).Code }()
+
+	select {
+	case code := <-done:
+		assert.Equal(t, http.StatusAccepted, code)
+	case <-time.After(time.Second):
+		t.Fatal("pairing request waited on a blocked publisher")
+	}
+}
+
+func TestPairRequest_HTMX_WaitsThenRedirects(t *testing.T) {
+	router, _, _, _ := createApprovalTestServer(t)
+
+	created := sendFrom(router, "POST", "/api/pair/request", phoneAddr, url.Values{deviceNameFormID: {"Guest phone"}}, true)
+	pending := sendFrom(router, "GET", "/api/pair/request/req-1", phoneAddr, nil, true)
+	sendFrom(router, "POST", "/pair/requests/req-1/approve", desktopAddr, url.Values{"scope": {"admin"}}, true)
+	approved := sendFrom(router, "GET", "/api/pair/request/req-1", phoneAddr, nil, true)
+
+	assert.Equal(t, http.StatusAccepted, created.Code)
+	assert.Contains(t, created.Body.String(), `hx-get="/api/pair/request/req-1"`)
+	assert.Contains(t, created.Body.String(), "CHK234")
+	assert.Contains(t, pending.Body.String(), `hx-get="/api/pair/request/req-1"`, "a pending poll should poll again")
+	assert.Equal(t, "/", approved.Header().Get("HX-Redirect"))
+	assert.NotNil(t, apiKeyCookie(approved))
+}
+
+func TestPairRequest_HTMXDenied_ShowsPairFormAgain(t *testing.T) {
+	router, _, _, _ := createApprovalTestServer(t)
+	requestPairing(router)
+	sendFrom(router, "POST", "/pair/requests/req-1/deny", desktopAddr, nil, true)
+
+	w := sendFrom(router, "GET", "/api/pair/request/req-1", phoneAddr, nil, true)
+
+	assert.Equal(t, http.StatusOK, w.Code, "HTMX should swap in the pair form")
+	assert.Contains(t, w.Body.String(), "The computer declined this device.")
+	assert.Contains(t, w.Body.String(), `<form id="pair-form"`)
+}
+
+func TestPairRequests_Page_ListsPendingWithDefaultScopes(t *testing.T) {
+	router, _, _, _ := createApprovalTestServer(t, WithPairingScopes(security.ScopeMedia))
+	requestPairing(router)
+
+	page := sendFrom(router, "GET", "/pair/requests", desktopAddr, nil, false)
+	fragment := sendFrom(router, "POST", "/pair/requests/req-1/deny", desktopAddr, nil, true)
+
+	assert.Equal(t, http.StatusOK, page.Code)
+	body := page.Body.String()
+	assert.Contains(t, body, "<title>Sway RM - Pairing requests</title>")
+	assert.Contains(t, body, "Guest phone")
+	assert.Contains(t, body, "CHK234")
+	assert.Contains(t, body, `hx-post="/pair/requests/req-1/approve"`)
+	assert.Contains(t, body, `value="media" checked`, "the configured default scopes should be preselected")
+	assert.NotContains(t, body, `value="admin" checked`)
+	assert.NotContains(t, fragment.Body.String(), "<title>", "HTMX should get just the list back")
+	assert.Contains(t, fragment.Body.String(), "No devices are waiting.")
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phasecurve/sway_rm/internal/clock"
+	"github.com/phasecurve/sway_rm/internal/middleware"
+	"github.com/phasecurve/sway_rm/internal/security"
+)
+
+var publicRoutes = map[string]bool{
+	"GET /":           true,
+	"GET /api/status": true,
+	"POST /api/pair":  true,
+	"GET /pair":       true,
+
+	"POST /api/pair/request":    true,
+	"GET /api/pair/request/:id": true,
+}
+
+// localRoutes are guarded by where the request comes from, not pairing.
+var localRoutes = map[string]bool{
+	"GET /pair/scopes":  true,
+	"POST /pair/scopes": true,
+
+	"GET /pair/requests":              true,
+	"POST /pair/requests/:id/approve": true,
+	"POST /pair/requests/:id/deny":    true,
+}
+
+func createAuthTestServer(t *testing.T) (*gin.Engine, *security.KeyStore) {
+	keyStore, _ := createTestKeyStore(t)
+	router := gin.New()
+	server := &Server{
+		KeyStore: keyStore,
+		Logger:   createTestLogger(),
+	}
+	server.SetupRoutes(router)
+	return router, keyStore
+}
+
+func routePath(path string) string {
+	return strings.NewReplacer(":con_id", "1", ":name", "1", ":action", "play", ":id", "x").Replace(path)
+}
+
+func TestRoutes_ControlRoutesRequirePairing(t *testing.T) {
+	router, keyStore := createAuthTestServer(t)
+	assert.NoError(t, keyStore.StoreAPIKey("expired-key", time.Now().Add(-time.Minute), []string{security.ScopeAdmin}))
+
+	for _, route := range router.Routes() {
+		if publicRoutes[route.Method+" "+route.Path] || localRoutes[route.Method+" "+route.Path] {
+			continue
+		}
+		for _, cookie := range []string{"", "expired-key", "unknown-key"} {
+			t.Run(route.Method+" "+route.Path+" "+cookie, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(route.Method, routePath(route.Path), nil)
+				if cookie != "" {
+					req.AddCookie(&http.Cookie{Name: apiKeyCookieName, Value: cookie})
+				}
+				router.ServeHTTP(w, req)
+
+				if strings.HasPrefix(route.Path, "/api/") {
+					assert.Equal(t, http.StatusUnauthorized, w.Code)
+					assert.JSONEq(t, `{"error":"not paired"}`, w.Body.String())
+				} else {
+					assert.Equal(t, http.StatusSeeOther, w.Code)
+					assert.Equal(t, "/", w.Header().Get("Location"))
+				}
+			})
+		}
+	}
+}
+
+func TestRequirePaired_HTMXRequest_RedirectsToPairForm(t *testing.T) {
+	router, _ := createAuthTestServer(t)
+	for _, path := range []string{"/api/workspaces", "/devices"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("HX-Request", "true")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code, path)
+		assert.Equal(t, "/", w.Header().Get("HX-Redirect"), "%s should send the page back to the pair form", path)
+	}
+}
+
+func TestRequirePaired_ValidKey_StoresKeyInContext(t *testing.T) {
+	keyStore, _ := createTestKeyStore(t)
+	assert.NoError(t, keyStore.SaveAPIKey(&security.APIKey{Key: "context-key", TTL: time.Now().Add(time.Hour), DeviceName: "Phone"}))
+	router := gin.New()
+	router.GET("/api/whoami", middleware.RequirePaired(keyStore, clock.Real{}), func(c *gin.Context) {
+		key, ok := middleware.APIKey(c)
+		if !ok {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"device": key.DeviceName, "id": key.ID})
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/whoami", nil)
+	req.AddCookie(&http.Cookie{Name: apiKeyCookieName, Value: "context-key"})
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body map[string]string
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "Phone", body["device"])
+	assert.NotEmpty(t, body["id"])
+}
+
+func TestAPIKey_NoMiddleware_ReturnsFalse(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	key, ok := middleware.APIKey(c)
+
+	assert.False(t, ok)
+	assert.Nil(t, key)
+}
+
+func TestRoot_ExpiredKey_ShowsExpiredState(t *testing.T) {
+	keyStore, _ := createTestKeyStore(t)
+	assert.NoError(t, keyStore.StoreAPIKey("expired-key", time.Now().Add(-time.Minute), []string{security.ScopeAdmin}))
+	router := gin.New()
+	server := &Server{
+		KeyStore:           keyStore,
+		Logger:             createTestLogger(),
+		ShortCodeGenerator: func() (string, error) { return "ABC234", nil },
+		Output:             &strings.Builder{},
+	}
+	server.SetupRoutes(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: apiKeyCookieName, Value: "expired-key"})
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Session expired")
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phasecurve/sway_rm/internal/clock/clocktest"
+	"github.com/phasecurve/sway_rm/internal/security"
+)
+
+var testClockStart = time.Date(2025, 3, 1, 20, 0, 0, 0, time.UTC)
+
+func createClockTestServer(t *testing.T) (*gin.Engine, *Server, *security.KeyStore, *clocktest.Fake) {
+	fakeClock := clocktest.NewFake(testClockStart)
+	keyStore, _ := createTestKeyStore(t, security.WithClock(fakeClock))
+	callCount := 0
+	server := NewServer(
+		WithKeyStore(keyStore),
+		WithClock(fakeClock),
+		WithShortCodeGenerator(func() (string, error) {
+			callCount++
+			return fmt.Sprintf("CODE-%d", callCount), nil
+		}),
+		WithAPICodeGenerator(func() (string, error) { return "clock-key", nil }),
+		WithOutput(io.Discard),
+		WithLogger(createTestLogger()),
+	)
+	router := gin.Default()
+	server.SetupRoutes(router)
+	return router, server, keyStore, fakeClock
+}
+
+func getStatusWithKey(router *gin.Engine, apiKey string) int {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/status", nil)
+	req.AddCookie(&http.Cookie{Name: apiKeyCookieName, Value: apiKey})
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestRoot_PairingCodeExpiryBoundary(t *testing.T) {
+	tests := []struct {
+		name         string
+		elapsed      time.Duration
+		expectedCode string
+	}{
+		{"just generated", 0, "CODE-1"},
+		{"one nanosecond before expiry", 5*time.Minute - time.Nanosecond, "CODE-1"},
+		{"exactly at expiry", 5 * time.Minute, "CODE-2"},
+		{"after expiry", 6 * time.Minute, "CODE-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, server, _, fakeClock := createClockTestServer(t)
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+			fakeClock.Advance(tt.elapsed)
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+			assert.Equal(t, tt.expectedCode, server.pairing().Code())
+		})
+	}
+}
+
+func TestRoot_NewPairingCode_ExpiresFiveMinutesFromClock(t *testing.T) {
+	router, server, _, _ := createClockTestServer(t)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	assert.Equal(t, testClockStart.Add(5*time.Minute), server.pairing().Expiry())
+}
+
+func TestPair_StoresKeyExpiryFromClock(t *testing.T) {
+	router, server, keyStore, _ := createClockTestServer(t)
+	issuePairingCode(t, server)
+
+	w := httptest.NewRecorder()
+	form := url.Values{"short-code": {"CODE-1"}}
+	req, _ := http.NewRequest("POST", "/api/pair", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	router.ServeHTTP(w, req)
+
+	stored, err := keyStore.GetAPIKey("clock-key")
+	assert.NoError(t, err)
+	assert.Equal(t, testClockStart.Add(time.Hour), stored.TTL.UTC())
+	assert.Equal(t, testClockStart, stored.PairedAt.UTC())
+}
+
+func TestStatus_KeyExpiryBoundary(t *testing.T) {
+	tests := []struct {
+		name     string
+		elapsed  time.Duration
+		expected int
+	}{
+		{"before expiry", 59 * time.Second, http.StatusOK},
+		{"one nanosecond before expiry", time.Minute - time.Nanosecond, http.StatusOK},
+		{"exactly at expiry", time.Minute, http.StatusUnauthorized},
+		{"after expiry", time.Hour, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, _, keyStore, fakeClock := createClockTestServer(t)
+			keyStore.StoreAPIKey("boundary-key", testClockStart.Add(time.Minute), []string{security.ScopeAdmin})
+
+			fakeClock.Advance(tt.elapsed)
+
+			assert.Equal(t, tt.expected, getStatusWithKey(router, "boundary-key"))
+		})
+	}
+}
+
+func TestPairRefreshMiddleware_SlidesFromNowUsingClock(t *testing.T) {
+	router, _, keyStore, fakeClock := createClockTestServer(t)
+	keyStore.StoreAPIKey("refresh-key", testClockStart.Add(time.Hour), []string{security.ScopeAdmin})
+	fakeClock.Advance(50 * time.Minute)
+
+	getStatusWithKey(router, "refresh-key")
+	getStatusWithKey(router, "refresh-key")
+
+	stored, err := keyStore.GetAPIKey("refresh-key")
+	assert.NoError(t, err)
+	assert.Equal(t, testClockStart.Add(80*time.Minute), stored.TTL.UTC(), "refresh should extend 30 minutes from now, not stack onto the old expiry")
+	assert.Equal(t, testClockStart.Add(50*time.Minute), stored.LastSeen.UTC(), "last-seen should come from the clock")
+}
+
+func TestPairRefreshMiddleware_LongerExpiry_NotShortened(t *testing.T) {
+	router, _, keyStore, fakeClock := createClockTestServer(t)
+	keyStore.StoreAPIKey("fresh-key", testClockStart.Add(time.Hour), []string{security.ScopeAdmin})
+	fakeClock.Advance(10 * time.Minute)
+
+	getStatusWithKey(router, "fresh-key")
+
+	stored, _ := keyStore.GetAPIKey("fresh-key")
+	assert.Equal(t, testClockStart.Add(time.Hour), stored.TTL.UTC(), "a request should never bring the expiry forward")
+}
+
+func TestPairRefreshMiddleware_KeyExpiredExactlyNow_NotRefreshed(t *testing.T) {
+	router, _, keyStore, fakeClock := createClockTestServer(t)
+	keyStore.StoreAPIKey("stale-key", testClockStart.Add(time.Hour), []string{security.ScopeAdmin})
+	fakeClock.Advance(time.Hour)
+
+	code := getStatusWithKey(router, "stale-key")
+
+	stored, err := keyStore.GetAPIKey("stale-key")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, testClockStart.Add(time.Hour), stored.TTL.UTC(), "expired keys should not be revived by refresh")
+}
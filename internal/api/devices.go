@@ -0,0 +1,122 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/phasecurve/sway_rm/internal/components"
+	"github.com/phasecurve/sway_rm/internal/middleware"
+	"github.com/phasecurve/sway_rm/internal/security"
+	"github.com/phasecurve/sway_rm/templates"
+)
+
+// devicesChangedEvent is triggered on the client to reload the device list.
+const devicesChangedEvent = "devices-changed"
+
+type deviceResponse struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Name      string    `json:"name"`
+	UserAgent string    `json:"user_agent"`
+	RemoteIP  string    `json:"remote_ip"`
+	PairedAt  time.Time `json:"paired_at"`
+	LastSeen  time.Time `json:"last_seen"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Current   bool      `json:"current"`
+	Scopes    []string  `json:"scopes,omitempty"`
+}
+
+func (s *Server) getDevicesPage(c *gin.Context) {
+	key, _ := middleware.APIKey(c)
+	component := templates.Devices(key.HasScope(security.ScopeAdmin))
+	component.Render(c.Request.Context(), c.Writer)
+}
+
+func (s *Server) getDevices(c *gin.Context) {
+	s.renderDevices(c)
+}
+
+func (s *Server) deleteDevice(c *gin.Context) {
+	id := c.Param("id")
+	current, _ := middleware.APIKey(c)
+	currentID := current.ID
+	if id != currentID && !current.HasScope(security.ScopeAdmin) {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if err := s.KeyStore.DeleteAPIKey(id); err != nil {
+		if errors.Is(err, security.ErrKeyNotFound) {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		s.Logger.Printf("failed to revoke device %s: %v", id, err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	if id == currentID {
+		c.SetCookie(apiKeyCookieName, "", -1, "/", "", false, true)
+		if isHTMXRequest(c) {
+			if err := s.ensurePairingCode(); err != nil {
+				s.Logger.Printf("failed to generate pairing code: %v", err)
+				c.Status(http.StatusInternalServerError)
+				return
+			}
+			c.Header("HX-Retarget", "#device-panel")
+			c.Header("Content-Type", "text/html")
+			component := components.PairForm()
+			component.Render(c.Request.Context(), c.Writer)
+			return
+		}
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	if isHTMXRequest(c) {
+		s.renderDevices(c)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) renderDevices(c *gin.Context) {
+	keys, err := s.KeyStore.ListAPIKeys()
+	if err != nil {
+		s.Logger.Printf("failed to list devices: %v", err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	current, _ := middleware.APIKey(c)
+	currentID := current.ID
+	if !current.HasScope(security.ScopeAdmin) {
+		keys = slices.DeleteFunc(keys, func(key *security.APIKey) bool { return key.ID != currentID })
+	}
+	if isHTMXRequest(c) {
+		c.Header("Content-Type", "text/html")
+		component := components.DeviceList(keys, currentID, s.getClock().Now())
+		component.Render(c.Request.Context(), c.Writer)
+		return
+	}
+
+	devices := make([]deviceResponse, 0, len(keys))
+	for _, key := range keys {
+		devices = append(devices, deviceResponse{
+			ID:        key.ID,
+			Type:      string(key.Type),
+			Name:      key.DeviceName,
+			UserAgent: key.UserAgent,
+			RemoteIP:  key.RemoteIP,
+			PairedAt:  key.PairedAt,
+			LastSeen:  key.LastSeen,
+			ExpiresAt: key.TTL,
+			Current:   key.ID == currentID,
+			Scopes:    key.Scopes,
+		})
+	}
+	c.JSON(http.StatusOK, devices)
+}
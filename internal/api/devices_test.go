@@ -0,0 +1,241 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phasecurve/sway_rm/internal/clock/clocktest"
+	"github.com/phasecurve/sway_rm/internal/security"
+)
+
+func createDeviceTestServer(t *testing.T) (*gin.Engine, *security.KeyStore) {
+	router := gin.Default()
+	keyStore, _ := createTestKeyStore(t)
+	server := &Server{
+		KeyStore:           keyStore,
+		ShortCodeGenerator: fakeShortCodeGenerator(),
+		Output:             io.Discard,
+		Logger:             createTestLogger(),
+	}
+	server.SetupRoutes(router)
+	keyStore.SaveAPIKey(&security.APIKey{
+		Key:        "other-phone-key",
+		TTL:        time.Now().Add(time.Hour),
+		DeviceName: "Other phone",
+		UserAgent:  "Mozilla/5.0 (iPhone)",
+		RemoteIP:   "192.168.1.30",
+		PairedAt:   time.Now().Add(-2 * time.Hour),
+		LastSeen:   time.Now().Add(-time.Hour),
+	})
+	return router, keyStore
+}
+
+func deviceID(t *testing.T, keyStore security.KeyStorer, apiKey string) string {
+	key, err := keyStore.GetAPIKey(apiKey)
+	if err != nil {
+		t.Fatalf("failed to look up %s: %v", apiKey, err)
+	}
+	return key.ID
+}
+
+func TestDevices_NotPaired_Unauthorized(t *testing.T) {
+	router, _ := createDeviceTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/devices", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestDevices_Paired_ListsDevicesWithoutKeys(t *testing.T) {
+	router, keyStore := createDeviceTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/devices", nil)
+	addPairedCookie(t, keyStore, req)
+	router.ServeHTTP(w, req)
+
+	var devices []deviceResponse
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &devices))
+	assert.Len(t, devices, 2)
+	assert.Equal(t, "Other phone", devices[0].Name)
+	assert.Equal(t, deviceID(t, keyStore, "other-phone-key"), devices[0].ID)
+	assert.False(t, devices[0].Current)
+	assert.True(t, devices[1].Current, "the requesting device should be marked current")
+	assert.NotContains(t, w.Body.String(), "other-phone-key", "raw api keys should never be listed")
+}
+
+func TestDevices_HTMXRequest_RendersRevokeButtons(t *testing.T) {
+	router, keyStore := createDeviceTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/devices", nil)
+	req.Header.Set(htmxRequestHeader, "true")
+	addPairedCookie(t, keyStore, req)
+	router.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, body, "Other phone")
+	assert.Contains(t, body, "Last seen")
+	assert.Contains(t, body, `hx-delete="/api/devices/`+deviceID(t, keyStore, "other-phone-key")+`"`)
+	assert.Contains(t, body, "(this device)")
+}
+
+func TestDeleteDevice_OtherDevice_RevokesIt(t *testing.T) {
+	router, keyStore := createDeviceTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api/devices/"+deviceID(t, keyStore, "other-phone-key"), nil)
+	addPairedCookie(t, keyStore, req)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.False(t, keyStore.ValidateAPIKey("other-phone-key"), "revoked device should lose access")
+	assert.True(t, keyStore.ValidateAPIKey("paired-test-key"), "requesting device should stay paired")
+}
+
+func TestDeleteDevice_UnknownID_NotFound(t *testing.T) {
+	router, keyStore := createDeviceTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api/devices/unknown", nil)
+	addPairedCookie(t, keyStore, req)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDeleteDevice_NotPaired_Unauthorized(t *testing.T) {
+	router, keyStore := createDeviceTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api/devices/"+deviceID(t, keyStore, "other-phone-key"), nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.True(t, keyStore.ValidateAPIKey("other-phone-key"), "unpaired request should not revoke devices")
+}
+
+func TestDeleteDevice_CurrentDevice_ShowsPairForm(t *testing.T) {
+	router, keyStore := createDeviceTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/", nil)
+	req.Header.Set(htmxRequestHeader, "true")
+	addPairedCookie(t, keyStore, req)
+	req.URL.Path = "/api/devices/" + deviceID(t, keyStore, "paired-test-key")
+	router.ServeHTTP(w, req)
+
+	var cleared *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == apiKeyCookieName {
+			cleared = cookie
+		}
+	}
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `id="pair-form"`, "revoking this device should fall back to the pair form")
+	assert.Equal(t, "#device-panel", w.Header().Get("HX-Retarget"))
+	assert.NotNil(t, cleared)
+	assert.Less(t, cleared.MaxAge, 0, "the api-key cookie should be cleared")
+	assert.False(t, keyStore.ValidateAPIKey("paired-test-key"))
+}
+
+func TestDevices_NotAdmin_ListsOnlyItself(t *testing.T) {
+	router, keyStore := createDeviceTestServer(t)
+	assert.NoError(t, keyStore.SaveAPIKey(&security.APIKey{Key: "media-key", TTL: time.Now().Add(time.Hour), Scopes: []string{security.ScopeMedia}}))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/devices", nil)
+	req.AddCookie(&http.Cookie{Name: apiKeyCookieName, Value: "media-key"})
+	router.ServeHTTP(w, req)
+
+	var devices []deviceResponse
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &devices))
+	assert.Len(t, devices, 1, "other devices should be hidden without the admin scope")
+	assert.True(t, devices[0].Current)
+}
+
+func TestDeleteDevice_NotAdminOtherDevice_NotFound(t *testing.T) {
+	router, keyStore := createDeviceTestServer(t)
+	assert.NoError(t, keyStore.SaveAPIKey(&security.APIKey{Key: "media-key", TTL: time.Now().Add(time.Hour), Scopes: []string{security.ScopeMedia}}))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api/devices/"+deviceID(t, keyStore, "other-phone-key"), nil)
+	req.AddCookie(&http.Cookie{Name: apiKeyCookieName, Value: "media-key"})
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.True(t, keyStore.ValidateAPIKey("other-phone-key"), "only admins may revoke other devices")
+}
+
+func TestDeleteDevice_NotAdminCurrentDevice_RevokesIt(t *testing.T) {
+	router, keyStore := createDeviceTestServer(t)
+	assert.NoError(t, keyStore.SaveAPIKey(&security.APIKey{Key: "media-key", TTL: time.Now().Add(time.Hour), Scopes: []string{security.ScopeMedia}}))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api/devices/"+deviceID(t, keyStore, "media-key"), nil)
+	req.AddCookie(&http.Cookie{Name: apiKeyCookieName, Value: "media-key"})
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.False(t, keyStore.ValidateAPIKey("media-key"), "a device should be able to unpair itself")
+}
+
+func TestDevices_FakeClock_MarksExpiryByServerTime(t *testing.T) {
+	fakeClock := clocktest.NewFake(testClockStart)
+	keyStore, _ := createTestKeyStore(t, security.WithClock(fakeClock))
+	router := gin.Default()
+	server := &Server{
+		KeyStore: keyStore,
+		Clock:    fakeClock,
+		Logger:   createTestLogger(),
+	}
+	server.SetupRoutes(router)
+	assert.NoError(t, keyStore.SaveAPIKey(&security.APIKey{Key: "admin-key", TTL: testClockStart.Add(2 * time.Hour), Scopes: []string{security.ScopeAdmin}}))
+	assert.NoError(t, keyStore.SaveAPIKey(&security.APIKey{Key: "old-key", TTL: testClockStart.Add(-time.Minute), DeviceName: "Old phone"}))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/devices", nil)
+	req.Header.Set(htmxRequestHeader, "true")
+	req.AddCookie(&http.Cookie{Name: apiKeyCookieName, Value: "admin-key"})
+	router.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	assert.Equal(t, 1, strings.Count(body, "expired"), "only the key past the server's time should be marked expired")
+	assert.Contains(t, body, `class="device current"`, "a key valid on the server clock should not be marked expired")
+}
+
+func TestDevicesPage_NotPaired_RedirectsToRoot(t *testing.T) {
+	router, _ := createDeviceTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/devices", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, "/", w.Header().Get("Location"))
+}
+
+func TestDevicesPage_Paired_LoadsDevicePanel(t *testing.T) {
+	router, keyStore := createDeviceTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/devices", nil)
+	addPairedCookie(t, keyStore, req)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `hx-get="/api/devices"`)
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"github.com/phasecurve/sway_rm/internal/input"
+)
+
+const (
+	maxInputMessageSize = 4096
+	maxTextLength       = 1024
+	textFormID          = "text"
+	keysFormID          = "keys"
+	keyFormID           = "key"
+	modifierFormID      = "modifier"
+)
+
+var inputUpgrader = websocket.Upgrader{
+	ReadBufferSize:  maxInputMessageSize,
+	WriteBufferSize: 1024,
+}
+
+func (s *Server) getInputSocket(c *gin.Context) {
+	if s.Input == nil {
+		c.Status(http.StatusServiceUnavailable)
+		return
+	}
+
+	conn, err := inputUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		s.Logger.Printf("failed to upgrade input socket: %v", err)
+		return
+	}
+	defer conn.Close()
+	conn.SetReadLimit(maxInputMessageSize)
+
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if messageType != websocket.BinaryMessage {
+			continue
+		}
+		events, err := input.DecodePointerEvents(data)
+		if err != nil {
+			s.Logger.Printf("invalid pointer message: %v", err)
+			continue
+		}
+		for _, event := range events {
+			if err := input.DispatchPointerEvent(s.Input, event); err != nil {
+				s.Logger.Printf("failed to inject pointer event: %v", err)
+			}
+		}
+	}
+}
+
+func (s *Server) postInputText(c *gin.Context) {
+	text := c.PostForm(textFormID)
+	if text == "" || len(text) > maxTextLength {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	if _, err := input.TextToStrokes(text); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if s.Input == nil {
+		c.Status(http.StatusServiceUnavailable)
+		return
+	}
+	if err := input.TypeText(s.Input, text); err != nil {
+		s.Logger.Printf("failed to type text: %v", err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) postInputKeys(c *gin.Context) {
+	combos, err := parseKeyCombos(c)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if s.Input == nil {
+		c.Status(http.StatusServiceUnavailable)
+		return
+	}
+	for _, combo := range combos {
+		if err := input.SendCombo(s.Input, combo); err != nil {
+			s.Logger.Printf("failed to send key combo: %v", err)
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func parseKeyCombos(c *gin.Context) ([]input.KeyCombo, error) {
+	specs := strings.Fields(c.PostForm(keysFormID))
+	if key := strings.TrimSpace(c.PostForm(keyFormID)); key != "" {
+		specs = append(specs, strings.Join(append(c.PostFormArray(modifierFormID), key), "+"))
+	}
+	if len(specs) == 0 {
+		return nil, errors.New("no keys given")
+	}
+	combos := make([]input.KeyCombo, 0, len(specs))
+	for _, spec := range specs {
+		combo, err := input.ParseCombo(spec)
+		if err != nil {
+			return nil, err
+		}
+		combos = append(combos, combo)
+	}
+	return combos, nil
+}
@@ -0,0 +1,240 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phasecurve/sway_rm/internal/input"
+	"github.com/phasecurve/sway_rm/internal/input/inputtest"
+	"github.com/phasecurve/sway_rm/internal/security"
+)
+
+func createInputTestServer(t *testing.T) (*httptest.Server, *security.KeyStore, *inputtest.Recorder) {
+	keyStore, _ := createTestKeyStore(t)
+	recorder := inputtest.NewRecorder()
+	router := gin.Default()
+	server := &Server{
+		KeyStore: keyStore,
+		Input:    recorder,
+		Logger:   createTestLogger(),
+	}
+	server.SetupRoutes(router)
+	httpServer := httptest.NewServer(router)
+	t.Cleanup(httpServer.Close)
+	return httpServer, keyStore, recorder
+}
+
+func dialPairedInputSocket(t *testing.T, httpServer *httptest.Server, keyStore security.KeyStorer) *websocket.Conn {
+	req, _ := http.NewRequest("GET", httpServer.URL, nil)
+	addPairedCookie(t, keyStore, req)
+	header := http.Header{"Cookie": {req.Header.Get("Cookie")}}
+
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/api/input/ws"
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		t.Fatalf("failed to dial input socket: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	return conn
+}
+
+func TestInputSocket_PointerMessages_ReachInjector(t *testing.T) {
+	httpServer, keyStore, recorder := createInputTestServer(t)
+	conn := dialPairedInputSocket(t, httpServer, keyStore)
+
+	message := input.EncodePointerEvents(
+		input.PointerEvent{Type: input.PointerMove, DX: 12, DY: -7},
+		input.PointerEvent{Type: input.PointerClick, Button: input.ButtonLeft},
+		input.PointerEvent{Type: input.PointerScroll, DY: -2},
+	)
+	assert.NoError(t, conn.WriteMessage(websocket.BinaryMessage, message))
+
+	expected := []string{"move 12 -7", "button 0 down", "button 0 up", "scroll -2 0"}
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual(expected, recorder.Events())
+	}, time.Second, 5*time.Millisecond, "pointer events should be injected in order")
+}
+
+func TestInputSocket_MalformedMessage_KeepsConnectionOpen(t *testing.T) {
+	httpServer, keyStore, recorder := createInputTestServer(t)
+	conn := dialPairedInputSocket(t, httpServer, keyStore)
+
+	assert.NoError(t, conn.WriteMessage(websocket.BinaryMessage, []byte{42, 0}))
+	assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("hello")))
+	assert.NoError(t, conn.WriteMessage(websocket.BinaryMessage, input.EncodePointerEvents(input.PointerEvent{Type: input.PointerMove, DX: 1})))
+
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]string{"move 1 0"}, recorder.Events())
+	}, time.Second, 5*time.Millisecond, "bad messages should be skipped without dropping the socket")
+}
+
+func TestInputSocket_NotPaired_Unauthorized(t *testing.T) {
+	httpServer, _, _ := createInputTestServer(t)
+
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/api/input/ws"
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestInputSocket_NoInjector_ServiceUnavailable(t *testing.T) {
+	router := gin.Default()
+	keyStore, _ := createTestKeyStore(t)
+	server := &Server{
+		KeyStore: keyStore,
+		Logger:   createTestLogger(),
+	}
+	server.SetupRoutes(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/input/ws", nil)
+	addPairedCookie(t, keyStore, req)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func createKeyboardTestServer(t *testing.T) (*gin.Engine, *security.KeyStore, *inputtest.Recorder) {
+	keyStore, _ := createTestKeyStore(t)
+	recorder := inputtest.NewRecorder()
+	router := gin.Default()
+	server := &Server{
+		KeyStore: keyStore,
+		Input:    recorder,
+		Logger:   createTestLogger(),
+	}
+	server.SetupRoutes(router)
+	return router, keyStore, recorder
+}
+
+func TestInputText_Paired_TypesStrokes(t *testing.T) {
+	router, keyStore, recorder := createKeyboardTestServer(t)
+
+	w := postPairedForm(t, router, keyStore, "/api/input/text", url.Values{"text": {"a:"}})
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, []string{"key 30 down", "key 30 up", "key 42 down", "key 39 down", "key 39 up", "key 42 up"}, recorder.Events())
+}
+
+func TestInputText_InvalidText_BadRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"empty", ""},
+		{"unsupported character", "naïve"},
+		{"too long", strings.Repeat("a", 1025)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, keyStore, recorder := createKeyboardTestServer(t)
+
+			w := postPairedForm(t, router, keyStore, "/api/input/text", url.Values{"text": {tt.text}})
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Empty(t, recorder.Events(), "invalid text should not type anything")
+		})
+	}
+}
+
+func TestInputText_NotPaired_Unauthorized(t *testing.T) {
+	router, _, recorder := createKeyboardTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/input/text", strings.NewReader("text=hi"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, recorder.Events(), "unpaired request should not type")
+}
+
+func TestInputText_InjectorFails_InternalServerError(t *testing.T) {
+	router, keyStore, recorder := createKeyboardTestServer(t)
+	recorder.Close()
+
+	w := postPairedForm(t, router, keyStore, "/api/input/text", url.Values{"text": {"hi"}})
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestInputKeys_Combos(t *testing.T) {
+	tests := []struct {
+		name     string
+		form     url.Values
+		expected []string
+	}{
+		{
+			name:     "single combo",
+			form:     url.Values{"keys": {"Super+Shift+q"}},
+			expected: []string{"key 125 down", "key 42 down", "key 16 down", "key 16 up", "key 42 up", "key 125 up"},
+		},
+		{
+			name:     "sequence of combos",
+			form:     url.Values{"keys": {"Ctrl+l Enter"}},
+			expected: []string{"key 29 down", "key 38 down", "key 38 up", "key 29 up", "key 28 down", "key 28 up"},
+		},
+		{
+			name:     "modifier toggles with key",
+			form:     url.Values{"modifier": {"Alt", "Shift"}, "key": {"Tab"}},
+			expected: []string{"key 56 down", "key 42 down", "key 15 down", "key 15 up", "key 42 up", "key 56 up"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, keyStore, recorder := createKeyboardTestServer(t)
+
+			w := postPairedForm(t, router, keyStore, "/api/input/keys", tt.form)
+
+			assert.Equal(t, http.StatusNoContent, w.Code)
+			assert.Equal(t, tt.expected, recorder.Events())
+		})
+	}
+}
+
+func TestInputKeys_InvalidCombo_BadRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		form url.Values
+	}{
+		{"no keys", url.Values{}},
+		{"unknown key", url.Values{"keys": {"Ctrl+Nope"}}},
+		{"unknown modifier", url.Values{"modifier": {"Hyper"}, "key": {"q"}}},
+		{"one bad combo in sequence", url.Values{"keys": {"Enter q+Ctrl"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, keyStore, recorder := createKeyboardTestServer(t)
+
+			w := postPairedForm(t, router, keyStore, "/api/input/keys", tt.form)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Empty(t, recorder.Events(), "invalid combos should not press any keys")
+		})
+	}
+}
+
+func TestInputKeys_NoInjector_ServiceUnavailable(t *testing.T) {
+	router := gin.Default()
+	keyStore, _ := createTestKeyStore(t)
+	server := &Server{
+		KeyStore: keyStore,
+		Logger:   createTestLogger(),
+	}
+	server.SetupRoutes(router)
+
+	w := postPairedForm(t, router, keyStore, "/api/input/keys", url.Values{"keys": {"Enter"}})
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"time"
+
+	"github.com/phasecurve/sway_rm/internal/middleware"
+)
+
+// SessionLifetimes controls how long pairing codes and sessions last. Zero
+// fields fall back to DefaultSessionLifetimes.
+type SessionLifetimes struct {
+	// SessionTTL is how long a newly paired session lasts.
+	SessionTTL time.Duration
+	// RefreshExtension keeps a session alive this long after each request.
+	RefreshExtension time.Duration
+	// MaxSession is the longest a session can last after pairing, however
+	// often it's used.
+	MaxSession  time.Duration
+	PairingCode time.Duration
+}
+
+var DefaultSessionLifetimes = SessionLifetimes{
+	SessionTTL:       time.Hour,
+	RefreshExtension: 30 * time.Minute,
+	MaxSession:       30 * 24 * time.Hour,
+	PairingCode:      defaultPairingCodeLifetime,
+}
+
+func (l SessionLifetimes) withDefaults() SessionLifetimes {
+	if l.SessionTTL <= 0 {
+		l.SessionTTL = DefaultSessionLifetimes.SessionTTL
+	}
+	if l.RefreshExtension <= 0 {
+		l.RefreshExtension = DefaultSessionLifetimes.RefreshExtension
+	}
+	if l.MaxSession <= 0 {
+		l.MaxSession = DefaultSessionLifetimes.MaxSession
+	}
+	if l.PairingCode <= 0 {
+		l.PairingCode = DefaultSessionLifetimes.PairingCode
+	}
+	return l
+}
+
+func (l SessionLifetimes) refreshPolicy() middleware.RefreshPolicy {
+	return middleware.RefreshPolicy{
+		Extension:   l.RefreshExtension,
+		MaxLifetime: l.MaxSession,
+	}
+}
@@ -0,0 +1,181 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phasecurve/sway_rm/internal/clock/clocktest"
+	"github.com/phasecurve/sway_rm/internal/security"
+)
+
+var testLifetimes = SessionLifetimes{
+	SessionTTL:       2 * time.Hour,
+	RefreshExtension: 45 * time.Minute,
+	MaxSession:       4 * time.Hour,
+	PairingCode:      time.Minute,
+}
+
+func createLifetimeTestServer(t *testing.T, lifetimes SessionLifetimes) (*gin.Engine, *security.KeyStore, *clocktest.Fake) {
+	fakeClock := clocktest.NewFake(testClockStart)
+	keyStore, _ := createTestKeyStore(t, security.WithClock(fakeClock))
+	server := NewServer(
+		WithKeyStore(keyStore),
+		WithClock(fakeClock),
+		WithShortCodeGenerator(fakeShortCodeGenerator()),
+		WithAPICodeGenerator(func() (string, error) { return "lifetime-key", nil }),
+		WithSessionLifetimes(lifetimes),
+		WithOutput(io.Discard),
+		WithLogger(createTestLogger()),
+	)
+	router := gin.New()
+	server.SetupRoutes(router)
+	return router, keyStore, fakeClock
+}
+
+func pairWithFakeCode(router *gin.Engine) *httptest.ResponseRecorder {
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/pair", strings.NewReader(url.Values{shortCodeFormID: {"123456"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func statusWithCookie(router *gin.Engine, apiKey string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/status", nil)
+	req.AddCookie(&http.Cookie{Name: apiKeyCookieName, Value: apiKey})
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSessionLifetimes_ZeroFields_UseDefaults(t *testing.T) {
+	lifetimes := SessionLifetimes{RefreshExtension: time.Minute}.withDefaults()
+
+	assert.Equal(t, SessionLifetimes{
+		SessionTTL:       time.Hour,
+		RefreshExtension: time.Minute,
+		MaxSession:       30 * 24 * time.Hour,
+		PairingCode:      5 * time.Minute,
+	}, lifetimes)
+}
+
+func TestPair_ConfiguredSessionTTL_CookieMatchesStoredExpiry(t *testing.T) {
+	router, keyStore, _ := createLifetimeTestServer(t, testLifetimes)
+
+	w := pairWithFakeCode(router)
+
+	key, err := keyStore.GetAPIKey("lifetime-key")
+	assert.NoError(t, err)
+	assert.Equal(t, testClockStart.Add(2*time.Hour), key.TTL.UTC())
+	if cookie := apiKeyCookie(w); assert.NotNil(t, cookie) {
+		assert.Equal(t, 7200, cookie.MaxAge)
+	}
+}
+
+func TestPair_SessionTTLLongerThanMax_Capped(t *testing.T) {
+	router, keyStore, _ := createLifetimeTestServer(t, SessionLifetimes{SessionTTL: 8 * time.Hour, MaxSession: 3 * time.Hour})
+
+	w := pairWithFakeCode(router)
+
+	key, _ := keyStore.GetAPIKey("lifetime-key")
+	assert.Equal(t, testClockStart.Add(3*time.Hour), key.TTL.UTC())
+	if cookie := apiKeyCookie(w); assert.NotNil(t, cookie) {
+		assert.Equal(t, 3*3600, cookie.MaxAge)
+	}
+}
+
+func TestPairingCode_ConfiguredLifetime(t *testing.T) {
+	router, _, fakeClock := createLifetimeTestServer(t, testLifetimes)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	fakeClock.Advance(time.Minute)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/pair", strings.NewReader(url.Values{shortCodeFormID: {"123456"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	router.ServeHTTP(w, req)
+
+	assert.Contains(t, w.Body.String(), "Invalid pairing code", "the code should expire after the configured minute")
+}
+
+func TestPairRefresh_ConfiguredExtension_CookieMaxAgeFollows(t *testing.T) {
+	router, keyStore, fakeClock := createLifetimeTestServer(t, testLifetimes)
+	pairWithFakeCode(router)
+	fakeClock.Advance(100 * time.Minute)
+
+	w := statusWithCookie(router, "lifetime-key")
+
+	key, _ := keyStore.GetAPIKey("lifetime-key")
+	assert.Equal(t, testClockStart.Add(145*time.Minute), key.TTL.UTC(), "refresh should extend 45 minutes from now")
+	if cookie := apiKeyCookie(w); assert.NotNil(t, cookie, "refresh should resend the cookie") {
+		assert.Equal(t, "lifetime-key", cookie.Value)
+		assert.Equal(t, 45*60, cookie.MaxAge, "cookie should expire with the stored key")
+		assert.True(t, cookie.HttpOnly)
+	}
+}
+
+func TestPairRefresh_UnchangedExpiry_CookieStillInSync(t *testing.T) {
+	router, _, fakeClock := createLifetimeTestServer(t, testLifetimes)
+	pairWithFakeCode(router)
+	fakeClock.Advance(10 * time.Minute)
+
+	w := statusWithCookie(router, "lifetime-key")
+
+	if cookie := apiKeyCookie(w); assert.NotNil(t, cookie) {
+		assert.Equal(t, 110*60, cookie.MaxAge, "cookie should count down with the stored expiry")
+	}
+}
+
+func TestPairRefresh_MaxSession_StopsExtending(t *testing.T) {
+	router, keyStore, fakeClock := createLifetimeTestServer(t, testLifetimes)
+	pairWithFakeCode(router)
+
+	for elapsed := 30 * time.Minute; elapsed < 4*time.Hour; elapsed += 30 * time.Minute {
+		fakeClock.Set(testClockStart.Add(elapsed))
+		assert.Equal(t, http.StatusOK, statusWithCookie(router, "lifetime-key").Code, "session should stay alive while used at %s", elapsed)
+	}
+	key, _ := keyStore.GetAPIKey("lifetime-key")
+	assert.Equal(t, testClockStart.Add(4*time.Hour), key.TTL.UTC(), "refresh should not extend past the maximum session lifetime")
+
+	fakeClock.Set(testClockStart.Add(4 * time.Hour))
+	w := statusWithCookie(router, "lifetime-key")
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "the session should end at the maximum lifetime however often it's used")
+	assert.Nil(t, apiKeyCookie(w), "expired sessions should not get a refreshed cookie")
+}
+
+func TestPairRefresh_NearMaxSession_CookieCappedToo(t *testing.T) {
+	router, _, fakeClock := createLifetimeTestServer(t, testLifetimes)
+	pairWithFakeCode(router)
+	for _, elapsed := range []time.Duration{110 * time.Minute, 150 * time.Minute, 190 * time.Minute} {
+		fakeClock.Set(testClockStart.Add(elapsed))
+		statusWithCookie(router, "lifetime-key")
+	}
+	fakeClock.Set(testClockStart.Add(230 * time.Minute))
+
+	w := statusWithCookie(router, "lifetime-key")
+
+	if cookie := apiKeyCookie(w); assert.NotNil(t, cookie) {
+		assert.Equal(t, 10*60, cookie.MaxAge)
+	}
+}
+
+func TestPairRefresh_KeyWithoutPairedAt_StartsMaxLifetimeNow(t *testing.T) {
+	router, keyStore, fakeClock := createLifetimeTestServer(t, testLifetimes)
+	assert.NoError(t, keyStore.SaveAPIKey(&security.APIKey{Key: "migrated-key", TTL: testClockStart.Add(time.Hour), Scopes: []string{security.ScopeAdmin}}))
+	fakeClock.Advance(time.Minute)
+
+	assert.Equal(t, http.StatusOK, statusWithCookie(router, "migrated-key").Code)
+
+	key, _ := keyStore.GetAPIKey("migrated-key")
+	assert.Equal(t, testClockStart.Add(time.Minute), key.PairedAt.UTC())
+	assert.Equal(t, testClockStart.Add(time.Hour), key.TTL.UTC())
+}
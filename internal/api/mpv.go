@@ -0,0 +1,237 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/phasecurve/sway_rm/internal/components"
+	"github.com/phasecurve/sway_rm/internal/mpv"
+)
+
+const (
+	seekModeFormID     = "mode"
+	seekPositionFormID = "position"
+	volumeFormID       = "volume"
+	volumeDeltaFormID  = "delta"
+	speedFormID        = "speed"
+	maxVolume          = 130
+	minSpeed           = 0.01
+	maxSpeed           = 100
+	nowPlayingEvent    = "now-playing"
+	instanceParam      = "instance"
+)
+
+var mpvTransportCommands = map[string][]any{
+	"play":   {"set_property", "pause", false},
+	"pause":  {"set_property", "pause", true},
+	"toggle": {"cycle", "pause"},
+	"stop":   {"stop"},
+	"next":   {"playlist-next"},
+	"prev":   {"playlist-prev"},
+}
+
+var mpvSeekModes = map[string]string{
+	"relative": "relative",
+	"absolute": "absolute",
+	"percent":  "absolute-percent",
+}
+
+func (s *Server) postMPVTransport(c *gin.Context) {
+	command, ok := mpvTransportCommands[c.Param("action")]
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	s.runMPVCommand(c, command...)
+}
+
+func (s *Server) postMPVSeek(c *gin.Context) {
+	mode, ok := mpvSeekModes[c.DefaultPostForm(seekModeFormID, "relative")]
+	if !ok {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	position, err := parseFiniteFloat(c.PostForm(seekPositionFormID))
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	if mode == "absolute-percent" && (position < 0 || position > 100) {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	s.runMPVCommand(c, "seek", position, mode)
+}
+
+func (s *Server) postMPVVolume(c *gin.Context) {
+	if delta := c.PostForm(volumeDeltaFormID); delta != "" {
+		change, err := parseFiniteFloat(delta)
+		if err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		s.runMPVCommand(c, "add", "volume", change)
+		return
+	}
+
+	volume, err := parseFiniteFloat(c.PostForm(volumeFormID))
+	if err != nil || volume < 0 || volume > maxVolume {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	s.runMPVCommand(c, "set_property", "volume", volume)
+}
+
+func (s *Server) postMPVSpeed(c *gin.Context) {
+	speed, err := parseFiniteFloat(c.PostForm(speedFormID))
+	if err != nil || speed < minSpeed || speed > maxSpeed {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	s.runMPVCommand(c, "set_property", "speed", speed)
+}
+
+// parseFiniteFloat parses a form number, refusing NaN and infinities:
+// NaN slips past range checks and neither can be sent to mpv as JSON.
+func parseFiniteFloat(value string) (float64, error) {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, errors.New("not a finite number")
+	}
+	return f, nil
+}
+
+func (s *Server) runMPVCommand(c *gin.Context, args ...any) {
+	player, ok := s.resolveMPV(c)
+	if !ok {
+		return
+	}
+	if _, err := player.Command(args...); err != nil {
+		s.Logger.Printf("failed to run mpv command %v: %v", args, err)
+		c.Status(http.StatusBadGateway)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) getMPVEvents(c *gin.Context) {
+	nowPlaying := s.NowPlaying
+	if id := c.Query(instanceParam); id != "" {
+		if s.MPVInstances == nil {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		tracker, err := s.MPVInstances.NowPlaying(id)
+		if err != nil {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		nowPlaying = tracker
+	}
+	if nowPlaying == nil {
+		c.Status(http.StatusServiceUnavailable)
+		return
+	}
+
+	states, unsubscribe := nowPlaying.Subscribe()
+	defer unsubscribe()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	s.sendNowPlaying(c, nowPlaying.State())
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case state, ok := <-states:
+			if !ok {
+				return
+			}
+			s.sendNowPlaying(c, state)
+		}
+	}
+}
+
+func (s *Server) sendNowPlaying(c *gin.Context, state mpv.NowPlaying) {
+	var fragment bytes.Buffer
+	if err := components.NowPlayingDetails(state).Render(c.Request.Context(), &fragment); err != nil {
+		s.Logger.Printf("failed to render now playing: %v", err)
+		return
+	}
+	c.SSEvent(nowPlayingEvent, fragment.String())
+	c.Writer.Flush()
+}
+
+func (s *Server) getMPVInstances(c *gin.Context) {
+	s.renderMPVInstances(c)
+}
+
+func (s *Server) postActivateMPVInstance(c *gin.Context) {
+	if s.MPVInstances == nil {
+		c.Status(http.StatusServiceUnavailable)
+		return
+	}
+	if err := s.MPVInstances.SetActive(c.Param("id")); err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	if isHTMXRequest(c) {
+		s.renderMPVInstances(c)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) renderMPVInstances(c *gin.Context) {
+	var instances []mpv.Instance
+	if s.MPVInstances != nil {
+		instances = s.MPVInstances.Instances()
+	}
+
+	if isHTMXRequest(c) {
+		c.Header("Content-Type", "text/html")
+		component := components.MPVInstanceList(instances)
+		component.Render(c.Request.Context(), c.Writer)
+		return
+	}
+	c.JSON(http.StatusOK, instances)
+}
+
+func (s *Server) resolveMPV(c *gin.Context) (mpv.Player, bool) {
+	id := c.Query(instanceParam)
+	if id == "" {
+		id = c.PostForm(instanceParam)
+	}
+
+	if id == "" && s.MPV != nil {
+		return s.MPV, true
+	}
+	if s.MPVInstances == nil {
+		if id != "" {
+			c.Status(http.StatusNotFound)
+		} else {
+			c.Status(http.StatusServiceUnavailable)
+		}
+		return nil, false
+	}
+
+	player, err := s.MPVInstances.Player(id)
+	if errors.Is(err, mpv.ErrUnknownInstance) {
+		c.Status(http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		c.Status(http.StatusServiceUnavailable)
+		return nil, false
+	}
+	return player, true
+}
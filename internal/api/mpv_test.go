@@ -0,0 +1,371 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phasecurve/sway_rm/internal/mpv"
+	"github.com/phasecurve/sway_rm/internal/mpv/mpvtest"
+	"github.com/phasecurve/sway_rm/internal/security"
+)
+
+func createMPVTestServer(t *testing.T) (*gin.Engine, *security.KeyStore, *mpvtest.Server) {
+	router := gin.Default()
+	keyStore, _ := createTestKeyStore(t)
+	mpvClient, fake := createTestMPV(t)
+	server := &Server{
+		KeyStore: keyStore,
+		MPV:      mpvClient,
+		Logger:   createTestLogger(),
+	}
+	server.SetupRoutes(router)
+	return router, keyStore, fake
+}
+
+func TestMPVTransport_Paired_SendsCommand(t *testing.T) {
+	tests := []struct {
+		action   string
+		expected []any
+	}{
+		{"play", []any{"set_property", "pause", false}},
+		{"pause", []any{"set_property", "pause", true}},
+		{"toggle", []any{"cycle", "pause"}},
+		{"stop", []any{"stop"}},
+		{"next", []any{"playlist-next"}},
+		{"prev", []any{"playlist-prev"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			router, keyStore, fake := createMPVTestServer(t)
+
+			w := postPairedForm(t, router, keyStore, "/api/mpv/"+tt.action, nil)
+
+			assert.Equal(t, http.StatusNoContent, w.Code)
+			assert.Equal(t, [][]any{tt.expected}, fake.Commands())
+		})
+	}
+}
+
+func TestMPVTransport_UnknownAction_NotFound(t *testing.T) {
+	router, keyStore, fake := createMPVTestServer(t)
+
+	w := postPairedForm(t, router, keyStore, "/api/mpv/quit", nil)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, fake.Commands(), "unknown actions should not reach mpv")
+}
+
+func TestMPVTransport_NotPaired_Unauthorized(t *testing.T) {
+	router, _, fake := createMPVTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/mpv/pause", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, fake.Commands(), "unpaired request should not control mpv")
+}
+
+func TestMPVSeek_Modes(t *testing.T) {
+	tests := []struct {
+		mode     string
+		position string
+		expected []any
+	}{
+		{"relative", "-10", []any{"seek", float64(-10), "relative"}},
+		{"absolute", "90.5", []any{"seek", 90.5, "absolute"}},
+		{"percent", "50", []any{"seek", float64(50), "absolute-percent"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			router, keyStore, fake := createMPVTestServer(t)
+
+			w := postPairedForm(t, router, keyStore, "/api/mpv/seek", url.Values{"mode": {tt.mode}, "position": {tt.position}})
+
+			assert.Equal(t, http.StatusNoContent, w.Code)
+			assert.Equal(t, [][]any{tt.expected}, fake.Commands())
+		})
+	}
+}
+
+func TestMPVSeek_InvalidInput_BadRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		form url.Values
+	}{
+		{"unknown mode", url.Values{"mode": {"chapter"}, "position": {"1"}}},
+		{"missing position", url.Values{"mode": {"relative"}}},
+		{"percent out of range", url.Values{"mode": {"percent"}, "position": {"150"}}},
+		{"relative NaN", url.Values{"mode": {"relative"}, "position": {"NaN"}}},
+		{"relative infinity", url.Values{"mode": {"relative"}, "position": {"Inf"}}},
+		{"absolute negative infinity", url.Values{"mode": {"absolute"}, "position": {"-Inf"}}},
+		{"percent NaN", url.Values{"mode": {"percent"}, "position": {"NaN"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, keyStore, fake := createMPVTestServer(t)
+
+			w := postPairedForm(t, router, keyStore, "/api/mpv/seek", tt.form)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Empty(t, fake.Commands())
+		})
+	}
+}
+
+func TestMPVVolume_AbsoluteAndDelta(t *testing.T) {
+	router, keyStore, fake := createMPVTestServer(t)
+
+	absolute := postPairedForm(t, router, keyStore, "/api/mpv/volume", url.Values{"volume": {"80"}})
+	delta := postPairedForm(t, router, keyStore, "/api/mpv/volume", url.Values{"delta": {"-5"}})
+
+	assert.Equal(t, http.StatusNoContent, absolute.Code)
+	assert.Equal(t, http.StatusNoContent, delta.Code)
+	assert.Equal(t, [][]any{
+		{"set_property", "volume", float64(80)},
+		{"add", "volume", float64(-5)},
+	}, fake.Commands())
+}
+
+func TestMPVVolume_InvalidInput_BadRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		form url.Values
+	}{
+		{"out of range", url.Values{"volume": {"500"}}},
+		{"NaN", url.Values{"volume": {"NaN"}}},
+		{"infinity", url.Values{"volume": {"Inf"}}},
+		{"delta NaN", url.Values{"delta": {"NaN"}}},
+		{"delta infinity", url.Values{"delta": {"+Inf"}}},
+		{"delta negative infinity", url.Values{"delta": {"-Inf"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, keyStore, fake := createMPVTestServer(t)
+
+			w := postPairedForm(t, router, keyStore, "/api/mpv/volume", tt.form)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Empty(t, fake.Commands())
+		})
+	}
+}
+
+func TestMPVSpeed_SetsSpeed(t *testing.T) {
+	router, keyStore, fake := createMPVTestServer(t)
+
+	w := postPairedForm(t, router, keyStore, "/api/mpv/speed", url.Values{"speed": {"1.5"}})
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, 1.5, fake.Property("speed"))
+}
+
+func TestMPVSpeed_Invalid_BadRequest(t *testing.T) {
+	for _, speed := range []string{"0", "NaN", "Inf", "-Inf"} {
+		t.Run(speed, func(t *testing.T) {
+			router, keyStore, fake := createMPVTestServer(t)
+
+			w := postPairedForm(t, router, keyStore, "/api/mpv/speed", url.Values{"speed": {speed}})
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Empty(t, fake.Commands())
+		})
+	}
+}
+
+func TestMPV_NotRunning_ReturnsBadGateway(t *testing.T) {
+	router, keyStore, fake := createMPVTestServer(t)
+	fake.Close()
+
+	w := postPairedForm(t, router, keyStore, "/api/mpv/pause", nil)
+
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+}
+
+func TestRoot_Paired_ShowsMPVRemote(t *testing.T) {
+	router, keyStore, _ := createMPVTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	addPairedCookie(t, keyStore, req)
+	router.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	assert.Contains(t, body, `id="mpv-remote"`, "paired state should show the mpv remote")
+	assert.Contains(t, body, `hx-post="/api/mpv/toggle"`, "remote should have a play/pause button")
+	assert.Contains(t, body, `type="range"`, "remote should have a seek slider")
+}
+
+func TestMPVEvents_Paired_StreamsNowPlaying(t *testing.T) {
+	keyStore, _ := createTestKeyStore(t)
+	mpvClient, fake := createTestMPV(t)
+	tracker := mpv.NewTracker(mpvClient)
+	t.Cleanup(tracker.Close)
+	tracker.Start()
+	router := gin.Default()
+	server := &Server{
+		KeyStore:   keyStore,
+		MPV:        mpvClient,
+		NowPlaying: tracker,
+		Logger:     createTestLogger(),
+	}
+	server.SetupRoutes(router)
+	httpServer := httptest.NewServer(router)
+	t.Cleanup(httpServer.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", httpServer.URL+"/api/mpv/events", nil)
+	addPairedCookie(t, keyStore, req)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	lines := bufio.NewScanner(resp.Body)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/event-stream")
+	readSSEData(t, lines, "now-playing-")
+
+	assert.Eventually(t, func() bool { return fake.Observers("media-title") == 1 }, time.Second, 5*time.Millisecond)
+	fake.SetProperty("media-title", "Big Buck Bunny")
+
+	readSSEData(t, lines, "Big Buck Bunny")
+}
+
+func TestMPVEvents_NotPaired_Unauthorized(t *testing.T) {
+	router, _, _ := createMPVTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/mpv/events", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestRoot_Paired_ConnectsNowPlayingStream(t *testing.T) {
+	router, keyStore, _ := createMPVTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	addPairedCookie(t, keyStore, req)
+	router.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	assert.Contains(t, body, `hx-ext="sse"`, "now playing should use the htmx sse extension")
+	assert.Contains(t, body, `sse-connect="/api/mpv/events"`, "now playing should connect to the event stream")
+}
+
+func createMPVRegistryTestServer(t *testing.T) (*gin.Engine, *security.KeyStore, map[string]*mpvtest.Server) {
+	dir := mpvtest.TempDir(t)
+	fakes := map[string]*mpvtest.Server{
+		"mpv-a": mpvtest.NewServerAt(t, filepath.Join(dir, "mpv-a.sock")),
+		"mpv-b": mpvtest.NewServerAt(t, filepath.Join(dir, "mpv-b.sock")),
+	}
+	registry := mpv.NewRegistry(filepath.Join(dir, "mpv-*.sock"))
+	t.Cleanup(registry.Close)
+	registry.Scan()
+
+	router := gin.Default()
+	keyStore, _ := createTestKeyStore(t)
+	server := &Server{
+		KeyStore:     keyStore,
+		MPV:          registry,
+		MPVInstances: registry,
+		Logger:       createTestLogger(),
+	}
+	server.SetupRoutes(router)
+	return router, keyStore, fakes
+}
+
+func TestMPVInstances_Paired_ListsInstances(t *testing.T) {
+	router, keyStore, fakes := createMPVRegistryTestServer(t)
+	fakes["mpv-b"].SetProperty("media-title", "Sintel")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/mpv/instances", nil)
+	addPairedCookie(t, keyStore, req)
+	router.ServeHTTP(w, req)
+
+	var instances []mpv.Instance
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &instances))
+	assert.Len(t, instances, 2)
+	assert.True(t, instances[0].Active)
+	assert.Equal(t, "mpv-b", instances[1].ID)
+}
+
+func TestMPVInstances_Activate_ChangesDefaultTarget(t *testing.T) {
+	router, keyStore, fakes := createMPVRegistryTestServer(t)
+
+	activate := postPairedForm(t, router, keyStore, "/api/mpv/instances/mpv-b/activate", nil)
+	pause := postPairedForm(t, router, keyStore, "/api/mpv/pause", nil)
+
+	assert.Equal(t, http.StatusNoContent, activate.Code)
+	assert.Equal(t, http.StatusNoContent, pause.Code)
+	assert.Equal(t, true, fakes["mpv-b"].Property("pause"), "activated instance should be controlled")
+	assert.Nil(t, fakes["mpv-a"].Property("pause"), "previous instance should be left alone")
+}
+
+func TestMPVInstances_ActivateUnknown_NotFound(t *testing.T) {
+	router, keyStore, _ := createMPVRegistryTestServer(t)
+
+	w := postPairedForm(t, router, keyStore, "/api/mpv/instances/mpv-missing/activate", nil)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestMPVTransport_InstanceParam_TargetsInstance(t *testing.T) {
+	router, keyStore, fakes := createMPVRegistryTestServer(t)
+
+	w := postPairedForm(t, router, keyStore, "/api/mpv/stop?instance=mpv-b", nil)
+	seek := postPairedForm(t, router, keyStore, "/api/mpv/seek", url.Values{"instance": {"mpv-b"}, "position": {"5"}})
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, http.StatusNoContent, seek.Code)
+	assert.Contains(t, fakes["mpv-b"].Commands(), []any{"stop"})
+	assert.Contains(t, fakes["mpv-b"].Commands(), []any{"seek", float64(5), "relative"})
+	assert.NotContains(t, fakes["mpv-a"].Commands(), []any{"stop"}, "active instance should not receive targeted commands")
+}
+
+func TestMPVTransport_UnknownInstance_NotFound(t *testing.T) {
+	router, keyStore, _ := createMPVRegistryTestServer(t)
+
+	w := postPairedForm(t, router, keyStore, "/api/mpv/stop?instance=mpv-missing", nil)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestMPVEvents_UnknownInstance_NotFound(t *testing.T) {
+	router, keyStore, _ := createMPVRegistryTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/mpv/events?instance=mpv-missing", nil)
+	addPairedCookie(t, keyStore, req)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestMPVInstances_HTMXRequest_RendersPicker(t *testing.T) {
+	router, keyStore, _ := createMPVRegistryTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/mpv/instances", nil)
+	req.Header.Set("HX-Request", "true")
+	addPairedCookie(t, keyStore, req)
+	router.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	assert.Contains(t, body, `class="mpv-instance active"`, "active instance should be marked")
+	assert.Contains(t, body, `hx-post="/api/mpv/instances/mpv-b/activate"`, "instances should be selectable")
+}
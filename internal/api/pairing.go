@@ -0,0 +1,187 @@
+package api
+
+import (
+	"crypto/subtle"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/phasecurve/sway_rm/internal/clock"
+	"github.com/phasecurve/sway_rm/internal/security"
+)
+
+const defaultPairingCodeLifetime = 5 * time.Minute
+
+// PairingCode is a short code for typing in and, when a token generator is
+// configured, a token for the QR pairing link. Both are single use: pairing
+// with either one consumes the other. Scopes are granted to the device that
+// pairs with it.
+type PairingCode struct {
+	Code   string
+	Token  string
+	Expiry time.Time
+	Scopes []string
+}
+
+type PairingManager struct {
+	mu       sync.Mutex
+	generate ShortCodeGenerator
+	tokens   PairingTokenGenerator
+	clock    clock.Clock
+	lifetime time.Duration
+	defaults []string
+	code     string
+	token    string
+	expiry   time.Time
+	scopes   []string
+}
+
+// NewPairingManager creates a manager; tokens may be nil to disable link pairing.
+func NewPairingManager(generate ShortCodeGenerator, tokens PairingTokenGenerator, clk clock.Clock, lifetime time.Duration) *PairingManager {
+	return &PairingManager{
+		generate: generate,
+		tokens:   tokens,
+		clock:    clk,
+		lifetime: lifetime,
+		defaults: []string{security.ScopeMedia},
+	}
+}
+
+// SetDefaultScopes sets the scopes each new code starts with.
+func (m *PairingManager) SetDefaultScopes(scopes []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.defaults = slices.Clone(scopes)
+}
+
+func (m *PairingManager) DefaultScopes() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.defaults)
+}
+
+// SetScopes changes what the current code grants. The next code goes back
+// to the defaults.
+func (m *PairingManager) SetScopes(scopes []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scopes = slices.Clone(scopes)
+}
+
+func (m *PairingManager) Scopes() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.scopes)
+}
+
+func (m *PairingManager) Code() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.code
+}
+
+func (m *PairingManager) Token() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.token
+}
+
+func (m *PairingManager) Expiry() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.expiry
+}
+
+// GenerateIfExpired returns the live pairing code, replacing it first if
+// none is set or it has expired. generated reports whether the code is new
+// and therefore still needs publishing.
+func (m *PairingManager) GenerateIfExpired() (code PairingCode, generated bool, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.isLive() {
+		return m.current(), false, nil
+	}
+	if err := m.replace(); err != nil {
+		return PairingCode{}, false, err
+	}
+	return m.current(), true, nil
+}
+
+func (m *PairingManager) Rotate() (PairingCode, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clear()
+	if err := m.replace(); err != nil {
+		return PairingCode{}, err
+	}
+	return m.current(), nil
+}
+
+// ConsumeIfMatches clears the pairing code when the candidate matches a
+// live code, so concurrent submissions of the same code pair only once. It
+// returns the scopes the code granted.
+func (m *PairingManager) ConsumeIfMatches(candidate string) ([]string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.isLive() {
+		return nil, false
+	}
+	if subtle.ConstantTimeCompare([]byte(candidate), []byte(m.code)) != 1 {
+		return nil, false
+	}
+	scopes := m.scopes
+	m.clear()
+	return scopes, true
+}
+
+// ConsumeTokenIfMatches is ConsumeIfMatches for the pairing link token.
+func (m *PairingManager) ConsumeTokenIfMatches(candidate string) ([]string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.isLive() || m.token == "" {
+		return nil, false
+	}
+	if subtle.ConstantTimeCompare([]byte(candidate), []byte(m.token)) != 1 {
+		return nil, false
+	}
+	scopes := m.scopes
+	m.clear()
+	return scopes, true
+}
+
+func (m *PairingManager) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clear()
+}
+
+func (m *PairingManager) isLive() bool {
+	return m.code != "" && m.clock.Now().Before(m.expiry)
+}
+
+func (m *PairingManager) current() PairingCode {
+	return PairingCode{Code: m.code, Token: m.token, Expiry: m.expiry, Scopes: slices.Clone(m.scopes)}
+}
+
+func (m *PairingManager) clear() {
+	m.code = ""
+	m.token = ""
+}
+
+func (m *PairingManager) replace() error {
+	code, err := m.generate()
+	if err != nil {
+		return err
+	}
+	var token string
+	if m.tokens != nil {
+		if token, err = m.tokens(); err != nil {
+			return err
+		}
+	}
+	m.code = code
+	m.token = token
+	m.expiry = m.clock.Now().Add(m.lifetime)
+	m.scopes = slices.Clone(m.defaults)
+	return nil
+}
@@ -0,0 +1,320 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phasecurve/sway_rm/internal/clock/clocktest"
+)
+
+func countingGenerator() (ShortCodeGenerator, *atomic.Int64) {
+	var calls atomic.Int64
+	return func() (string, error) {
+		return fmt.Sprintf("CODE%d", calls.Add(1)), nil
+	}, &calls
+}
+
+func TestPairingManager_Code_EmptyUntilGenerated(t *testing.T) {
+	generate, _ := countingGenerator()
+	manager := NewPairingManager(generate, nil, clocktest.NewFake(testClockStart), 5*time.Minute)
+
+	assert.Empty(t, manager.Code(), "should return empty when no code is set")
+
+	code, generated, err := manager.GenerateIfExpired()
+	assert.NoError(t, err)
+	assert.True(t, generated)
+	assert.Equal(t, "CODE1", code.Code)
+	assert.Equal(t, "CODE1", manager.Code(), "should return the code once set")
+	assert.Equal(t, testClockStart.Add(5*time.Minute), manager.Expiry())
+}
+
+func TestPairingManager_GenerateIfExpired_KeepsLiveCode(t *testing.T) {
+	generate, calls := countingGenerator()
+	fakeClock := clocktest.NewFake(testClockStart)
+	manager := NewPairingManager(generate, nil, fakeClock, 5*time.Minute)
+	manager.GenerateIfExpired()
+
+	fakeClock.Advance(4 * time.Minute)
+	code, generated, _ := manager.GenerateIfExpired()
+
+	assert.False(t, generated)
+	assert.Equal(t, "CODE1", code.Code)
+	assert.Equal(t, int64(1), calls.Load())
+}
+
+func TestPairingManager_GenerateIfExpired_ReplacesExpiredCode(t *testing.T) {
+	generate, _ := countingGenerator()
+	fakeClock := clocktest.NewFake(testClockStart)
+	manager := NewPairingManager(generate, nil, fakeClock, 5*time.Minute)
+	manager.GenerateIfExpired()
+
+	fakeClock.Advance(5 * time.Minute)
+	code, generated, _ := manager.GenerateIfExpired()
+
+	assert.True(t, generated)
+	assert.Equal(t, "CODE2", code.Code)
+}
+
+func TestPairingManager_GenerateIfExpired_GeneratorFails_KeepsNoCode(t *testing.T) {
+	manager := NewPairingManager(func() (string, error) { return "", errors.New("entropy exhausted") }, nil, clocktest.NewFake(testClockStart), time.Minute)
+
+	_, generated, err := manager.GenerateIfExpired()
+
+	assert.ErrorContains(t, err, "entropy exhausted")
+	assert.False(t, generated)
+	assert.Empty(t, manager.Code())
+}
+
+func TestPairingManager_ConsumeIfMatches(t *testing.T) {
+	tests := []struct {
+		name      string
+		elapsed   time.Duration
+		candidate string
+		expected  bool
+	}{
+		{"matching live code", 0, "CODE1", true},
+		{"wrong code", 0, "CODE2", false},
+		{"empty candidate", 0, "", false},
+		{"prefix of code", 0, "CODE", false},
+		{"expired code", 5 * time.Minute, "CODE1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generate, _ := countingGenerator()
+			fakeClock := clocktest.NewFake(testClockStart)
+			manager := NewPairingManager(generate, nil, fakeClock, 5*time.Minute)
+			manager.GenerateIfExpired()
+			fakeClock.Advance(tt.elapsed)
+
+			assert.Equal(t, tt.expected, matched(manager.ConsumeIfMatches(tt.candidate)))
+		})
+	}
+}
+
+func TestPairingManager_ConsumeIfMatches_SingleUse(t *testing.T) {
+	generate, _ := countingGenerator()
+	manager := NewPairingManager(generate, nil, clocktest.NewFake(testClockStart), 5*time.Minute)
+	manager.GenerateIfExpired()
+
+	assert.True(t, matched(manager.ConsumeIfMatches("CODE1")))
+	assert.False(t, matched(manager.ConsumeIfMatches("CODE1")), "a consumed code should not match again")
+	assert.Empty(t, manager.Code())
+}
+
+func TestPairingManager_ConcurrentGenerateIfExpired_GeneratesOnce(t *testing.T) {
+	generate, calls := countingGenerator()
+	manager := NewPairingManager(generate, nil, clocktest.NewFake(testClockStart), 5*time.Minute)
+
+	var wg sync.WaitGroup
+	var generatedCount atomic.Int64
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, generated, _ := manager.GenerateIfExpired(); generated {
+				generatedCount.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(1), calls.Load(), "concurrent callers should share one generated code")
+	assert.Equal(t, int64(1), generatedCount.Load(), "only one caller should be told to publish")
+}
+
+func TestPairingManager_ConcurrentConsume_OnlyOneWins(t *testing.T) {
+	generate, _ := countingGenerator()
+	manager := NewPairingManager(generate, nil, clocktest.NewFake(testClockStart), 5*time.Minute)
+	manager.GenerateIfExpired()
+
+	var wg sync.WaitGroup
+	var wins atomic.Int64
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if matched(manager.ConsumeIfMatches("CODE1")) {
+				wins.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(1), wins.Load(), "a code should pair exactly one device")
+}
+
+func TestServer_ConcurrentRootAndPair_PairsOnceWithoutRaces(t *testing.T) {
+	generate, calls := countingGenerator()
+	keyStore, _ := createTestKeyStore(t)
+	var keys atomic.Int64
+	server := NewServer(
+		WithKeyStore(keyStore),
+		WithShortCodeGenerator(generate),
+		WithAPICodeGenerator(func() (string, error) {
+			return fmt.Sprintf("concurrent-key-%d", keys.Add(1)), nil
+		}),
+		WithPairingLimits(PairingLimits{MaxAttemptsPerIP: 1000, MaxAttemptsGlobal: 1000, Window: time.Minute, Lockout: time.Minute, RotateAfter: 1000}),
+		WithOutput(io.Discard),
+		WithLogger(createTestLogger()),
+	)
+	router := gin.New()
+	server.SetupRoutes(router)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	var wg sync.WaitGroup
+	var paired atomic.Int64
+	for i := 0; i < 40; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+			assert.Equal(t, http.StatusOK, w.Code)
+		}()
+		go func(i int) {
+			defer wg.Done()
+			w := postPairFrom(router, fmt.Sprintf("192.168.1.%d:1000", i), "CODE1")
+			for _, cookie := range w.Result().Cookies() {
+				if cookie.Name == apiKeyCookieName {
+					paired.Add(1)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(1), paired.Load(), "only one concurrent submission should pair")
+	assert.GreaterOrEqual(t, calls.Load(), int64(2), "a fresh code should be generated after the first is consumed")
+}
+
+func staticTokens(tokens ...string) PairingTokenGenerator {
+	var mu sync.Mutex
+	return func() (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		token := tokens[0]
+		tokens = tokens[1:]
+		return token, nil
+	}
+}
+
+func TestPairingManager_GenerateIfExpired_IssuesTokenWithCode(t *testing.T) {
+	generate, _ := countingGenerator()
+	manager := NewPairingManager(generate, staticTokens("token-1", "token-2"), clocktest.NewFake(testClockStart), 5*time.Minute)
+
+	code, _, err := manager.GenerateIfExpired()
+
+	assert.NoError(t, err)
+	assert.Equal(t, PairingCode{Code: "CODE1", Token: "token-1", Expiry: testClockStart.Add(5 * time.Minute), Scopes: []string{"media"}}, code)
+	assert.Equal(t, "token-1", manager.Token())
+}
+
+func TestPairingManager_ConsumeTokenIfMatches(t *testing.T) {
+	tests := []struct {
+		name      string
+		elapsed   time.Duration
+		candidate string
+		expected  bool
+	}{
+		{"matching live token", 0, "token-1", true},
+		{"wrong token", 0, "token-2", false},
+		{"empty candidate", 0, "", false},
+		{"short code instead of token", 0, "CODE1", false},
+		{"expired token", 5 * time.Minute, "token-1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generate, _ := countingGenerator()
+			fakeClock := clocktest.NewFake(testClockStart)
+			manager := NewPairingManager(generate, staticTokens("token-1"), fakeClock, 5*time.Minute)
+			manager.GenerateIfExpired()
+			fakeClock.Advance(tt.elapsed)
+
+			assert.Equal(t, tt.expected, matched(manager.ConsumeTokenIfMatches(tt.candidate)))
+		})
+	}
+}
+
+func TestPairingManager_ConsumeToken_AlsoConsumesCode(t *testing.T) {
+	generate, _ := countingGenerator()
+	manager := NewPairingManager(generate, staticTokens("token-1"), clocktest.NewFake(testClockStart), 5*time.Minute)
+	manager.GenerateIfExpired()
+
+	assert.True(t, matched(manager.ConsumeTokenIfMatches("token-1")))
+	assert.False(t, matched(manager.ConsumeIfMatches("CODE1")), "the code should not pair a second device")
+	assert.False(t, matched(manager.ConsumeTokenIfMatches("token-1")), "the token should be single use")
+}
+
+func TestPairingManager_ConsumeCode_AlsoConsumesToken(t *testing.T) {
+	generate, _ := countingGenerator()
+	manager := NewPairingManager(generate, staticTokens("token-1"), clocktest.NewFake(testClockStart), 5*time.Minute)
+	manager.GenerateIfExpired()
+
+	assert.True(t, matched(manager.ConsumeIfMatches("CODE1")))
+	assert.False(t, matched(manager.ConsumeTokenIfMatches("token-1")), "the link should not pair a second device")
+	assert.Empty(t, manager.Token())
+}
+
+func TestPairingManager_NoTokenGenerator_RejectsTokens(t *testing.T) {
+	generate, _ := countingGenerator()
+	manager := NewPairingManager(generate, nil, clocktest.NewFake(testClockStart), 5*time.Minute)
+	manager.GenerateIfExpired()
+
+	assert.Empty(t, manager.Token())
+	assert.False(t, matched(manager.ConsumeTokenIfMatches("")), "an unset token should never match")
+}
+
+// matched drops the granted scopes from a consume result.
+func matched(_ []string, ok bool) bool {
+	return ok
+}
+
+func TestPairingManager_NewCode_GrantsDefaultScopes(t *testing.T) {
+	generate, _ := countingGenerator()
+	manager := NewPairingManager(generate, nil, clocktest.NewFake(testClockStart), 5*time.Minute)
+	manager.SetDefaultScopes([]string{"media"})
+
+	code, _, _ := manager.GenerateIfExpired()
+	scopes, ok := manager.ConsumeIfMatches("CODE1")
+
+	assert.True(t, ok)
+	assert.Equal(t, []string{"media"}, code.Scopes)
+	assert.Equal(t, []string{"media"}, scopes)
+}
+
+func TestPairingManager_SetScopes_OnlyAppliesToCurrentCode(t *testing.T) {
+	generate, _ := countingGenerator()
+	manager := NewPairingManager(generate, staticTokens("token-1", "token-2"), clocktest.NewFake(testClockStart), 5*time.Minute)
+	manager.GenerateIfExpired()
+
+	manager.SetScopes([]string{"input", "media"})
+	guest, _ := manager.ConsumeTokenIfMatches("token-1")
+	manager.GenerateIfExpired()
+	next, _ := manager.ConsumeIfMatches("CODE2")
+
+	assert.Equal(t, []string{"input", "media"}, guest)
+	assert.Equal(t, []string{"media"}, next, "a guest's scopes should not carry over to the next code")
+}
+
+func TestPairingManager_Rotate_ResetsScopes(t *testing.T) {
+	generate, _ := countingGenerator()
+	manager := NewPairingManager(generate, nil, clocktest.NewFake(testClockStart), 5*time.Minute)
+	manager.GenerateIfExpired()
+	manager.SetScopes([]string{"admin"})
+
+	code, err := manager.Rotate()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"media"}, code.Scopes)
+	assert.Equal(t, []string{"media"}, manager.Scopes())
+}
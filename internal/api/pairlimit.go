@@ -0,0 +1,147 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+const globalAttemptKey = "*"
+
+type PairingLimits struct {
+	MaxAttemptsPerIP  int
+	MaxAttemptsGlobal int
+	Window            time.Duration
+	Lockout           time.Duration
+	RotateAfter       int
+}
+
+var DefaultPairingLimits = PairingLimits{
+	MaxAttemptsPerIP:  5,
+	MaxAttemptsGlobal: 20,
+	Window:            5 * time.Minute,
+	Lockout:           5 * time.Minute,
+	RotateAfter:       10,
+}
+
+type attemptWindow struct {
+	failures    int
+	start       time.Time
+	lockedUntil time.Time
+}
+
+type attemptLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	lockout time.Duration
+	windows map[string]*attemptWindow
+}
+
+func newAttemptLimiter(limit int, window, lockout time.Duration) *attemptLimiter {
+	return &attemptLimiter{
+		limit:   limit,
+		window:  window,
+		lockout: lockout,
+		windows: make(map[string]*attemptWindow),
+	}
+}
+
+func (l *attemptLimiter) retryAfter(key string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	w, ok := l.windows[key]
+	if !ok || !now.Before(w.lockedUntil) {
+		return 0
+	}
+	return w.lockedUntil.Sub(now)
+}
+
+func (l *attemptLimiter) fail(key string, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune(now)
+	w, ok := l.windows[key]
+	if !ok || !now.Before(w.start.Add(l.window)) {
+		w = &attemptWindow{start: now}
+		l.windows[key] = w
+	}
+	w.failures++
+	if l.limit > 0 && w.failures >= l.limit {
+		w.lockedUntil = now.Add(l.lockout)
+		w.failures = 0
+		w.start = w.lockedUntil
+	}
+}
+
+func (l *attemptLimiter) reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.windows, key)
+}
+
+func (l *attemptLimiter) prune(now time.Time) {
+	for key, w := range l.windows {
+		if !now.Before(w.start.Add(l.window)) && !now.Before(w.lockedUntil) {
+			delete(l.windows, key)
+		}
+	}
+}
+
+type pairGuard struct {
+	perIP        *attemptLimiter
+	global       *attemptLimiter
+	rotateAfter  int
+	mu           sync.Mutex
+	codeFailures int
+}
+
+func newPairGuard(limits PairingLimits) *pairGuard {
+	return &pairGuard{
+		perIP:       newAttemptLimiter(limits.MaxAttemptsPerIP, limits.Window, limits.Lockout),
+		global:      newAttemptLimiter(limits.MaxAttemptsGlobal, limits.Window, limits.Lockout),
+		rotateAfter: limits.RotateAfter,
+	}
+}
+
+// reserve checks the lockouts and, when there are none, counts the attempt
+// against them under one lock, so concurrent guesses can't all get past the
+// check before any of them is recorded. It returns how long a locked out
+// caller has to wait.
+func (g *pairGuard) reserve(ip string, now time.Time) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if wait := max(g.perIP.retryAfter(ip, now), g.global.retryAfter(globalAttemptKey, now)); wait > 0 {
+		return wait
+	}
+	g.perIP.fail(ip, now)
+	g.global.fail(globalAttemptKey, now)
+	return 0
+}
+
+// fail records that a reserved attempt had the wrong code and reports
+// whether the current code has now seen enough wrong guesses that it
+// should be replaced.
+func (g *pairGuard) fail() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.codeFailures++
+	if g.rotateAfter > 0 && g.codeFailures >= g.rotateAfter {
+		g.codeFailures = 0
+		return true
+	}
+	return false
+}
+
+func (g *pairGuard) succeed(ip string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.perIP.reset(ip)
+	g.codeFailures = 0
+}
+
+// newCode forgets the wrong guesses made against the previous code.
+func (g *pairGuard) newCode() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.codeFailures = 0
+}
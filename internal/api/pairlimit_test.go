@@ -0,0 +1,246 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phasecurve/sway_rm/internal/clock/clocktest"
+)
+
+var testPairingLimits = PairingLimits{
+	MaxAttemptsPerIP:  3,
+	MaxAttemptsGlobal: 6,
+	Window:            time.Minute,
+	Lockout:           2 * time.Minute,
+	RotateAfter:       100,
+}
+
+func createPairLimitTestServer(t *testing.T, limits PairingLimits) (*gin.Engine, *Server, *clocktest.Fake, *syncBuffer) {
+	fakeClock := clocktest.NewFake(testClockStart)
+	keyStore, _ := createTestKeyStore(t)
+	var output syncBuffer
+	callCount := 0
+	server := NewServer(
+		WithKeyStore(keyStore),
+		WithClock(fakeClock),
+		WithPairingLimits(limits),
+		WithShortCodeGenerator(func() (string, error) {
+			callCount++
+			return fmt.Sprintf("CODE%d", callCount), nil
+		}),
+		WithAPICodeGenerator(func() (string, error) { return "limited-key", nil }),
+		WithOutput(&output),
+		WithLogger(createTestLogger()),
+	)
+	router := gin.Default()
+	server.SetupRoutes(router)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	return router, server, fakeClock, &output
+}
+
+func postPairFrom(router *gin.Engine, remoteAddr string, code string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	form := url.Values{"short-code": {code}}
+	req, _ := http.NewRequest("POST", "/api/pair", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = remoteAddr
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestPair_LowerCaseOrPaddedCode_Pairs(t *testing.T) {
+	for _, entered := range []string{"code1", "  CODE1\n", " Code1 "} {
+		t.Run(entered, func(t *testing.T) {
+			router, _, _, _ := createPairLimitTestServer(t, testPairingLimits)
+
+			w := postPairFrom(router, "192.168.1.50:1000", entered)
+
+			assert.Contains(t, w.Body.String(), "Paired", "codes should match whatever the case or surrounding whitespace")
+		})
+	}
+}
+
+func TestPair_TooManyFailuresFromIP_LocksOut(t *testing.T) {
+	router, _, _, _ := createPairLimitTestServer(t, testPairingLimits)
+	for i := 0; i < 3; i++ {
+		w := postPairFrom(router, "192.168.1.50:1000", "WRONG")
+		assert.Equal(t, http.StatusOK, w.Code, "attempts under the limit should just show the error")
+	}
+
+	w := postPairFrom(router, "192.168.1.50:1000", "CODE1")
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "even the right code should be refused while locked out")
+	assert.Equal(t, "120", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "Too many pairing attempts. Try again in 2m0s.")
+	assert.Contains(t, w.Body.String(), `id="pair-form"`, "lockout should render the pair form with the message")
+	assert.Empty(t, w.Result().Cookies())
+}
+
+func TestPair_LockoutElapsed_AllowsPairing(t *testing.T) {
+	router, _, fakeClock, _ := createPairLimitTestServer(t, testPairingLimits)
+	for i := 0; i < 3; i++ {
+		postPairFrom(router, "192.168.1.50:1000", "WRONG")
+	}
+
+	fakeClock.Advance(90 * time.Second)
+	locked := postPairFrom(router, "192.168.1.50:1000", "CODE1")
+	fakeClock.Advance(30 * time.Second)
+	unlocked := postPairFrom(router, "192.168.1.50:1000", "CODE1")
+
+	assert.Equal(t, http.StatusTooManyRequests, locked.Code)
+	assert.Equal(t, "30", locked.Header().Get("Retry-After"), "retry-after should count down with the clock")
+	assert.Equal(t, http.StatusOK, unlocked.Code)
+	assert.Contains(t, unlocked.Body.String(), "Paired")
+}
+
+func TestPair_ForgedForwardedFor_StillLockedOut(t *testing.T) {
+	router, _, _, _ := createPairLimitTestServer(t, testPairingLimits)
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		form := url.Values{"short-code": {"WRONG"}}
+		req, _ := http.NewRequest("POST", "/api/pair", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("10.0.0.%d", i))
+		req.Header.Set("X-Real-IP", fmt.Sprintf("10.0.1.%d", i))
+		req.RemoteAddr = "192.168.1.50:1000"
+		router.ServeHTTP(w, req)
+	}
+
+	w := postPairFrom(router, "192.168.1.50:1000", "CODE1")
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "forwarding headers must not give a client a fresh attempt counter")
+}
+
+func TestPair_LockedOutIP_DoesNotBlockOtherIPs(t *testing.T) {
+	router, _, _, _ := createPairLimitTestServer(t, testPairingLimits)
+	for i := 0; i < 3; i++ {
+		postPairFrom(router, "192.168.1.50:1000", "WRONG")
+	}
+
+	w := postPairFrom(router, "192.168.1.51:1000", "CODE1")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Paired")
+}
+
+func TestPair_FailuresAcrossIPs_TripGlobalLimit(t *testing.T) {
+	router, _, _, _ := createPairLimitTestServer(t, testPairingLimits)
+	for i := 0; i < 6; i++ {
+		postPairFrom(router, fmt.Sprintf("192.168.1.%d:1000", 60+i), "WRONG")
+	}
+
+	w := postPairFrom(router, "192.168.1.99:1000", "CODE1")
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "a distributed guess should trip the global limit")
+	assert.Equal(t, "120", w.Header().Get("Retry-After"))
+}
+
+func TestPair_FailuresOutsideWindow_DoNotAccumulate(t *testing.T) {
+	router, _, fakeClock, _ := createPairLimitTestServer(t, testPairingLimits)
+	for i := 0; i < 4; i++ {
+		postPairFrom(router, "192.168.1.50:1000", "WRONG")
+		fakeClock.Advance(40 * time.Second)
+		if i == 1 {
+			fakeClock.Advance(time.Minute)
+		}
+	}
+
+	w := postPairFrom(router, "192.168.1.50:1000", "CODE1")
+
+	assert.Equal(t, http.StatusOK, w.Code, "failures spread across windows should not lock out")
+}
+
+func TestPair_SuccessfulPairing_ResetsIPFailures(t *testing.T) {
+	limits := testPairingLimits
+	router, server, _, _ := createPairLimitTestServer(t, limits)
+	postPairFrom(router, "192.168.1.50:1000", "WRONG")
+	postPairFrom(router, "192.168.1.50:1000", "WRONG")
+	postPairFrom(router, "192.168.1.50:1000", "CODE1")
+	server.ensurePairingCode()
+
+	postPairFrom(router, "192.168.1.50:1000", "WRONG")
+	w := postPairFrom(router, "192.168.1.50:1000", server.pairing().Code())
+
+	assert.Equal(t, http.StatusOK, w.Code, "earlier failures should be forgotten after a successful pairing")
+	assert.Contains(t, w.Body.String(), "Paired")
+}
+
+func TestPair_RepeatedFailures_RotateCode(t *testing.T) {
+	limits := testPairingLimits
+	limits.MaxAttemptsPerIP = 10
+	limits.RotateAfter = 2
+	router, server, _, output := createPairLimitTestServer(t, limits)
+
+	postPairFrom(router, "192.168.1.50:1000", "WRONG")
+	assert.Equal(t, "CODE1", server.pairing().Code(), "code should survive until the rotation threshold")
+	postPairFrom(router, "192.168.1.50:1000", "WRONG")
+	old := postPairFrom(router, "192.168.1.50:1000", "CODE1")
+	current := postPairFrom(router, "192.168.1.50:1000", "CODE2")
+
+	assert.Contains(t, old.Body.String(), "Invalid pairing code", "the rotated-out code should no longer pair")
+	waitForOutput(t, output, "CODE2")
+	assert.Contains(t, current.Body.String(), "Paired")
+}
+
+func TestPair_NewCode_ForgetsEarlierFailures(t *testing.T) {
+	limits := testPairingLimits
+	limits.MaxAttemptsPerIP = 10
+	limits.RotateAfter = 2
+	router, server, fakeClock, _ := createPairLimitTestServer(t, limits)
+	postPairFrom(router, "192.168.1.50:1000", "WRONG")
+
+	fakeClock.Advance(defaultPairingCodeLifetime)
+	assert.NoError(t, server.ensurePairingCode())
+	postPairFrom(router, "192.168.1.50:1000", "WRONG")
+
+	assert.Equal(t, "CODE2", server.pairing().Code(), "guesses against an expired code should not count towards rotating the next one")
+}
+
+func TestPair_ConcurrentGuesses_StopAtLimit(t *testing.T) {
+	router, _, _, _ := createPairLimitTestServer(t, testPairingLimits)
+	const guesses = 20
+	codes := make(chan int, guesses)
+	var wg sync.WaitGroup
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- postPairFrom(router, "192.168.1.50:1000", "WRONG").Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	checked := 0
+	for code := range codes {
+		if code != http.StatusTooManyRequests {
+			checked++
+		}
+	}
+	assert.Equal(t, testPairingLimits.MaxAttemptsPerIP, checked, "only the allowed attempts should reach the code check")
+}
+
+func TestPair_NoCodeSet_EmptyCodeRejected(t *testing.T) {
+	router := gin.Default()
+	keyStore, _ := createTestKeyStore(t)
+	server := &Server{
+		KeyStore:         keyStore,
+		APICodeGenerator: func() (string, error) { return "should-not-exist", nil },
+		Logger:           createTestLogger(),
+	}
+	server.SetupRoutes(router)
+
+	w := postPairFrom(router, "192.168.1.50:1000", "")
+
+	assert.Contains(t, w.Body.String(), "Invalid pairing code")
+	assert.Empty(t, w.Result().Cookies(), "an empty code must never match an unset pairing code")
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phasecurve/sway_rm/internal/clock/clocktest"
+	"github.com/phasecurve/sway_rm/internal/security"
+)
+
+func createPairLinkTestServer(t *testing.T, opts ...ServerOption) (*gin.Engine, *security.KeyStore, *syncBuffer) {
+	keyStore, _ := createTestKeyStore(t)
+	var output syncBuffer
+	options := []ServerOption{
+		WithKeyStore(keyStore),
+		WithClock(clocktest.NewFake(testClockStart)),
+		WithPairingLimits(testPairingLimits),
+		WithShortCodeGenerator(func() (string, error) { return "CODE42", nil }),
+		WithAPICodeGenerator(func() (string, error) { return "link-key", nil }),
+		WithPairingTokenGenerator(staticTokens("token-1", "token-2", "token-3")),
+		WithPairingURL("http://192.168.1.20:8080/"),
+		WithOutput(&output),
+		WithLogger(createTestLogger()),
+	}
+	server := NewServer(append(options, opts...)...)
+	router := gin.Default()
+	server.SetupRoutes(router)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	return router, keyStore, &output
+}
+
+func getPairLink(router *gin.Engine, remoteAddr, token string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/pair?token="+token, nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (iPhone)")
+	req.RemoteAddr = remoteAddr
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRoot_PairingURLSet_PrintsQRCodeAndLink(t *testing.T) {
+	_, _, output := createPairLinkTestServer(t)
+
+	printed := waitForOutput(t, output, "/pair?token=")
+
+	assert.Contains(t, printed, "CODE42", "should still print the typed code")
+	assert.Contains(t, printed, "http://192.168.1.20:8080/pair?token=token-1", "should print the pairing link")
+	assert.Contains(t, printed, "█", "should render the QR code with block characters")
+}
+
+func TestRoot_NoPairingURL_PrintsCodeOnly(t *testing.T) {
+	_, _, output := createPairLinkTestServer(t, WithPairingURL(""))
+
+	printed := waitForOutput(t, output, "CODE42")
+
+	assert.Contains(t, printed, "CODE42")
+	assert.NotContains(t, printed, "/pair?token=", "should not print a link without a base URL")
+	assert.NotContains(t, printed, "█", "should not render a QR code without a base URL")
+}
+
+func TestPairLink_ValidToken_SetsCookieAndRedirects(t *testing.T) {
+	router, keyStore, _ := createPairLinkTestServer(t)
+
+	w := getPairLink(router, "192.168.1.30:4000", "token-1")
+
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, "/", w.Header().Get("Location"))
+	var cookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == apiKeyCookieName {
+			cookie = c
+		}
+	}
+	if assert.NotNil(t, cookie, "should set the api key cookie") {
+		assert.Equal(t, "link-key", cookie.Value)
+		assert.True(t, cookie.HttpOnly)
+	}
+	stored, err := keyStore.GetAPIKey("link-key")
+	assert.NoError(t, err)
+	assert.Equal(t, "Mozilla/5.0 (iPhone)", stored.UserAgent)
+	assert.Equal(t, "192.168.1.30", stored.RemoteIP)
+	assert.True(t, testClockStart.Add(time.Hour).Equal(stored.TTL), "link pairing should get the usual session length")
+}
+
+func TestPairLink_TokenReused_Unauthorized(t *testing.T) {
+	router, _, _ := createPairLinkTestServer(t)
+	getPairLink(router, "192.168.1.30:4000", "token-1")
+
+	w := getPairLink(router, "192.168.1.31:4000", "token-1")
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, w.Result().Cookies(), "a used link should not pair another device")
+}
+
+func TestPairLink_InvalidToken_ShowsPairForm(t *testing.T) {
+	router, _, _ := createPairLinkTestServer(t)
+
+	w := getPairLink(router, "192.168.1.30:4000", "not-the-token")
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "This pairing link has expired or was already used.")
+	assert.Contains(t, w.Body.String(), `id="pair-form"`, "should offer the typed code instead")
+	assert.Empty(t, w.Result().Cookies())
+}
+
+func TestPairLink_UsedCode_InvalidatesToken(t *testing.T) {
+	router, _, _ := createPairLinkTestServer(t)
+	postPairFrom(router, "192.168.1.30:4000", "CODE42")
+
+	w := getPairLink(router, "192.168.1.31:4000", "token-1")
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "the link should die with the code it was issued with")
+}
+
+func TestPairLink_TooManyBadTokens_LocksOut(t *testing.T) {
+	router, _, _ := createPairLinkTestServer(t)
+	for i := 0; i < 3; i++ {
+		getPairLink(router, "192.168.1.50:1000", "guess")
+	}
+
+	w := getPairLink(router, "192.168.1.50:1000", "token-1")
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "guessing tokens should share the pairing limits")
+	assert.Equal(t, "120", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "Too many pairing attempts.")
+	assert.Empty(t, w.Result().Cookies())
+}
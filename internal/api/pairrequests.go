@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/phasecurve/sway_rm/internal"
+	"github.com/phasecurve/sway_rm/internal/clock"
+)
+
+const (
+	defaultPairRequestLifetime = 2 * time.Minute
+	defaultPairRequestWait     = 25 * time.Second
+	maxPendingPairRequests     = 10
+)
+
+var ErrTooManyPairRequests = errors.New("too many pairing requests waiting")
+
+type pairRequest struct {
+	internal.PairRequest
+	decided chan struct{}
+}
+
+// PairRequests holds devices waiting for the desktop user to approve
+// them. Each address may have one request waiting; a new one replaces it.
+type PairRequests struct {
+	mu       sync.Mutex
+	clock    clock.Clock
+	lifetime time.Duration
+	requests map[string]*pairRequest
+}
+
+func NewPairRequests(clk clock.Clock, lifetime time.Duration) *PairRequests {
+	return &PairRequests{
+		clock:    clk,
+		lifetime: lifetime,
+		requests: map[string]*pairRequest{},
+	}
+}
+
+// Add queues a pending request, filling in its status and times.
+func (p *PairRequests) Add(request internal.PairRequest) (internal.PairRequest, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.clock.Now()
+	pending := 0
+	for id, existing := range p.requests {
+		switch {
+		case !now.Before(existing.Expiry):
+			p.remove(id)
+		case existing.Status != internal.RequestPending:
+		case existing.RemoteIP == request.RemoteIP:
+			p.remove(id)
+		default:
+			pending++
+		}
+	}
+	if pending >= maxPendingPairRequests {
+		return internal.PairRequest{}, ErrTooManyPairRequests
+	}
+
+	request.Status = internal.RequestPending
+	request.Created = now
+	request.Expiry = now.Add(p.lifetime)
+	request.Scopes = nil
+	p.requests[request.ID] = &pairRequest{PairRequest: request, decided: make(chan struct{})}
+	return request, nil
+}
+
+// Pending lists live requests waiting for a decision, oldest first.
+func (p *PairRequests) Pending() []internal.PairRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var pending []internal.PairRequest
+	for id := range p.requests {
+		if r, ok := p.live(id); ok && r.Status == internal.RequestPending {
+			pending = append(pending, r.snapshot())
+		}
+	}
+	slices.SortFunc(pending, func(a, b internal.PairRequest) int {
+		return a.Created.Compare(b.Created)
+	})
+	return pending
+}
+
+// Decide approves or denies a live pending request, waking its waiter.
+// scopes are what an approved device is granted.
+func (p *PairRequests) Decide(id string, status internal.PairRequestStatus, scopes []string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r, ok := p.live(id)
+	if !ok || r.Status != internal.RequestPending {
+		return false
+	}
+	r.Status = status
+	if status == internal.RequestApproved {
+		r.Scopes = slices.Clone(scopes)
+	}
+	close(r.decided)
+	return true
+}
+
+// Wait blocks until the request is decided, timeout passes or ctx is
+// done, then returns its latest state. ok is false for unknown or expired
+// requests.
+func (p *PairRequests) Wait(ctx context.Context, id string, timeout time.Duration) (request internal.PairRequest, ok bool) {
+	p.mu.Lock()
+	r, ok := p.live(id)
+	p.mu.Unlock()
+	if !ok {
+		return internal.PairRequest{}, false
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-r.decided:
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if r, ok = p.live(id); !ok {
+		return internal.PairRequest{}, false
+	}
+	return r.snapshot(), true
+}
+
+// Claim removes a decided request, so an approval hands out one key.
+func (p *PairRequests) Claim(id string) (internal.PairRequest, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r, ok := p.live(id)
+	if !ok || r.Status == internal.RequestPending {
+		return internal.PairRequest{}, false
+	}
+	delete(p.requests, id)
+	return r.snapshot(), true
+}
+
+func (p *PairRequests) live(id string) (*pairRequest, bool) {
+	r, ok := p.requests[id]
+	if !ok {
+		return nil, false
+	}
+	if !p.clock.Now().Before(r.Expiry) {
+		p.remove(id)
+		return nil, false
+	}
+	return r, true
+}
+
+func (p *PairRequests) remove(id string) {
+	r := p.requests[id]
+	if r.Status == internal.RequestPending {
+		close(r.decided)
+	}
+	delete(p.requests, id)
+}
+
+func (r *pairRequest) snapshot() internal.PairRequest {
+	request := r.PairRequest
+	request.Scopes = slices.Clone(r.Scopes)
+	return request
+}
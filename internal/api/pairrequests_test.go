@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phasecurve/sway_rm/internal"
+	"github.com/phasecurve/sway_rm/internal/clock/clocktest"
+)
+
+func addPairRequest(t *testing.T, requests *PairRequests, id, ip string) internal.PairRequest {
+	t.Helper()
+	request, err := requests.Add(internal.PairRequest{ID: id, CheckCode: "CHK" + id, RemoteIP: ip})
+	assert.NoError(t, err)
+	return request
+}
+
+func TestPairRequests_Add_IsPendingWithLifetime(t *testing.T) {
+	requests := NewPairRequests(clocktest.NewFake(testClockStart), 2*time.Minute)
+
+	request := addPairRequest(t, requests, "req-1", "192.168.1.30")
+
+	assert.Equal(t, internal.RequestPending, request.Status)
+	assert.Equal(t, testClockStart, request.Created)
+	assert.Equal(t, testClockStart.Add(2*time.Minute), request.Expiry)
+	assert.Equal(t, []internal.PairRequest{request}, requests.Pending())
+}
+
+func TestPairRequests_SameAddress_ReplacesPendingRequest(t *testing.T) {
+	requests := NewPairRequests(clocktest.NewFake(testClockStart), 2*time.Minute)
+	addPairRequest(t, requests, "req-1", "192.168.1.30")
+
+	addPairRequest(t, requests, "req-2", "192.168.1.30")
+
+	pending := requests.Pending()
+	if assert.Len(t, pending, 1) {
+		assert.Equal(t, "req-2", pending[0].ID)
+	}
+	assert.False(t, requests.Decide("req-1", internal.RequestApproved, []string{"admin"}), "the replaced request should be gone")
+}
+
+func TestPairRequests_TooManyPending_Refused(t *testing.T) {
+	requests := NewPairRequests(clocktest.NewFake(testClockStart), 2*time.Minute)
+	for i := 0; i < maxPendingPairRequests; i++ {
+		addPairRequest(t, requests, fmt.Sprintf("req-%d", i), fmt.Sprintf("192.168.1.%d", i))
+	}
+
+	_, err := requests.Add(internal.PairRequest{ID: "one-too-many", RemoteIP: "192.168.1.200"})
+
+	assert.ErrorIs(t, err, ErrTooManyPairRequests)
+}
+
+func TestPairRequests_Expired_DroppedAndMakesRoom(t *testing.T) {
+	fakeClock := clocktest.NewFake(testClockStart)
+	requests := NewPairRequests(fakeClock, 2*time.Minute)
+	for i := 0; i < maxPendingPairRequests; i++ {
+		addPairRequest(t, requests, fmt.Sprintf("req-%d", i), fmt.Sprintf("192.168.1.%d", i))
+	}
+
+	fakeClock.Advance(2 * time.Minute)
+
+	assert.Empty(t, requests.Pending())
+	assert.False(t, requests.Decide("req-0", internal.RequestApproved, []string{"admin"}), "expired requests cannot be approved")
+	_, err := requests.Add(internal.PairRequest{ID: "fresh", RemoteIP: "192.168.1.200"})
+	assert.NoError(t, err)
+}
+
+func TestPairRequests_Decide_WakesWaiter(t *testing.T) {
+	requests := NewPairRequests(clocktest.NewFake(testClockStart), 2*time.Minute)
+	addPairRequest(t, requests, "req-1", "192.168.1.30")
+
+	result := make(chan internal.PairRequest)
+	go func() {
+		request, _ := requests.Wait(context.Background(), "req-1", time.Minute)
+		result <- request
+	}()
+	assert.True(t, requests.Decide("req-1", internal.RequestApproved, []string{"media"}))
+
+	select {
+	case request := <-result:
+		assert.Equal(t, internal.RequestApproved, request.Status)
+		assert.Equal(t, []string{"media"}, request.Scopes)
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiter was not woken by the decision")
+	}
+}
+
+func TestPairRequests_Wait_TimesOutWhilePending(t *testing.T) {
+	requests := NewPairRequests(clocktest.NewFake(testClockStart), 2*time.Minute)
+	addPairRequest(t, requests, "req-1", "192.168.1.30")
+
+	request, ok := requests.Wait(context.Background(), "req-1", 10*time.Millisecond)
+
+	assert.True(t, ok)
+	assert.Equal(t, internal.RequestPending, request.Status)
+}
+
+func TestPairRequests_Wait_UnknownRequest(t *testing.T) {
+	requests := NewPairRequests(clocktest.NewFake(testClockStart), 2*time.Minute)
+
+	_, ok := requests.Wait(context.Background(), "missing", time.Minute)
+
+	assert.False(t, ok)
+}
+
+func TestPairRequests_Decide_OnlyOnce(t *testing.T) {
+	requests := NewPairRequests(clocktest.NewFake(testClockStart), 2*time.Minute)
+	addPairRequest(t, requests, "req-1", "192.168.1.30")
+
+	assert.True(t, requests.Decide("req-1", internal.RequestDenied, nil))
+	assert.False(t, requests.Decide("req-1", internal.RequestApproved, []string{"admin"}), "a denial should not be overturned")
+	assert.Empty(t, requests.Pending())
+}
+
+func TestPairRequests_Claim_SingleUse(t *testing.T) {
+	requests := NewPairRequests(clocktest.NewFake(testClockStart), 2*time.Minute)
+	addPairRequest(t, requests, "req-1", "192.168.1.30")
+
+	_, pendingClaimed := requests.Claim("req-1")
+	requests.Decide("req-1", internal.RequestApproved, []string{"admin"})
+	first, firstOK := requests.Claim("req-1")
+	_, secondOK := requests.Claim("req-1")
+
+	assert.False(t, pendingClaimed, "undecided requests cannot be claimed")
+	assert.True(t, firstOK)
+	assert.Equal(t, internal.RequestApproved, first.Status)
+	assert.False(t, secondOK, "an approval should hand out one key")
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phasecurve/sway_rm/internal/publish"
+)
+
+type recordingPublisher struct {
+	mu       sync.Mutex
+	codes    []publish.Code
+	requests []publish.Request
+	err      error
+}
+
+func (r *recordingPublisher) PublishCode(code publish.Code) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.codes = append(r.codes, code)
+	return r.err
+}
+
+func (r *recordingPublisher) Codes() []publish.Code {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]publish.Code(nil), r.codes...)
+}
+
+func (r *recordingPublisher) PublishRequest(request publish.Request) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, request)
+	return r.err
+}
+
+func (r *recordingPublisher) Requests() []publish.Request {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]publish.Request(nil), r.requests...)
+}
+
+// syncBuffer collects output written by the background publisher.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// waitForOutput waits until the background publisher has written text.
+func waitForOutput(t *testing.T, output *syncBuffer, text string) string {
+	assert.Eventually(t, func() bool { return strings.Contains(output.String(), text) }, time.Second, 5*time.Millisecond, "expected output containing %q", text)
+	return output.String()
+}
+
+type blockingPublisher struct {
+	release chan struct{}
+}
+
+func (b *blockingPublisher) PublishCode(publish.Code) error {
+	<-b.release
+	return nil
+}
+
+func (b *blockingPublisher) PublishRequest(publish.Request) error {
+	<-b.release
+	return nil
+}
+
+func TestRoot_SlowPublisher_RespondsWithoutWaiting(t *testing.T) {
+	slow := &blockingPublisher{release: make(chan struct{})}
+	defer close(slow.release)
+	keyStore, _ := createTestKeyStore(t)
+	server := NewServer(WithKeyStore(keyStore), WithPublishers(slow), WithLogger(createTestLogger()))
+	router := gin.Default()
+	server.SetupRoutes(router)
+
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		done <- w.Code
+	}()
+
+	select {
+	case code := <-done:
+		assert.Equal(t, http.StatusOK, code)
+	case <-time.After(time.Second):
+		t.Fatal("page load waited on a blocked publisher")
+	}
+}
+
+func TestRoot_WithPublishers_PublishesToEachInsteadOfOutput(t *testing.T) {
+	first, second := &recordingPublisher{}, &recordingPublisher{}
+
+	_, _, output := createPairLinkTestServer(t, WithPublishers(first, second))
+
+	expected := publish.Code{
+		Code:      "CODE42",
+		Link:      "http://192.168.1.20:8080/pair?token=token-1",
+		Expiry:    testClockStart.Add(defaultPairingCodeLifetime),
+		ExpiresIn: defaultPairingCodeLifetime,
+	}
+	assert.Eventually(t, func() bool { return len(first.Codes()) == 1 && len(second.Codes()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []publish.Code{expected}, first.Codes())
+	assert.Equal(t, []publish.Code{expected}, second.Codes())
+	assert.Empty(t, output.String(), "publishers should replace the default terminal output")
+}
+
+func TestRoot_PublisherFails_StillServesPairForm(t *testing.T) {
+	var logOutput syncBuffer
+	failing := &recordingPublisher{err: errors.New("notification daemon gone")}
+	router, _, _ := createPairLinkTestServer(t, WithPublishers(failing), WithLogger(log.New(&logOutput, "", 0)), WithShortCodeGenerator(func() (string, error) { return "OTHER1", nil }))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	waitForOutput(t, &logOutput, "failed to publish pairing code: notification daemon gone")
+	assert.Len(t, failing.Codes(), 1, "a live code should not be republished on every page load")
+}
+
+func TestRotatePairingCode_PublishesNewCode(t *testing.T) {
+	publisher := &recordingPublisher{}
+	router, server, fakeClock, _ := createPairLimitTestServer(t, PairingLimits{MaxAttemptsPerIP: 100, MaxAttemptsGlobal: 100, Window: time.Minute, Lockout: time.Minute, RotateAfter: 2})
+	server.Publisher = publisher
+	fakeClock.Advance(time.Minute)
+
+	postPairFrom(router, "192.168.1.50:1000", "WRONG")
+	postPairFrom(router, "192.168.1.50:1000", "WRONG")
+
+	assert.Eventually(t, func() bool { return len(publisher.Codes()) > 0 }, time.Second, 5*time.Millisecond)
+	codes := publisher.Codes()
+	if assert.Len(t, codes, 1) {
+		assert.Equal(t, "CODE2", codes[0].Code)
+		assert.Equal(t, defaultPairingCodeLifetime, codes[0].ExpiresIn, "expiry should be measured from the server clock")
+	}
+}
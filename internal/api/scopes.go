@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/phasecurve/sway_rm/internal/components"
+	"github.com/phasecurve/sway_rm/internal/security"
+	"github.com/phasecurve/sway_rm/templates"
+)
+
+const pairScopeFormID = "scope"
+
+// getPairScopes shows the desktop user the current pairing code and lets
+// them choose what the device that pairs with it may control.
+func (s *Server) getPairScopes(c *gin.Context) {
+	if err := s.ensurePairingCode(); err != nil {
+		s.Logger.Printf("failed to generate pairing code: %v", err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	manager := s.pairing()
+	component := templates.PairScopes(manager.Code(), manager.Expiry(), manager.Scopes())
+	component.Render(c.Request.Context(), c.Writer)
+}
+
+func (s *Server) postPairScopes(c *gin.Context) {
+	scopes, err := security.ParseScopes(c.PostFormArray(pairScopeFormID)...)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(scopes) == 0 {
+		c.String(http.StatusBadRequest, "choose at least one scope")
+		return
+	}
+	if err := s.ensurePairingCode(); err != nil {
+		s.Logger.Printf("failed to generate pairing code: %v", err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	manager := s.pairing()
+	manager.SetScopes(scopes)
+
+	if isHTMXRequest(c) {
+		c.Header("Content-Type", "text/html")
+		component := components.PairScopePicker(manager.Code(), manager.Expiry(), manager.Scopes(), "Saved")
+		component.Render(c.Request.Context(), c.Writer)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,289 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phasecurve/sway_rm/internal/security"
+)
+
+// routeScopes is the scope each paired route needs, or "" for routes every
+// paired device may use.
+var routeScopes = map[string]string{
+	"GET /api/workspaces":                  security.ScopeWindows,
+	"GET /api/workspaces/events":           security.ScopeWindows,
+	"POST /api/workspaces/:name/focus":     security.ScopeWindows,
+	"GET /api/tree":                        security.ScopeWindows,
+	"POST /api/windows/:con_id/:action":    security.ScopeWindows,
+	"POST /api/containers/:con_id/layout":  security.ScopeWindows,
+	"GET /api/mpv/events":                  security.ScopeMedia,
+	"GET /api/mpv/instances":               security.ScopeMedia,
+	"POST /api/mpv/instances/:id/activate": security.ScopeMedia,
+	"POST /api/mpv/seek":                   security.ScopeMedia,
+	"POST /api/mpv/volume":                 security.ScopeMedia,
+	"POST /api/mpv/speed":                  security.ScopeMedia,
+	"POST /api/mpv/:action":                security.ScopeMedia,
+	"GET /devices":                         "",
+	"GET /api/devices":                     "",
+	"DELETE /api/devices/:id":              "",
+	"POST /api/tokens":                     security.ScopeAdmin,
+	"GET /api/input/ws":                    security.ScopeInput,
+	"POST /api/input/text":                 security.ScopeInput,
+	"POST /api/input/keys":                 security.ScopeInput,
+}
+
+func createScopeTestServer(t *testing.T) (*gin.Engine, *security.KeyStore) {
+	router, keyStore := createAuthTestServer(t)
+	for _, scope := range security.AllScopes {
+		assert.NoError(t, keyStore.SaveAPIKey(&security.APIKey{
+			Key:    scope + "-key",
+			TTL:    time.Now().Add(time.Hour),
+			Scopes: []string{scope},
+		}))
+	}
+	return router, keyStore
+}
+
+func TestRoutes_EveryControlRouteHasAScope(t *testing.T) {
+	router, _ := createAuthTestServer(t)
+
+	seen := map[string]bool{}
+	for _, route := range router.Routes() {
+		name := route.Method + " " + route.Path
+		if publicRoutes[name] || localRoutes[name] {
+			continue
+		}
+		seen[name] = true
+		assert.Contains(t, routeScopes, name, "%s should be listed with the scope it needs", name)
+	}
+	for name := range routeScopes {
+		assert.True(t, seen[name], "%s is listed but not registered", name)
+	}
+}
+
+func TestRequireScope_RouteTable(t *testing.T) {
+	router, _ := createScopeTestServer(t)
+
+	for _, route := range router.Routes() {
+		required, ok := routeScopes[route.Method+" "+route.Path]
+		if !ok {
+			continue
+		}
+		for _, granted := range security.AllScopes {
+			allowed := required == "" || granted == required || granted == security.ScopeAdmin
+			t.Run(route.Method+" "+route.Path+" as "+granted, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(route.Method, routePath(route.Path), nil)
+				req.AddCookie(&http.Cookie{Name: apiKeyCookieName, Value: granted + "-key"})
+				router.ServeHTTP(w, req)
+
+				if allowed {
+					assert.NotEqual(t, http.StatusForbidden, w.Code, "%s should be allowed", granted)
+					assert.NotEqual(t, http.StatusUnauthorized, w.Code)
+					return
+				}
+				assert.Equal(t, http.StatusForbidden, w.Code, "%s should be refused", granted)
+				if strings.HasPrefix(route.Path, "/api/") {
+					assert.JSONEq(t, `{"error":"missing scope","scope":"`+required+`"}`, w.Body.String())
+				}
+			})
+		}
+	}
+}
+
+func TestRoot_LimitedScopes_OnlyShowsAllowedPanels(t *testing.T) {
+	router, _ := createScopeTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: apiKeyCookieName, Value: "media-key"})
+	router.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	assert.Contains(t, body, `id="mpv-remote"`)
+	assert.NotContains(t, body, `id="trackpad"`, "input panels need the input scope")
+	assert.NotContains(t, body, `id="workspace-panel"`, "window panels need the windows scope")
+	assert.NotContains(t, body, "Manage devices", "device management needs the admin scope")
+	assert.Contains(t, body, `<a href="/devices">This device</a>`, "every device can see and revoke itself")
+}
+
+func TestRoot_Admin_ShowsEverything(t *testing.T) {
+	router, _ := createScopeTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: apiKeyCookieName, Value: "admin-key"})
+	router.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	for _, id := range []string{`id="mpv-remote"`, `id="trackpad"`, `id="workspace-panel"`, `href="/devices"`} {
+		assert.Contains(t, body, id)
+	}
+}
+
+func createPairScopesTestServer(t *testing.T, opts ...ServerOption) (*gin.Engine, *Server, *security.KeyStore) {
+	keyStore, _ := createTestKeyStore(t)
+	callCount := 0
+	server := NewServer(append([]ServerOption{
+		WithKeyStore(keyStore),
+		WithShortCodeGenerator(func() (string, error) {
+			callCount++
+			return fmt.Sprintf("CODE%02d", callCount), nil
+		}),
+		WithAPICodeGenerator(func() (string, error) { return "paired-key", nil }),
+		WithOutput(io.Discard),
+		WithLogger(createTestLogger()),
+	}, opts...)...)
+	router := gin.New()
+	server.SetupRoutes(router)
+	return router, server, keyStore
+}
+
+func requestPairScopes(router *gin.Engine, method, remoteAddr string, form url.Values) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, "/pair/scopes", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = remoteAddr
+	req.Host = localHost
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func pairWithCode(router *gin.Engine, code string) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/pair", strings.NewReader(url.Values{shortCodeFormID: {code}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	router.ServeHTTP(w, req)
+}
+
+func TestPairScopes_FromThisComputer_ShowsCurrentCode(t *testing.T) {
+	for _, remoteAddr := range []string{"127.0.0.1:40000", "[::1]:40000"} {
+		router, _, _ := createPairScopesTestServer(t)
+
+		w := requestPairScopes(router, "GET", remoteAddr, nil)
+
+		assert.Equal(t, http.StatusOK, w.Code, remoteAddr)
+		assert.Contains(t, w.Body.String(), "CODE01")
+		assert.Contains(t, w.Body.String(), `value="media" checked`, "the default scopes should be preselected")
+		assert.NotContains(t, w.Body.String(), `value="admin" checked`, "new devices should not get admin by default")
+	}
+}
+
+func TestPairScopes_FromNetwork_Forbidden(t *testing.T) {
+	router, server, _ := createPairScopesTestServer(t)
+
+	get := requestPairScopes(router, "GET", "192.168.1.20:40000", nil)
+	post := requestPairScopes(router, "POST", "192.168.1.20:40000", url.Values{"scope": {"admin"}})
+
+	assert.Equal(t, http.StatusForbidden, get.Code)
+	assert.Equal(t, http.StatusForbidden, post.Code)
+	assert.NotContains(t, get.Body.String(), "CODE01", "the code should not be shown to the network")
+	assert.Empty(t, server.pairing().Code(), "a refused request should not create a code")
+}
+
+func TestPairScopes_Chosen_GrantedToNextDeviceOnly(t *testing.T) {
+	router, _, keyStore := createPairScopesTestServer(t)
+
+	w := requestPairScopes(router, "POST", "127.0.0.1:40000", url.Values{"scope": {"media", "input"}})
+	pairWithCode(router, "CODE01")
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	guest, err := keyStore.GetAPIKey("paired-key")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"input", "media"}, guest.Scopes)
+
+	next := requestPairScopes(router, "GET", "127.0.0.1:40000", nil)
+	assert.Contains(t, next.Body.String(), "CODE02")
+	assert.Contains(t, next.Body.String(), `value="media" checked`)
+	assert.NotContains(t, next.Body.String(), `value="input" checked`, "the next code should go back to the defaults")
+}
+
+func TestPairScopes_HTMXRequest_ReturnsPicker(t *testing.T) {
+	router, _, _ := createPairScopesTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/pair/scopes", strings.NewReader(url.Values{"scope": {"media"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("HX-Request", "true")
+	req.RemoteAddr = "127.0.0.1:40000"
+	req.Host = localHost
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `value="media" checked`)
+	assert.NotContains(t, w.Body.String(), `value="admin" checked`)
+	assert.Contains(t, w.Body.String(), "Saved")
+}
+
+func TestPairScopes_InvalidSelection_BadRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		form url.Values
+	}{
+		{"nothing selected", url.Values{}},
+		{"unknown scope", url.Values{"scope": {"network"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, server, _ := createPairScopesTestServer(t)
+
+			w := requestPairScopes(router, "POST", "127.0.0.1:40000", tt.form)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Empty(t, server.pairing().Scopes(), "a bad selection should not change the grant")
+		})
+	}
+}
+
+func TestPair_WithPairingScopes_GrantsConfiguredDefault(t *testing.T) {
+	router, server, keyStore := createPairScopesTestServer(t, WithPairingScopes(security.ScopeMedia))
+	server.ensurePairingCode()
+
+	pairWithCode(router, "CODE01")
+
+	key, err := keyStore.GetAPIKey("paired-key")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"media"}, key.Scopes)
+}
+
+func TestPairLink_ChosenScopes_Granted(t *testing.T) {
+	router, server, keyStore := createPairScopesTestServer(t, WithPairingTokenGenerator(func() (string, error) { return "link-token", nil }))
+	requestPairScopes(router, "POST", "127.0.0.1:40000", url.Values{"scope": {"windows"}})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/pair?token=link-token", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	key, err := keyStore.GetAPIKey("paired-key")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"windows"}, key.Scopes)
+	assert.Empty(t, server.pairing().Code())
+}
+
+func TestDevices_ListsScopes(t *testing.T) {
+	router, _ := createScopeTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/devices", nil)
+	req.AddCookie(&http.Cookie{Name: apiKeyCookieName, Value: "admin-key"})
+	router.ServeHTTP(w, req)
+
+	var devices []deviceResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &devices))
+	var scopes [][]string
+	for _, device := range devices {
+		scopes = append(scopes, device.Scopes)
+	}
+	assert.ElementsMatch(t, [][]string{{"media"}, {"input"}, {"windows"}, {"admin"}}, scopes)
+}
@@ -1,12 +1,20 @@
 package api
 
 import (
-	"fmt"
 	"io"
+	"net/url"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/phasecurve/sway_rm/internal"
+	"github.com/phasecurve/sway_rm/internal/clock"
+	"github.com/phasecurve/sway_rm/internal/input"
+	"github.com/phasecurve/sway_rm/internal/mpv"
+	"github.com/phasecurve/sway_rm/internal/publish"
 	"github.com/phasecurve/sway_rm/internal/security"
+	"github.com/phasecurve/sway_rm/internal/sway"
 )
 
 type Logger interface {
@@ -14,13 +22,34 @@ type Logger interface {
 }
 
 type Server struct {
-	ShortCodeGenerator ShortCodeGenerator
-	APICodeGenerator   APICodeGenerator
-	KeyStore           security.KeyStorer
-	Output             io.Writer
-	Logger             Logger
-	currentPairingCode string
-	pairingCodeExpiry  time.Time
+	ShortCodeGenerator    ShortCodeGenerator
+	APICodeGenerator      APICodeGenerator
+	PairingTokenGenerator PairingTokenGenerator
+	PairingURL            string
+	LocalURL              string
+	PairingScopes         []string
+	KeyStore              security.KeyStorer
+	Sway                  sway.Controller
+	SwayEvents            sway.EventSubscriber
+	MPV                   mpv.Player
+	NowPlaying            mpv.NowPlayingSubscriber
+	MPVInstances          mpv.InstanceRegistry
+	Input                 input.InputInjector
+	Output                io.Writer
+	Publisher             publish.CodePublisher
+	Logger                Logger
+	Clock                 clock.Clock
+	pairingLimits         PairingLimits
+	lifetimes             SessionLifetimes
+	pairGuardOnce         sync.Once
+	pairGuard             *pairGuard
+	pairingOnce           sync.Once
+	pairingManager        *PairingManager
+	pairRequestsOnce      sync.Once
+	pairRequestManager    *PairRequests
+	pairRequestWait       time.Duration
+	publishOnce           sync.Once
+	publishJobs           chan func()
 }
 
 type ServerOption func(*Server)
@@ -43,12 +72,105 @@ func WithAPICodeGenerator(gen APICodeGenerator) ServerOption {
 	}
 }
 
+func WithPairingTokenGenerator(gen PairingTokenGenerator) ServerOption {
+	return func(s *Server) {
+		s.PairingTokenGenerator = gen
+	}
+}
+
+// WithPairingURL sets the base URL phones reach the server on, e.g.
+// http://192.168.1.20:8080, used to build QR pairing links.
+func WithPairingURL(baseURL string) ServerOption {
+	return func(s *Server) {
+		s.PairingURL = baseURL
+	}
+}
+
+// WithLocalURL sets the URL the server is reached on from this machine,
+// e.g. http://localhost:8080, used to link to the local-only pages.
+func WithLocalURL(baseURL string) ServerOption {
+	return func(s *Server) {
+		s.LocalURL = baseURL
+	}
+}
+
+// WithPairingScopes sets the scopes newly paired devices get unless they're
+// changed on the pairing scope picker. Defaults to media, so a device only
+// gets admin when it's chosen for it.
+func WithPairingScopes(scopes ...string) ServerOption {
+	return func(s *Server) {
+		s.PairingScopes = scopes
+	}
+}
+
+func WithSway(controller sway.Controller) ServerOption {
+	return func(s *Server) {
+		s.Sway = controller
+	}
+}
+
+func WithSwayEvents(events sway.EventSubscriber) ServerOption {
+	return func(s *Server) {
+		s.SwayEvents = events
+	}
+}
+
+func WithMPV(player mpv.Player) ServerOption {
+	return func(s *Server) {
+		s.MPV = player
+	}
+}
+
+func WithNowPlaying(nowPlaying mpv.NowPlayingSubscriber) ServerOption {
+	return func(s *Server) {
+		s.NowPlaying = nowPlaying
+	}
+}
+
+func WithMPVInstances(instances mpv.InstanceRegistry) ServerOption {
+	return func(s *Server) {
+		s.MPVInstances = instances
+	}
+}
+
+func WithInput(injector input.InputInjector) ServerOption {
+	return func(s *Server) {
+		s.Input = injector
+	}
+}
+
 func WithOutput(output io.Writer) ServerOption {
 	return func(s *Server) {
 		s.Output = output
 	}
 }
 
+// WithPublishers replaces printing pairing codes to Output with the given
+// publishers.
+func WithPublishers(publishers ...publish.CodePublisher) ServerOption {
+	return func(s *Server) {
+		s.Publisher = publish.All(publishers...)
+	}
+}
+
+func WithClock(c clock.Clock) ServerOption {
+	return func(s *Server) {
+		s.Clock = c
+	}
+}
+
+func WithPairingLimits(limits PairingLimits) ServerOption {
+	return func(s *Server) {
+		s.pairingLimits = limits
+	}
+}
+
+func WithSessionLifetimes(lifetimes SessionLifetimes) ServerOption {
+	return func(s *Server) {
+		s.lifetimes = lifetimes
+	}
+}
+
 func WithLogger(logger Logger) ServerOption {
 	return func(s *Server) {
 		s.Logger = logger
@@ -57,9 +179,13 @@ func WithLogger(logger Logger) ServerOption {
 
 func NewServer(opts ...ServerOption) *Server {
 	s := &Server{
-		ShortCodeGenerator: security.GenerateShortCode,
-		APICodeGenerator:   security.GenerateAPIKey,
-		Output:             os.Stdout,
+		ShortCodeGenerator:    security.GenerateShortCode,
+		APICodeGenerator:      security.GenerateAPIKey,
+		PairingTokenGenerator: security.GeneratePairingToken,
+		Output:                os.Stdout,
+		Clock:                 clock.Real{},
+		pairingLimits:         DefaultPairingLimits,
+		lifetimes:             DefaultSessionLifetimes,
 	}
 	for _, opt := range opts {
 		opt(s)
@@ -67,40 +193,148 @@ func NewServer(opts ...ServerOption) *Server {
 	return s
 }
 
-func (s *Server) resetPairingCode() {
-	s.currentPairingCode = ""
+func (s *Server) publishShortCode(code PairingCode) {
+	published := publish.Code{
+		Code:      code.Code,
+		Link:      s.pairingLink(code.Token),
+		Expiry:    code.Expiry,
+		ExpiresIn: code.Expiry.Sub(s.getClock().Now()),
+	}
+	publisher := s.getPublisher()
+	s.publishInBackground("pairing code", func() error {
+		return publisher.PublishCode(published)
+	})
 }
 
-func (s *Server) publishShortCode() {
-	fmt.Fprintf(s.Output, `
+// publishQueueSize is how many codes and requests may wait on slow
+// publishers before more are dropped.
+const publishQueueSize = 16
 
-		╔════════════════════════╗
-		║  Pairing code: %s  ║
-		╚════════════════════════╝
+// publishInBackground queues publish so slow hooks and notifications don't
+// hold up the request. A single worker keeps codes in the order issued.
+func (s *Server) publishInBackground(what string, publish func() error) {
+	s.publishOnce.Do(func() {
+		s.publishJobs = make(chan func(), publishQueueSize)
+		go func() {
+			for job := range s.publishJobs {
+				job()
+			}
+		}()
+	})
+	job := func() {
+		if err := publish(); err != nil {
+			s.Logger.Printf("failed to publish %s: %v", what, err)
+		}
+	}
+	select {
+	case s.publishJobs <- job:
+	default:
+		s.Logger.Printf("dropped %s: publishers are falling behind", what)
+	}
+}
+
+func (s *Server) pairingLink(token string) string {
+	if token == "" || s.PairingURL == "" {
+		return ""
+	}
+	return strings.TrimSuffix(s.PairingURL, "/") + "/pair?" + url.Values{pairingTokenQueryID: {token}}.Encode()
+}
+
+func (s *Server) ensurePairingCode() error {
+	code, generated, err := s.pairing().GenerateIfExpired()
+	if err != nil {
+		return err
+	}
+	if generated {
+		s.getPairGuard().newCode()
+		s.publishShortCode(code)
+	}
+	return nil
+}
 
-`, s.getCurrentPairingCode())
+func (s *Server) rotatePairingCode() {
+	code, err := s.pairing().Rotate()
+	if err != nil {
+		s.Logger.Printf("failed to rotate pairing code: %v", err)
+		return
+	}
+	s.getPairGuard().newCode()
+	s.publishShortCode(code)
 }
 
-func (s *Server) setNewShortCodeExpiry() {
-	s.pairingCodeExpiry = time.Now().Add(5 * time.Minute)
+func (s *Server) pairing() *PairingManager {
+	s.pairingOnce.Do(func() {
+		s.pairingManager = NewPairingManager(s.ShortCodeGenerator, s.PairingTokenGenerator, s.getClock(), s.getLifetimes().PairingCode)
+		if len(s.PairingScopes) > 0 {
+			s.pairingManager.SetDefaultScopes(s.PairingScopes)
+		}
+	})
+	return s.pairingManager
 }
 
-func (s *Server) setNewShortCode() {
-	s.currentPairingCode = s.ShortCodeGenerator()
+func (s *Server) pairRequests() *PairRequests {
+	s.pairRequestsOnce.Do(func() {
+		s.pairRequestManager = NewPairRequests(s.getClock(), defaultPairRequestLifetime)
+	})
+	return s.pairRequestManager
 }
 
-func (s *Server) hasShortCodeExpired() bool {
-	return s.getPairingCodeExpiry().Before(time.Now())
+func (s *Server) getPairRequestWait() time.Duration {
+	if s.pairRequestWait <= 0 {
+		return defaultPairRequestWait
+	}
+	return s.pairRequestWait
 }
 
-func (s *Server) isShortCodeSet() bool {
-	return s.getCurrentPairingCode() != ""
+func (s *Server) publishPairRequest(request internal.PairRequest) {
+	publisher, ok := s.getPublisher().(publish.RequestPublisher)
+	if !ok {
+		return
+	}
+	var reviewURL string
+	if s.LocalURL != "" {
+		reviewURL = strings.TrimSuffix(s.LocalURL, "/") + "/pair/requests"
+	}
+	published := publish.Request{
+		DeviceName: request.DeviceName,
+		RemoteIP:   request.RemoteIP,
+		CheckCode:  request.CheckCode,
+		ReviewURL:  reviewURL,
+		Expiry:     request.Expiry,
+	}
+	s.publishInBackground("pairing request", func() error {
+		return publisher.PublishRequest(published)
+	})
+}
+
+func (s *Server) getPublisher() publish.CodePublisher {
+	if s.Publisher == nil {
+		if s.Output == nil {
+			return publish.NewTerminal(io.Discard)
+		}
+		return publish.NewTerminal(s.Output)
+	}
+	return s.Publisher
+}
+
+func (s *Server) getClock() clock.Clock {
+	if s.Clock == nil {
+		return clock.Real{}
+	}
+	return s.Clock
 }
 
-func (s *Server) getCurrentPairingCode() string {
-	return s.currentPairingCode
+func (s *Server) getLifetimes() SessionLifetimes {
+	return s.lifetimes.withDefaults()
 }
 
-func (s *Server) getPairingCodeExpiry() time.Time {
-	return s.pairingCodeExpiry
+func (s *Server) getPairGuard() *pairGuard {
+	s.pairGuardOnce.Do(func() {
+		limits := s.pairingLimits
+		if limits == (PairingLimits{}) {
+			limits = DefaultPairingLimits
+		}
+		s.pairGuard = newPairGuard(limits)
+	})
+	return s.pairGuard
 }
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/phasecurve/sway_rm/internal/components"
+	"github.com/phasecurve/sway_rm/internal/middleware"
+	"github.com/phasecurve/sway_rm/internal/security"
+)
+
+const (
+	tokenNameFormID          = "name"
+	tokenScopeFormID         = "scope"
+	tokenDaysFormID          = "days"
+	maxTokenNameLength       = 64
+	defaultTokenLifetimeDays = 365
+	maxTokenLifetimeDays     = 3650
+)
+
+type tokenResponse struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Token     string    `json:"token"`
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// postToken mints a named long-lived token for scripts. Only a paired
+// browser session may mint tokens, so a leaked token can't create more.
+func (s *Server) postToken(c *gin.Context) {
+	caller, _ := middleware.APIKey(c)
+	if caller == nil || caller.Type != security.KeyTypeSession {
+		c.String(http.StatusForbidden, "tokens can only be created from a paired session")
+		return
+	}
+
+	name := strings.TrimSpace(c.PostForm(tokenNameFormID))
+	if name == "" || len(name) > maxTokenNameLength {
+		c.String(http.StatusBadRequest, "token name must be 1 to %d characters", maxTokenNameLength)
+		return
+	}
+	scopes, err := security.ParseScopes(c.PostFormArray(tokenScopeFormID)...)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(scopes) == 0 {
+		c.String(http.StatusBadRequest, "choose at least one scope")
+		return
+	}
+	days, err := parseTokenDays(c.PostForm(tokenDaysFormID))
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	raw, err := s.APICodeGenerator()
+	if err != nil {
+		s.Logger.Printf("failed to generate api token: %v", err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	now := s.getClock().Now()
+	key := &security.APIKey{
+		Key:        raw,
+		Type:       security.KeyTypeToken,
+		TTL:        now.AddDate(0, 0, days),
+		DeviceName: name,
+		UserAgent:  c.Request.UserAgent(),
+		PairedAt:   now,
+		RemoteIP:   remoteIP(c),
+		Scopes:     scopes,
+	}
+	if err := s.KeyStore.SaveAPIKey(key); err != nil {
+		s.Logger.Printf("failed to save api token: %v", err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	if isHTMXRequest(c) {
+		c.Header("HX-Trigger", devicesChangedEvent)
+		c.Header("Content-Type", "text/html")
+		c.Status(http.StatusCreated)
+		component := components.TokenCreated(name, raw)
+		component.Render(c.Request.Context(), c.Writer)
+		return
+	}
+	c.JSON(http.StatusCreated, tokenResponse{
+		ID:        key.ID,
+		Name:      name,
+		Token:     raw,
+		Scopes:    scopes,
+		ExpiresAt: key.TTL,
+	})
+}
+
+func parseTokenDays(value string) (int, error) {
+	if value == "" {
+		return defaultTokenLifetimeDays, nil
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 1 || days > maxTokenLifetimeDays {
+		return 0, errors.New("days must be a whole number from 1 to " + strconv.Itoa(maxTokenLifetimeDays))
+	}
+	return days, nil
+}
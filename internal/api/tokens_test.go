@@ -0,0 +1,236 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phasecurve/sway_rm/internal/clock/clocktest"
+	"github.com/phasecurve/sway_rm/internal/security"
+)
+
+func createTokenTestServer(t *testing.T) (*gin.Engine, *security.KeyStore, *clocktest.Fake) {
+	fakeClock := clocktest.NewFake(testClockStart)
+	keyStore, _ := createTestKeyStore(t, security.WithClock(fakeClock))
+	assert.NoError(t, keyStore.SaveAPIKey(&security.APIKey{Key: "session-key", TTL: testClockStart.Add(time.Hour), Scopes: []string{security.ScopeAdmin}}))
+	server := NewServer(
+		WithKeyStore(keyStore),
+		WithClock(fakeClock),
+		WithAPICodeGenerator(func() (string, error) { return "minted-token", nil }),
+		WithOutput(io.Discard),
+		WithLogger(createTestLogger()),
+	)
+	router := gin.New()
+	server.SetupRoutes(router)
+	return router, keyStore, fakeClock
+}
+
+func postTokenWith(router *gin.Engine, form url.Values, auth func(*http.Request)) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/tokens", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	auth(req)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func withCookie(key string) func(*http.Request) {
+	return func(req *http.Request) {
+		req.AddCookie(&http.Cookie{Name: apiKeyCookieName, Value: key})
+	}
+}
+
+func withBearer(key string) func(*http.Request) {
+	return func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+}
+
+func getWith(router *gin.Engine, path string, auth func(*http.Request)) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", path, nil)
+	auth(req)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestToken_FromSession_MintsLongLivedToken(t *testing.T) {
+	router, keyStore, _ := createTokenTestServer(t)
+
+	w := postTokenWith(router, url.Values{"name": {"home-assistant"}, "scope": {"media,input", "media"}}, withCookie("session-key"))
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var body tokenResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "minted-token", body.Token)
+	assert.Equal(t, "home-assistant", body.Name)
+	assert.Equal(t, []string{"input", "media"}, body.Scopes, "scopes should be split, sorted and deduplicated")
+	assert.True(t, testClockStart.AddDate(0, 0, 365).Equal(body.ExpiresAt), "tokens should last a year by default")
+
+	stored, err := keyStore.GetAPIKey("minted-token")
+	assert.NoError(t, err)
+	assert.Equal(t, security.KeyTypeToken, stored.Type)
+	assert.Equal(t, body.ID, stored.ID)
+	assert.Equal(t, []string{"input", "media"}, stored.Scopes)
+}
+
+func TestToken_CustomLifetime(t *testing.T) {
+	router, _, _ := createTokenTestServer(t)
+
+	w := postTokenWith(router, url.Values{"name": {"cli"}, "scope": {"media"}, "days": {"30"}}, withCookie("session-key"))
+
+	var body tokenResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.True(t, testClockStart.AddDate(0, 0, 30).Equal(body.ExpiresAt))
+}
+
+func TestToken_InvalidInput_BadRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		form url.Values
+	}{
+		{"missing name", url.Values{}},
+		{"blank name", url.Values{"name": {"   "}}},
+		{"long name", url.Values{"name": {strings.Repeat("n", 65)}}},
+		{"no scopes", url.Values{"name": {"cli"}}},
+		{"bad scope", url.Values{"name": {"cli"}, "scope": {"Media!"}}},
+		{"unknown scope", url.Values{"name": {"cli"}, "scope": {"media,network"}}},
+		{"zero days", url.Values{"name": {"cli"}, "scope": {"media"}, "days": {"0"}}},
+		{"too many days", url.Values{"name": {"cli"}, "scope": {"media"}, "days": {"3651"}}},
+		{"non-numeric days", url.Values{"name": {"cli"}, "scope": {"media"}, "days": {"forever"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, keyStore, _ := createTokenTestServer(t)
+
+			w := postTokenWith(router, tt.form, withCookie("session-key"))
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			_, err := keyStore.GetAPIKey("minted-token")
+			assert.ErrorIs(t, err, security.ErrKeyNotFound, "invalid requests should not mint a token")
+		})
+	}
+}
+
+func TestToken_FromToken_Forbidden(t *testing.T) {
+	router, keyStore, _ := createTokenTestServer(t)
+	assert.NoError(t, keyStore.SaveAPIKey(&security.APIKey{Key: "script-token", Type: security.KeyTypeToken, TTL: testClockStart.Add(time.Hour), Scopes: []string{security.ScopeAdmin}}))
+
+	w := postTokenWith(router, url.Values{"name": {"escalate"}, "scope": {"admin"}}, withBearer("script-token"))
+
+	assert.Equal(t, http.StatusForbidden, w.Code, "a token should not be able to mint more tokens")
+}
+
+func TestToken_HTMXRequest_ShowsTokenOnceAndRefreshesDevices(t *testing.T) {
+	router, _, _ := createTokenTestServer(t)
+
+	w := postTokenWith(router, url.Values{"name": {"cli"}, "scope": {"media"}}, func(req *http.Request) {
+		withCookie("session-key")(req)
+		req.Header.Set("HX-Request", "true")
+	})
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "devices-changed", w.Header().Get("HX-Trigger"))
+	assert.Contains(t, w.Body.String(), "minted-token")
+	assert.Contains(t, w.Body.String(), "won't be shown again")
+}
+
+func TestBearer_MintedToken_AuthenticatesAPI(t *testing.T) {
+	router, _, _ := createTokenTestServer(t)
+	postTokenWith(router, url.Values{"name": {"cli"}, "scope": {"admin"}}, withCookie("session-key"))
+
+	status := getWith(router, "/api/status", withBearer("minted-token"))
+	devices := getWith(router, "/api/devices", withBearer("minted-token"))
+
+	assert.Equal(t, http.StatusOK, status.Code)
+	assert.Equal(t, http.StatusOK, devices.Code)
+	var listed []deviceResponse
+	assert.NoError(t, json.Unmarshal(devices.Body.Bytes(), &listed))
+	types := map[string]string{}
+	for _, device := range listed {
+		types[device.Name] = device.Type
+	}
+	assert.Equal(t, "token", types["cli"])
+}
+
+func TestBearer_AuthorizationHeaderVariants(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expected int
+	}{
+		{"bearer", "Bearer session-key", http.StatusOK},
+		{"lowercase scheme", "bearer session-key", http.StatusOK},
+		{"unknown key", "Bearer nope", http.StatusUnauthorized},
+		{"basic scheme", "Basic session-key", http.StatusUnauthorized},
+		{"empty token", "Bearer ", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, _, _ := createTokenTestServer(t)
+
+			w := getWith(router, "/api/devices", func(req *http.Request) {
+				req.Header.Set("Authorization", tt.header)
+			})
+
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+}
+
+func TestBearer_HeaderTakesPrecedenceOverCookie(t *testing.T) {
+	router, _, _ := createTokenTestServer(t)
+
+	w := getWith(router, "/api/devices", func(req *http.Request) {
+		withCookie("session-key")(req)
+		withBearer("revoked-token")(req)
+	})
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "an explicit bad token should not fall back to the cookie")
+}
+
+func TestPairRefresh_Token_KeepsMintedExpiry(t *testing.T) {
+	router, keyStore, fakeClock := createTokenTestServer(t)
+	postTokenWith(router, url.Values{"name": {"cli"}, "scope": {"media"}, "days": {"1"}}, withCookie("session-key"))
+	fakeClock.Advance(time.Hour)
+
+	getWith(router, "/api/status", withBearer("minted-token"))
+
+	stored, err := keyStore.GetAPIKey("minted-token")
+	assert.NoError(t, err)
+	assert.True(t, testClockStart.AddDate(0, 0, 1).Equal(stored.TTL), "tokens should not slide their expiry")
+}
+
+func TestPairRefresh_SessionViaBearer_ExtendsExpiry(t *testing.T) {
+	router, keyStore, fakeClock := createTokenTestServer(t)
+	fakeClock.Advance(50 * time.Minute)
+
+	w := getWith(router, "/api/status", withBearer("session-key"))
+
+	stored, _ := keyStore.GetAPIKey("session-key")
+	assert.True(t, testClockStart.Add(80*time.Minute).Equal(stored.TTL), "sessions should refresh however the key is presented")
+	assert.Empty(t, w.Result().Cookies(), "bearer clients should not be handed a cookie")
+}
+
+func TestBearer_TokenScopes_LimitRoutes(t *testing.T) {
+	router, _, _ := createTokenTestServer(t)
+	postTokenWith(router, url.Values{"name": {"media-only"}, "scope": {"media"}}, withCookie("session-key"))
+
+	windows := getWith(router, "/api/workspaces", withBearer("minted-token"))
+	media := getWith(router, "/api/mpv/instances", withBearer("minted-token"))
+	devices := getWith(router, "/api/devices", withBearer("minted-token"))
+
+	var listed []deviceResponse
+	assert.Equal(t, http.StatusForbidden, windows.Code, "a media token should not control windows")
+	assert.NotEqual(t, http.StatusForbidden, media.Code)
+	assert.NoError(t, json.Unmarshal(devices.Body.Bytes(), &listed))
+	assert.Len(t, listed, 1, "a media token should only see itself")
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/phasecurve/sway_rm/internal/components"
+	"github.com/phasecurve/sway_rm/internal/sway"
+)
+
+const (
+	workspaceFormID = "workspace"
+	layoutFormID    = "layout"
+)
+
+var windowCommands = map[string]string{
+	"focus":      "focus",
+	"kill":       "kill",
+	"fullscreen": "fullscreen toggle",
+	"float":      "floating toggle",
+}
+
+var containerLayouts = map[string]bool{
+	"splith":   true,
+	"splitv":   true,
+	"tabbed":   true,
+	"stacking": true,
+}
+
+func (s *Server) getTree(c *gin.Context) {
+	s.renderTree(c)
+}
+
+func (s *Server) postWindowAction(c *gin.Context) {
+	conID, err := strconv.ParseInt(c.Param("con_id"), 10, 64)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	action := c.Param("action")
+	command, ok := windowCommands[action]
+	if action == "move-to-workspace" {
+		workspace := c.PostForm(workspaceFormID)
+		if workspace == "" {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		command = "move container to workspace " + sway.Quote(workspace)
+	} else if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	s.runContainerCommand(c, conID, command)
+}
+
+func (s *Server) postContainerLayout(c *gin.Context) {
+	conID, err := strconv.ParseInt(c.Param("con_id"), 10, 64)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	layout := c.PostForm(layoutFormID)
+	if !containerLayouts[layout] {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	s.runContainerCommand(c, conID, "layout "+layout)
+}
+
+func (s *Server) runContainerCommand(c *gin.Context, conID int64, command string) {
+	if s.Sway == nil {
+		c.Status(http.StatusServiceUnavailable)
+		return
+	}
+	if _, err := s.Sway.RunCommand(fmt.Sprintf("[con_id=%d] %s", conID, command)); err != nil {
+		s.Logger.Printf("failed to run %q on container %d: %v", command, conID, err)
+		c.Status(http.StatusBadGateway)
+		return
+	}
+
+	if isHTMXRequest(c) {
+		s.renderTree(c)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) renderTree(c *gin.Context) {
+	if s.Sway == nil {
+		c.Status(http.StatusServiceUnavailable)
+		return
+	}
+	tree, err := s.Sway.GetTree()
+	if err != nil {
+		s.Logger.Printf("failed to get window tree: %v", err)
+		c.Status(http.StatusBadGateway)
+		return
+	}
+
+	if isHTMXRequest(c) {
+		c.Header("Content-Type", "text/html")
+		component := components.WindowTreeNodes(tree)
+		component.Render(c.Request.Context(), c.Writer)
+		return
+	}
+	c.JSON(http.StatusOK, tree)
+}
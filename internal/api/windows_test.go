@@ -0,0 +1,179 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phasecurve/sway_rm/internal/security"
+	"github.com/phasecurve/sway_rm/internal/sway"
+	"github.com/phasecurve/sway_rm/internal/sway/swaytest"
+)
+
+const testTreeReply = `{"id":1,"type":"root","nodes":[
+	{"id":2,"type":"output","name":"eDP-1","nodes":[
+		{"id":3,"type":"workspace","name":"1","layout":"splith","nodes":[
+			{"id":10,"type":"con","name":"Big Buck Bunny","app_id":"mpv","focused":true},
+			{"id":11,"type":"con","name":"~","window":99,"window_properties":{"class":"XTerm"}}
+		]}
+	]}
+]}`
+
+func createWindowTestServer(t *testing.T) (*gin.Engine, *security.KeyStore, *swaytest.Server) {
+	router := gin.Default()
+	keyStore, _ := createTestKeyStore(t)
+	swayClient, fake := createTestSway(t)
+	fake.SetReply(uint32(sway.MessageGetTree), testTreeReply)
+	server := &Server{
+		KeyStore: keyStore,
+		Sway:     swayClient,
+		Logger:   createTestLogger(),
+	}
+	server.SetupRoutes(router)
+	return router, keyStore, fake
+}
+
+func postPairedForm(t *testing.T, router *gin.Engine, keyStore security.KeyStorer, path string, form url.Values) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	addPairedCookie(t, keyStore, req)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestTree_NotPaired_Unauthorized(t *testing.T) {
+	router, _, _ := createWindowTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/tree", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestTree_Paired_ReturnsSwayTree(t *testing.T) {
+	router, keyStore, _ := createWindowTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/tree", nil)
+	addPairedCookie(t, keyStore, req)
+	router.ServeHTTP(w, req)
+
+	var tree sway.Node
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tree))
+	assert.Equal(t, "mpv", *tree.Nodes[0].Nodes[0].Nodes[0].AppID)
+}
+
+func TestTree_HTMXRequest_RendersWindows(t *testing.T) {
+	router, keyStore, _ := createWindowTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/tree", nil)
+	req.Header.Set("HX-Request", "true")
+	addPairedCookie(t, keyStore, req)
+	router.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	assert.Contains(t, body, "<details", "containers should be collapsible")
+	assert.Contains(t, body, "Big Buck Bunny", "window title should be shown")
+	assert.Contains(t, body, `<span class="window-app">XTerm</span>`, "xwayland windows should show their class")
+	assert.Contains(t, body, `<span class="window-icon">M</span>`, "windows should have an icon")
+	assert.Contains(t, body, `hx-post="/api/windows/10/kill"`, "windows should have actions")
+	assert.Contains(t, body, `hx-post="/api/containers/3/layout"`, "containers should have layout actions")
+}
+
+func TestWindowAction_Paired_RunsCommandForContainer(t *testing.T) {
+	tests := []struct {
+		action   string
+		form     url.Values
+		expected string
+	}{
+		{"focus", nil, "[con_id=10] focus"},
+		{"kill", nil, "[con_id=10] kill"},
+		{"fullscreen", nil, "[con_id=10] fullscreen toggle"},
+		{"float", nil, "[con_id=10] floating toggle"},
+		{"move-to-workspace", url.Values{"workspace": {"2:web"}}, `[con_id=10] move container to workspace "2:web"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			router, keyStore, fake := createWindowTestServer(t)
+
+			w := postPairedForm(t, router, keyStore, "/api/windows/10/"+tt.action, tt.form)
+
+			assert.Equal(t, http.StatusNoContent, w.Code)
+			assert.Equal(t, []string{tt.expected}, fake.Commands())
+		})
+	}
+}
+
+func TestWindowAction_InvalidRequests_Rejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		expected int
+	}{
+		{"non-numeric id", "/api/windows/abc/focus", http.StatusBadRequest},
+		{"unknown action", "/api/windows/10/explode", http.StatusNotFound},
+		{"move without workspace", "/api/windows/10/move-to-workspace", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, keyStore, fake := createWindowTestServer(t)
+
+			w := postPairedForm(t, router, keyStore, tt.path, nil)
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Empty(t, fake.Commands(), "invalid requests should not reach sway")
+		})
+	}
+}
+
+func TestWindowAction_NotPaired_DoesNotRunCommand(t *testing.T) {
+	router, _, fake := createWindowTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/windows/10/kill", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, fake.Commands(), "unpaired request should not kill windows")
+}
+
+func TestContainerLayout_ValidLayout_RunsCommand(t *testing.T) {
+	router, keyStore, fake := createWindowTestServer(t)
+
+	w := postPairedForm(t, router, keyStore, "/api/containers/3/layout", url.Values{"layout": {"tabbed"}})
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, []string{"[con_id=3] layout tabbed"}, fake.Commands())
+}
+
+func TestContainerLayout_InvalidLayout_BadRequest(t *testing.T) {
+	router, keyStore, fake := createWindowTestServer(t)
+
+	w := postPairedForm(t, router, keyStore, "/api/containers/3/layout", url.Values{"layout": {"toggle all; exit"}})
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, fake.Commands(), "unknown layouts should not reach sway")
+}
+
+func TestWindowAction_HTMXRequest_RendersUpdatedTree(t *testing.T) {
+	router, keyStore, _ := createWindowTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/windows/10/focus", nil)
+	req.Header.Set("HX-Request", "true")
+	addPairedCookie(t, keyStore, req)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `id="window-tree"`, "action should swap in the refreshed tree")
+}
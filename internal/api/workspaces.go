@@ -0,0 +1,102 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/phasecurve/sway_rm/internal/components"
+	"github.com/phasecurve/sway_rm/internal/sway"
+)
+
+const workspacesEvent = "workspaces"
+
+func (s *Server) getWorkspaces(c *gin.Context) {
+	s.renderWorkspaces(c)
+}
+
+func (s *Server) postFocusWorkspace(c *gin.Context) {
+	if s.Sway == nil {
+		c.Status(http.StatusServiceUnavailable)
+		return
+	}
+
+	name := c.Param("name")
+	if _, err := s.Sway.RunCommand("workspace " + sway.Quote(name)); err != nil {
+		s.Logger.Printf("failed to focus workspace %q: %v", name, err)
+		c.Status(http.StatusBadGateway)
+		return
+	}
+
+	if isHTMXRequest(c) {
+		s.renderWorkspaces(c)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) renderWorkspaces(c *gin.Context) {
+	if s.Sway == nil {
+		c.Status(http.StatusServiceUnavailable)
+		return
+	}
+	workspaces, err := s.Sway.GetWorkspaces()
+	if err != nil {
+		s.Logger.Printf("failed to list workspaces: %v", err)
+		c.Status(http.StatusBadGateway)
+		return
+	}
+
+	if isHTMXRequest(c) {
+		c.Header("Content-Type", "text/html")
+		component := components.WorkspaceTiles(workspaces)
+		component.Render(c.Request.Context(), c.Writer)
+		return
+	}
+	c.JSON(http.StatusOK, workspaces)
+}
+
+// getWorkspaceEvents streams fresh workspace tiles whenever sway reports a
+// workspace or output change.
+func (s *Server) getWorkspaceEvents(c *gin.Context) {
+	if s.Sway == nil || s.SwayEvents == nil {
+		c.Status(http.StatusServiceUnavailable)
+		return
+	}
+
+	events, unsubscribe := s.SwayEvents.Subscribe()
+	defer unsubscribe()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	s.sendWorkspaces(c)
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if event.Type == sway.EventWorkspace || event.Type == sway.EventOutput {
+				s.sendWorkspaces(c)
+			}
+		}
+	}
+}
+
+func (s *Server) sendWorkspaces(c *gin.Context) {
+	workspaces, err := s.Sway.GetWorkspaces()
+	if err != nil {
+		s.Logger.Printf("failed to list workspaces: %v", err)
+		return
+	}
+	var fragment bytes.Buffer
+	if err := components.WorkspaceTiles(workspaces).Render(c.Request.Context(), &fragment); err != nil {
+		s.Logger.Printf("failed to render workspaces: %v", err)
+		return
+	}
+	c.SSEvent(workspacesEvent, fragment.String())
+	c.Writer.Flush()
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phasecurve/sway_rm/internal/security"
+	"github.com/phasecurve/sway_rm/internal/sway"
+	"github.com/phasecurve/sway_rm/internal/sway/swaytest"
+)
+
+const testWorkspacesReply = `[
+	{"num":1,"name":"1","visible":true,"focused":true,"urgent":false,"output":"eDP-1"},
+	{"num":2,"name":"2:web","visible":false,"focused":false,"urgent":true,"output":"HDMI-A-1"}
+]`
+
+func createWorkspaceTestServer(t *testing.T) (*gin.Engine, *security.KeyStore, *swaytest.Server) {
+	router := gin.Default()
+	keyStore, _ := createTestKeyStore(t)
+	swayClient, fake := createTestSway(t)
+	fake.SetReply(uint32(sway.MessageGetWorkspaces), testWorkspacesReply)
+	events := sway.NewEventStream(fake.SocketPath, createTestLogger())
+	t.Cleanup(events.Close)
+	events.Start()
+	server := &Server{
+		KeyStore:   keyStore,
+		Sway:       swayClient,
+		SwayEvents: events,
+		Logger:     createTestLogger(),
+	}
+	server.SetupRoutes(router)
+	return router, keyStore, fake
+}
+
+func TestWorkspaces_NotPaired_Unauthorized(t *testing.T) {
+	router, _, _ := createWorkspaceTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/workspaces", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "unpaired request should not list workspaces")
+}
+
+func TestWorkspaces_Paired_ReturnsJSONWithFlags(t *testing.T) {
+	router, keyStore, _ := createWorkspaceTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/workspaces", nil)
+	addPairedCookie(t, keyStore, req)
+	router.ServeHTTP(w, req)
+
+	var workspaces []sway.Workspace
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &workspaces))
+	assert.Len(t, workspaces, 2)
+	assert.True(t, workspaces[0].Focused, "focused flag should be included")
+	assert.True(t, workspaces[0].Visible, "visible flag should be included")
+	assert.True(t, workspaces[1].Urgent, "urgent flag should be included")
+	assert.Equal(t, "HDMI-A-1", workspaces[1].Output, "output should be included")
+}
+
+func TestWorkspaces_HTMXRequest_RendersTiles(t *testing.T) {
+	router, keyStore, _ := createWorkspaceTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/workspaces", nil)
+	req.Header.Set("HX-Request", "true")
+	addPairedCookie(t, keyStore, req)
+	router.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	assert.Contains(t, body, `class="workspace-tile focused visible"`, "focused workspace tile should be marked")
+	assert.Contains(t, body, `class="workspace-tile urgent"`, "urgent workspace tile should be marked")
+	assert.Contains(t, body, `hx-post="/api/workspaces/2:web/focus"`, "tiles should post focus requests")
+	assert.NotContains(t, body, "hx-trigger", "tiles should refresh from sway events, not polling")
+}
+
+func TestFocusWorkspace_Paired_RunsSwayCommand(t *testing.T) {
+	router, keyStore, fake := createWorkspaceTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/workspaces/2:web/focus", nil)
+	addPairedCookie(t, keyStore, req)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, []string{`workspace "2:web"`}, fake.Commands(), "should ask sway to focus the workspace")
+}
+
+func TestFocusWorkspace_NotPaired_DoesNotRunCommand(t *testing.T) {
+	router, _, fake := createWorkspaceTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/workspaces/1/focus", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, fake.Commands(), "unpaired request should not reach sway")
+}
+
+func TestFocusWorkspace_SwayRejects_ReturnsBadGateway(t *testing.T) {
+	router, keyStore, fake := createWorkspaceTestServer(t)
+	fake.SetReply(uint32(sway.MessageRunCommand), `[{"success":false,"error":"no such workspace"}]`)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/workspaces/9/focus", nil)
+	addPairedCookie(t, keyStore, req)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+}
+
+// readSSEData reads an event stream until a data line contains contains.
+func readSSEData(t *testing.T, lines *bufio.Scanner, contains string) string {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) && lines.Scan() {
+		line := lines.Text()
+		if strings.HasPrefix(line, "data:") && strings.Contains(line, contains) {
+			return line
+		}
+	}
+	t.Fatalf("did not receive SSE data containing %q", contains)
+	return ""
+}
+
+func TestWorkspaceEvents_SwayEvent_StreamsTiles(t *testing.T) {
+	router, keyStore, fake := createWorkspaceTestServer(t)
+	httpServer := httptest.NewServer(router)
+	t.Cleanup(httpServer.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", httpServer.URL+"/api/workspaces/events", nil)
+	addPairedCookie(t, keyStore, req)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	lines := bufio.NewScanner(resp.Body)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/event-stream")
+	readSSEData(t, lines, "2:web")
+
+	assert.Eventually(t, func() bool { return fake.Subscribers() == 1 }, time.Second, 5*time.Millisecond)
+	fake.SetReply(uint32(sway.MessageGetWorkspaces), `[{"num":3,"name":"3:chat","focused":true,"output":"eDP-1"}]`)
+	fake.Emit(uint32(sway.EventWindow), `{"change":"title","container":{"name":"ignored"}}`)
+	fake.Emit(uint32(sway.EventWorkspace), `{"change":"focus","current":{"name":"3:chat"}}`)
+
+	readSSEData(t, lines, "3:chat")
+}
+
+func TestWorkspaceEvents_NotPaired_Unauthorized(t *testing.T) {
+	router, _, _ := createWorkspaceTestServer(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/workspaces/events", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestWorkspaceEvents_NoEventStream_Unavailable(t *testing.T) {
+	router := gin.Default()
+	keyStore, _ := createTestKeyStore(t)
+	swayClient, _ := createTestSway(t)
+	server := &Server{
+		KeyStore: keyStore,
+		Sway:     swayClient,
+		Logger:   createTestLogger(),
+	}
+	server.SetupRoutes(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/workspaces/events", nil)
+	addPairedCookie(t, keyStore, req)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
@@ -29,7 +29,7 @@ func pairFormInput() templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<form id=\"pair-form\" hx-post=\"/api/pair\" hx-swap=\"outerHTML\" hx-target=\"#pair-container\"><input type=\"text\" name=\"short-code\" id=\"short-code\"> <button type=\"submit\">Pair</button></form>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<form id=\"pair-form\" hx-post=\"/api/pair\" hx-swap=\"outerHTML\" hx-target=\"#pair-container\" hx-on::before-swap=\"if (event.detail.xhr.status === 429) { event.detail.shouldSwap = true; event.detail.isError = false; }\"><input type=\"text\" name=\"short-code\" id=\"short-code\" autocomplete=\"off\" autocapitalize=\"characters\"> <input type=\"text\" name=\"device-name\" id=\"device-name\" placeholder=\"Device name (optional)\" maxlength=\"64\"> <button type=\"submit\">Pair</button> <button type=\"button\" hx-post=\"/api/pair/request\" hx-include=\"#device-name\" hx-target=\"#pair-container\" hx-swap=\"outerHTML\" hx-on::before-swap=\"if (event.detail.xhr.status === 429) { event.detail.shouldSwap = true; event.detail.isError = false; }\">Ask the computer to approve</button></form>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -102,7 +102,7 @@ func PairFormWithError(errorMessage string) templ.Component {
 		var templ_7745c5c3_Var4 string
 		templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinStringErrs(errorMessage)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/components/pair_form.templ`, Line: 21, Col: 39}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/components/pair_form.templ`, Line: 29, Col: 39}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
 		if templ_7745c5c3_Err != nil {
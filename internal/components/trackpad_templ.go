@@ -0,0 +1,40 @@
+// Code generated by templ - DO NOT EDIT.
+
+// templ: version: v0.3.960
+package components
+
+//lint:file-ignore SA4006 This context is only used if a nested component is present.
+
+import "github.com/a-h/templ"
+import templruntime "github.com/a-h/templ/runtime"
+
+func Trackpad() templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var1 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var1 == nil {
+			templ_7745c5c3_Var1 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<style>\n        #trackpad-surface { height: 45vh; border: 1px solid #888; border-radius: 1rem; touch-action: none; }\n    </style><div id=\"trackpad\"><div id=\"trackpad-surface\"></div></div><script>\n        (function () {\n            const surface = document.getElementById(\"trackpad-surface\");\n            const scheme = location.protocol === \"https:\" ? \"wss:\" : \"ws:\";\n            const sensitivity = 1.5;\n            const pixelsPerScrollStep = 30;\n            const tapMillis = 200;\n            let socket;\n            let move = { x: 0, y: 0 };\n            let scroll = { x: 0, y: 0 };\n            let clicks = [];\n            let last = null;\n            let touchStart = 0;\n            let maxTouches = 0;\n            let moved = false;\n            let scheduled = false;\n\n            function connect() {\n                socket = new WebSocket(scheme + \"//\" + location.host + \"/api/input/ws\");\n                socket.binaryType = \"arraybuffer\";\n                socket.onclose = function () { setTimeout(connect, 1000); };\n            }\n\n            function clamp(value) {\n                return Math.max(-32768, Math.min(32767, Math.trunc(value)));\n            }\n\n            function pair(type, x, y) {\n                const view = new DataView(new ArrayBuffer(5));\n                view.setUint8(0, type);\n                view.setInt16(1, clamp(x), true);\n                view.setInt16(3, clamp(y), true);\n                return new Uint8Array(view.buffer);\n            }\n\n            function flush() {\n                scheduled = false;\n                const parts = [];\n                const dx = clamp(move.x), dy = clamp(move.y);\n                if (dx !== 0 || dy !== 0) {\n                    parts.push(pair(1, dx, dy));\n                    move.x -= dx;\n                    move.y -= dy;\n                }\n                const sx = Math.trunc(scroll.x / pixelsPerScrollStep), sy = Math.trunc(scroll.y / pixelsPerScrollStep);\n                if (sx !== 0 || sy !== 0) {\n                    parts.push(pair(4, sx, sy));\n                    scroll.x -= sx * pixelsPerScrollStep;\n                    scroll.y -= sy * pixelsPerScrollStep;\n                }\n                clicks.forEach(function (button) { parts.push(new Uint8Array([3, button])); });\n                clicks = [];\n                if (parts.length === 0 || !socket || socket.readyState !== WebSocket.OPEN) {\n                    return;\n                }\n                const message = new Uint8Array(parts.reduce(function (n, p) { return n + p.length; }, 0));\n                let offset = 0;\n                parts.forEach(function (p) { message.set(p, offset); offset += p.length; });\n                socket.send(message);\n            }\n\n            function schedule() {\n                if (!scheduled) {\n                    scheduled = true;\n                    requestAnimationFrame(flush);\n                }\n            }\n\n            function centre(touches) {\n                let x = 0, y = 0;\n                for (const touch of touches) {\n                    x += touch.clientX;\n                    y += touch.clientY;\n                }\n                return { x: x / touches.length, y: y / touches.length };\n            }\n\n            surface.addEventListener(\"touchstart\", function (e) {\n                e.preventDefault();\n                if (maxTouches === 0) {\n                    touchStart = Date.now();\n                    moved = false;\n                }\n                maxTouches = Math.max(maxTouches, e.touches.length);\n                last = centre(e.touches);\n            });\n\n            surface.addEventListener(\"touchmove\", function (e) {\n                e.preventDefault();\n                const current = centre(e.touches);\n                const dx = current.x - last.x, dy = current.y - last.y;\n                last = current;\n                if (Math.abs(dx) + Math.abs(dy) > 1) {\n                    moved = true;\n                }\n                if (e.touches.length >= 2) {\n                    scroll.x += dx;\n                    scroll.y -= dy;\n                } else {\n                    move.x += dx * sensitivity;\n                    move.y += dy * sensitivity;\n                }\n                schedule();\n            });\n\n            surface.addEventListener(\"touchend\", function (e) {\n                e.preventDefault();\n                if (e.touches.length > 0) {\n                    last = centre(e.touches);\n                    return;\n                }\n                if (!moved && Date.now() - touchStart < tapMillis) {\n                    clicks.push(maxTouches >= 2 ? 1 : 0);\n                    schedule();\n                }\n                maxTouches = 0;\n            });\n\n            connect();\n        })();\n    </script>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		return nil
+	})
+}
+
+var _ = templruntime.GeneratedTemplate
@@ -0,0 +1,195 @@
+// Package dbus is a minimal D-Bus client: enough to authenticate on the
+// session bus and make method calls with simple argument types.
+package dbus
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sessionBusEnvName = "DBUS_SESSION_BUS_ADDRESS"
+	busName           = "org.freedesktop.DBus"
+	busPath           = ObjectPath("/org/freedesktop/DBus")
+	defaultTimeout    = 5 * time.Second
+)
+
+// SessionBusAddress returns $DBUS_SESSION_BUS_ADDRESS, falling back to the
+// systemd user bus socket.
+func SessionBusAddress() string {
+	if address := os.Getenv(sessionBusEnvName); address != "" {
+		return address
+	}
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		return "unix:path=" + filepath.Join(runtimeDir, "bus")
+	}
+	return ""
+}
+
+// Error is an error reply from a peer.
+type Error struct {
+	Name    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return e.Name
+	}
+	return e.Name + ": " + e.Message
+}
+
+type Conn struct {
+	mu      sync.Mutex
+	conn    net.Conn
+	reader  *bufio.Reader
+	serial  uint32
+	name    string
+	timeout time.Duration
+}
+
+// Dial connects to the first reachable unix transport in address,
+// authenticates and registers with the bus.
+func Dial(address string) (*Conn, error) {
+	if address == "" {
+		return nil, errors.New("no d-bus address")
+	}
+	var errs []error
+	for _, entry := range strings.Split(address, ";") {
+		network, path, err := parseAddress(entry)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		raw, err := net.DialTimeout(network, path, defaultTimeout)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		c := &Conn{conn: raw, reader: bufio.NewReader(raw), timeout: defaultTimeout}
+		if err := c.authenticate(); err != nil {
+			raw.Close()
+			errs = append(errs, fmt.Errorf("d-bus authentication failed: %w", err))
+			continue
+		}
+		reply, err := c.Call(busName, busPath, busName, "Hello", "")
+		if err != nil {
+			raw.Close()
+			errs = append(errs, fmt.Errorf("d-bus hello failed: %w", err))
+			continue
+		}
+		if len(reply) == 1 {
+			c.name, _ = reply[0].(string)
+		}
+		return c, nil
+	}
+	return nil, fmt.Errorf("failed to connect to d-bus: %w", errors.Join(errs...))
+}
+
+func parseAddress(entry string) (network, path string, err error) {
+	transport, params, ok := strings.Cut(entry, ":")
+	if !ok || transport != "unix" {
+		return "", "", fmt.Errorf("unsupported d-bus address %q", entry)
+	}
+	for _, param := range strings.Split(params, ",") {
+		key, value, _ := strings.Cut(param, "=")
+		value, err := url.PathUnescape(value)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid d-bus address %q: %w", entry, err)
+		}
+		switch key {
+		case "path":
+			return "unix", value, nil
+		case "abstract":
+			return "unix", "@" + value, nil
+		}
+	}
+	return "", "", fmt.Errorf("d-bus address %q has no socket path", entry)
+}
+
+// authenticate runs the SASL EXTERNAL handshake, which proves our uid
+// through the socket credentials.
+func (c *Conn) authenticate() error {
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	defer c.conn.SetDeadline(time.Time{})
+
+	uid := hex.EncodeToString([]byte(strconv.Itoa(os.Getuid())))
+	if _, err := fmt.Fprintf(c.conn, "\x00AUTH EXTERNAL %s\r\n", uid); err != nil {
+		return err
+	}
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "OK ") {
+		return fmt.Errorf("unexpected reply %q", strings.TrimSpace(line))
+	}
+	_, err = fmt.Fprint(c.conn, "BEGIN\r\n")
+	return err
+}
+
+// Name is the unique bus name assigned to this connection.
+func (c *Conn) Name() string {
+	return c.name
+}
+
+// Call invokes a method and waits for its reply, returning the reply body.
+func (c *Conn) Call(destination string, path ObjectPath, iface, member string, signature Signature, args ...any) ([]any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.serial++
+	call := &Message{
+		Type:        TypeMethodCall,
+		Serial:      c.serial,
+		Path:        path,
+		Interface:   iface,
+		Member:      member,
+		Destination: destination,
+		Signature:   signature,
+		Body:        args,
+	}
+	data, err := call.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	defer c.conn.SetDeadline(time.Time{})
+	if _, err := c.conn.Write(data); err != nil {
+		return nil, err
+	}
+	for {
+		reply, err := ReadMessage(c.reader)
+		if err != nil {
+			return nil, err
+		}
+		if reply.ReplySerial != call.Serial {
+			continue
+		}
+		switch reply.Type {
+		case TypeMethodReturn:
+			return reply.Body, nil
+		case TypeError:
+			replyErr := &Error{Name: reply.ErrorName}
+			if len(reply.Body) > 0 {
+				replyErr.Message, _ = reply.Body[0].(string)
+			}
+			return nil, replyErr
+		}
+	}
+}
+
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,124 @@
+package dbus_test
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phasecurve/sway_rm/internal/dbus"
+	"github.com/phasecurve/sway_rm/internal/dbus/dbustest"
+)
+
+func TestDial_FakeBus_RegistersUniqueName(t *testing.T) {
+	bus := dbustest.NewBus(t)
+
+	conn, err := dbus.Dial(bus.Address())
+
+	assert.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, ":1.1", conn.Name())
+}
+
+func TestCall_ReturnsReplyBody(t *testing.T) {
+	bus := dbustest.NewBus(t)
+	bus.Handle("org.example.Echo", "Twice", func(call *dbus.Message) (dbus.Signature, []any, error) {
+		word := call.Body[0].(string)
+		return "su", []any{word + word, uint32(len(word) * 2)}, nil
+	})
+	conn, err := dbus.Dial(bus.Address())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	reply, err := conn.Call("org.example", "/org/example", "org.example.Echo", "Twice", "s", "hi")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"hihi", uint32(4)}, reply)
+	calls := bus.Calls()
+	if assert.Len(t, calls, 1) {
+		assert.Equal(t, "org.example", calls[0].Destination)
+		assert.Equal(t, dbus.ObjectPath("/org/example"), calls[0].Path)
+		assert.Equal(t, ":1.1", calls[0].Sender)
+	}
+}
+
+func TestCall_ErrorReply_ReturnsDBusError(t *testing.T) {
+	bus := dbustest.NewBus(t)
+	conn, err := dbus.Dial(bus.Address())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Call("org.example", "/org/example", "org.example.Missing", "Nope", "")
+
+	var dbusErr *dbus.Error
+	if assert.ErrorAs(t, err, &dbusErr) {
+		assert.Equal(t, "org.freedesktop.DBus.Error.UnknownMethod", dbusErr.Name)
+		assert.Contains(t, dbusErr.Message, "org.example.Missing.Nope")
+	}
+}
+
+// createRejectingSocket listens next to the bus and hangs up on every
+// connection, so authentication against it fails.
+func createRejectingSocket(t *testing.T, bus *dbustest.Bus) string {
+	path := filepath.Join(filepath.Dir(bus.SocketPath), "reject")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("failed to listen on %s: %v", path, err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	return "unix:path=" + path
+}
+
+func TestDial_Addresses(t *testing.T) {
+	bus := dbustest.NewBus(t)
+	rejecting := createRejectingSocket(t, bus)
+	tests := []struct {
+		name    string
+		address string
+		valid   bool
+	}{
+		{"path with guid", bus.Address() + ",guid=0123", true},
+		{"falls back to second entry", "unix:path=/nonexistent/bus;" + bus.Address(), true},
+		{"falls back past failed authentication", rejecting + ";" + bus.Address(), true},
+		{"authentication fails everywhere", rejecting, false},
+		{"empty", "", false},
+		{"tcp transport", "tcp:host=localhost,port=1234", false},
+		{"no path", "unix:guid=0123", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := dbus.Dial(tt.address)
+
+			if tt.valid {
+				assert.NoError(t, err)
+				conn.Close()
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestSessionBusAddress_PrefersEnvironment(t *testing.T) {
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", "unix:path=/tmp/custom-bus")
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+
+	assert.Equal(t, "unix:path=/tmp/custom-bus", dbus.SessionBusAddress())
+}
+
+func TestSessionBusAddress_FallsBackToRuntimeDir(t *testing.T) {
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", "")
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+
+	assert.Equal(t, "unix:path=/run/user/1000/bus", dbus.SessionBusAddress())
+}
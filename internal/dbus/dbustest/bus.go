@@ -0,0 +1,160 @@
+package dbustest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/phasecurve/sway_rm/internal/dbus"
+)
+
+// Handler answers a method call with a reply body, or a *dbus.Error.
+type Handler func(call *dbus.Message) (dbus.Signature, []any, error)
+
+// Bus is a stand-in session bus. It accepts any EXTERNAL credentials,
+// answers Hello itself and routes other calls to handlers keyed by
+// "interface.member".
+type Bus struct {
+	SocketPath string
+	listener   net.Listener
+	mu         sync.Mutex
+	handlers   map[string]Handler
+	calls      []*dbus.Message
+	clients    int
+}
+
+func NewBus(t testing.TB) *Bus {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "dbustest")
+	if err != nil {
+		t.Fatalf("failed to create socket dir: %v", err)
+	}
+	b := &Bus{
+		SocketPath: filepath.Join(dir, "bus"),
+		handlers:   map[string]Handler{},
+	}
+	listener, err := net.Listen("unix", b.SocketPath)
+	if err != nil {
+		t.Fatalf("failed to listen on fake bus socket: %v", err)
+	}
+	b.listener = listener
+	go b.serve()
+	t.Cleanup(func() {
+		listener.Close()
+		os.RemoveAll(dir)
+	})
+	return b
+}
+
+func (b *Bus) Address() string {
+	return "unix:path=" + b.SocketPath
+}
+
+func (b *Bus) Handle(iface, member string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[iface+"."+member] = handler
+}
+
+// Calls returns every method call received apart from Hello.
+func (b *Bus) Calls() []*dbus.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*dbus.Message(nil), b.calls...)
+}
+
+func (b *Bus) serve() {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+		b.mu.Lock()
+		b.clients++
+		name := fmt.Sprintf(":1.%d", b.clients)
+		b.mu.Unlock()
+		go b.handle(conn, name)
+	}
+}
+
+func (b *Bus) handle(conn net.Conn, name string) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	if !authenticate(conn, reader) {
+		return
+	}
+
+	var serial uint32
+	reply := func(m *dbus.Message) {
+		serial++
+		m.Serial = serial
+		m.Destination = name
+		data, err := m.Encode()
+		if err != nil {
+			panic(err)
+		}
+		conn.Write(data)
+	}
+
+	for {
+		call, err := dbus.ReadMessage(reader)
+		if err != nil {
+			return
+		}
+		if call.Type != dbus.TypeMethodCall {
+			continue
+		}
+		if call.Interface == "org.freedesktop.DBus" && call.Member == "Hello" {
+			reply(&dbus.Message{Type: dbus.TypeMethodReturn, ReplySerial: call.Serial, Signature: "s", Body: []any{name}})
+			reply(&dbus.Message{Type: dbus.TypeSignal, Path: "/org/freedesktop/DBus", Interface: "org.freedesktop.DBus", Member: "NameAcquired", Signature: "s", Body: []any{name}})
+			continue
+		}
+
+		b.mu.Lock()
+		call.Sender = name
+		b.calls = append(b.calls, call)
+		handler := b.handlers[call.Interface+"."+call.Member]
+		b.mu.Unlock()
+
+		if handler == nil {
+			handler = unknownMethod
+		}
+		signature, body, err := handler(call)
+		if err != nil {
+			dbusErr, ok := err.(*dbus.Error)
+			if !ok {
+				dbusErr = &dbus.Error{Name: "org.freedesktop.DBus.Error.Failed", Message: err.Error()}
+			}
+			reply(&dbus.Message{Type: dbus.TypeError, ReplySerial: call.Serial, ErrorName: dbusErr.Name, Signature: "s", Body: []any{dbusErr.Message}})
+			continue
+		}
+		if call.Flags&dbus.FlagNoReplyExpected == 0 {
+			reply(&dbus.Message{Type: dbus.TypeMethodReturn, ReplySerial: call.Serial, Signature: signature, Body: body})
+		}
+	}
+}
+
+func unknownMethod(call *dbus.Message) (dbus.Signature, []any, error) {
+	return "", nil, &dbus.Error{
+		Name:    "org.freedesktop.DBus.Error.UnknownMethod",
+		Message: fmt.Sprintf("no handler for %s.%s", call.Interface, call.Member),
+	}
+}
+
+func authenticate(conn net.Conn, reader *bufio.Reader) bool {
+	if nul, err := reader.ReadByte(); err != nil || nul != 0 {
+		return false
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "AUTH EXTERNAL ") {
+		return false
+	}
+	fmt.Fprint(conn, "OK 0123456789abcdef0123456789abcdef\r\n")
+	line, err = reader.ReadString('\n')
+	return err == nil && strings.TrimSpace(line) == "BEGIN"
+}
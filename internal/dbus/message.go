@@ -0,0 +1,501 @@
+package dbus
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+)
+
+type MessageType byte
+
+const (
+	TypeMethodCall   MessageType = 1
+	TypeMethodReturn MessageType = 2
+	TypeError        MessageType = 3
+	TypeSignal       MessageType = 4
+)
+
+const (
+	protocolVersion = 1
+	littleEndian    = 'l'
+	maxMessageSize  = 1 << 27
+)
+
+const (
+	fieldPath        = 1
+	fieldInterface   = 2
+	fieldMember      = 3
+	fieldErrorName   = 4
+	fieldReplySerial = 5
+	fieldDestination = 6
+	fieldSender      = 7
+	fieldSignature   = 8
+)
+
+// FlagNoReplyExpected tells the peer not to send a method return.
+const FlagNoReplyExpected = 0x1
+
+type ObjectPath string
+
+type Signature string
+
+// Variant is a value tagged with its own signature, e.g. a hint value in
+// a{sv}.
+type Variant struct {
+	Signature Signature
+	Value     any
+}
+
+type Message struct {
+	Type        MessageType
+	Flags       byte
+	Serial      uint32
+	Path        ObjectPath
+	Interface   string
+	Member      string
+	ErrorName   string
+	ReplySerial uint32
+	Destination string
+	Sender      string
+	Signature   Signature
+	Body        []any
+}
+
+// Encode marshals the message in little-endian wire format. Body values
+// must match Signature: byte, bool, int32, uint32, string, ObjectPath,
+// Signature, Variant, []string and map[string]Variant are supported.
+func (m *Message) Encode() ([]byte, error) {
+	var body encoder
+	if err := body.encodeAll(string(m.Signature), m.Body); err != nil {
+		return nil, err
+	}
+
+	var fields []headerField
+	add := func(code byte, signature Signature, value any, set bool) {
+		if set {
+			fields = append(fields, headerField{code, Variant{signature, value}})
+		}
+	}
+	add(fieldPath, "o", m.Path, m.Path != "")
+	add(fieldInterface, "s", m.Interface, m.Interface != "")
+	add(fieldMember, "s", m.Member, m.Member != "")
+	add(fieldErrorName, "s", m.ErrorName, m.ErrorName != "")
+	add(fieldReplySerial, "u", m.ReplySerial, m.ReplySerial != 0)
+	add(fieldDestination, "s", m.Destination, m.Destination != "")
+	add(fieldSender, "s", m.Sender, m.Sender != "")
+	add(fieldSignature, "g", m.Signature, m.Signature != "")
+
+	var header encoder
+	header.buf = append(header.buf, littleEndian, byte(m.Type), m.Flags, protocolVersion)
+	header.uint32(uint32(len(body.buf)))
+	header.uint32(m.Serial)
+	lengthAt := header.arrayStart(8)
+	start := len(header.buf)
+	for _, field := range fields {
+		header.align(8)
+		header.buf = append(header.buf, field.code)
+		if err := header.encode("v", field.value); err != nil {
+			return nil, err
+		}
+	}
+	header.arrayEnd(lengthAt, start)
+	header.align(8)
+
+	return append(header.buf, body.buf...), nil
+}
+
+type headerField struct {
+	code  byte
+	value Variant
+}
+
+// ReadMessage reads one message from r.
+func ReadMessage(r io.Reader) (*Message, error) {
+	fixed := make([]byte, 16)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, err
+	}
+	if fixed[0] != littleEndian {
+		return nil, fmt.Errorf("unsupported byte order %q", fixed[0])
+	}
+	if fixed[3] != protocolVersion {
+		return nil, fmt.Errorf("unsupported protocol version %d", fixed[3])
+	}
+	bodyLength := binary.LittleEndian.Uint32(fixed[4:])
+	fieldsLength := binary.LittleEndian.Uint32(fixed[12:])
+	headerLength := 16 + int(fieldsLength)
+	padded := (headerLength + 7) &^ 7
+	if uint64(padded)+uint64(bodyLength) > maxMessageSize {
+		return nil, errors.New("message too large")
+	}
+
+	raw := make([]byte, padded+int(bodyLength))
+	copy(raw, fixed)
+	if _, err := io.ReadFull(r, raw[16:]); err != nil {
+		return nil, err
+	}
+
+	m := &Message{
+		Type:   MessageType(fixed[1]),
+		Flags:  fixed[2],
+		Serial: binary.LittleEndian.Uint32(fixed[8:]),
+	}
+	header := decoder{buf: raw[:headerLength], pos: 16}
+	for header.pos < headerLength {
+		header.align(8)
+		code, err := header.byte()
+		if err != nil {
+			return nil, err
+		}
+		value, err := header.decode("v")
+		if err != nil {
+			return nil, err
+		}
+		v := value.(Variant).Value
+		switch code {
+		case fieldPath:
+			m.Path, _ = v.(ObjectPath)
+		case fieldInterface:
+			m.Interface, _ = v.(string)
+		case fieldMember:
+			m.Member, _ = v.(string)
+		case fieldErrorName:
+			m.ErrorName, _ = v.(string)
+		case fieldReplySerial:
+			m.ReplySerial, _ = v.(uint32)
+		case fieldDestination:
+			m.Destination, _ = v.(string)
+		case fieldSender:
+			m.Sender, _ = v.(string)
+		case fieldSignature:
+			m.Signature, _ = v.(Signature)
+		}
+	}
+
+	body := decoder{buf: raw[padded:]}
+	for sig := string(m.Signature); sig != ""; {
+		single, rest, err := nextType(sig)
+		if err != nil {
+			return nil, err
+		}
+		value, err := body.decode(single)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode body: %w", err)
+		}
+		m.Body = append(m.Body, value)
+		sig = rest
+	}
+	return m, nil
+}
+
+// nextType splits the first complete type off a signature.
+func nextType(sig string) (string, string, error) {
+	if sig == "" {
+		return "", "", errors.New("empty signature")
+	}
+	switch sig[0] {
+	case 'a':
+		elem, rest, err := nextType(sig[1:])
+		if err != nil {
+			return "", "", err
+		}
+		return "a" + elem, rest, nil
+	case '{', '(':
+		closing := byte('}')
+		if sig[0] == '(' {
+			closing = ')'
+		}
+		inner := sig[1:]
+		n := 1
+		for inner != "" && inner[0] != closing {
+			_, rest, err := nextType(inner)
+			if err != nil {
+				return "", "", err
+			}
+			n += len(inner) - len(rest)
+			inner = rest
+		}
+		if inner == "" {
+			return "", "", fmt.Errorf("unterminated signature %q", sig)
+		}
+		return sig[:n+1], sig[n+1:], nil
+	}
+	return sig[:1], sig[1:], nil
+}
+
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) align(n int) {
+	for len(e.buf)%n != 0 {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+func (e *encoder) uint32(v uint32) {
+	e.align(4)
+	e.buf = binary.LittleEndian.AppendUint32(e.buf, v)
+}
+
+func (e *encoder) string(s string) {
+	e.uint32(uint32(len(s)))
+	e.buf = append(e.buf, s...)
+	e.buf = append(e.buf, 0)
+}
+
+func (e *encoder) arrayStart(elementAlignment int) int {
+	e.uint32(0)
+	at := len(e.buf) - 4
+	e.align(elementAlignment)
+	return at
+}
+
+func (e *encoder) arrayEnd(lengthAt, start int) {
+	binary.LittleEndian.PutUint32(e.buf[lengthAt:], uint32(len(e.buf)-start))
+}
+
+func (e *encoder) encodeAll(sig string, values []any) error {
+	for _, value := range values {
+		if sig == "" {
+			return errors.New("more values than signature types")
+		}
+		single, rest, err := nextType(sig)
+		if err != nil {
+			return err
+		}
+		if err := e.encode(single, value); err != nil {
+			return err
+		}
+		sig = rest
+	}
+	if sig != "" {
+		return fmt.Errorf("missing values for signature %q", sig)
+	}
+	return nil
+}
+
+func (e *encoder) encode(sig string, value any) error {
+	mismatch := func() error { return fmt.Errorf("cannot encode %T as %q", value, sig) }
+	switch sig {
+	case "y":
+		v, ok := value.(byte)
+		if !ok {
+			return mismatch()
+		}
+		e.buf = append(e.buf, v)
+	case "b":
+		v, ok := value.(bool)
+		if !ok {
+			return mismatch()
+		}
+		var n uint32
+		if v {
+			n = 1
+		}
+		e.uint32(n)
+	case "i":
+		v, ok := value.(int32)
+		if !ok {
+			return mismatch()
+		}
+		e.uint32(uint32(v))
+	case "u":
+		v, ok := value.(uint32)
+		if !ok {
+			return mismatch()
+		}
+		e.uint32(v)
+	case "s":
+		v, ok := value.(string)
+		if !ok {
+			return mismatch()
+		}
+		e.string(v)
+	case "o":
+		v, ok := value.(ObjectPath)
+		if !ok {
+			return mismatch()
+		}
+		e.string(string(v))
+	case "g":
+		v, ok := value.(Signature)
+		if !ok {
+			return mismatch()
+		}
+		e.buf = append(e.buf, byte(len(v)))
+		e.buf = append(e.buf, v...)
+		e.buf = append(e.buf, 0)
+	case "v":
+		v, ok := value.(Variant)
+		if !ok {
+			return mismatch()
+		}
+		if _, rest, err := nextType(string(v.Signature)); err != nil || rest != "" {
+			return fmt.Errorf("variant signature %q must be a single type", v.Signature)
+		}
+		e.encode("g", v.Signature)
+		return e.encode(string(v.Signature), v.Value)
+	case "as":
+		v, ok := value.([]string)
+		if !ok {
+			return mismatch()
+		}
+		lengthAt := e.arrayStart(4)
+		start := len(e.buf)
+		for _, s := range v {
+			e.string(s)
+		}
+		e.arrayEnd(lengthAt, start)
+	case "a{sv}":
+		v, ok := value.(map[string]Variant)
+		if !ok {
+			return mismatch()
+		}
+		lengthAt := e.arrayStart(8)
+		start := len(e.buf)
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			e.align(8)
+			e.string(key)
+			if err := e.encode("v", v[key]); err != nil {
+				return err
+			}
+		}
+		e.arrayEnd(lengthAt, start)
+	default:
+		return fmt.Errorf("unsupported signature %q", sig)
+	}
+	return nil
+}
+
+type decoder struct {
+	buf []byte
+	pos int
+}
+
+var errShortBuffer = errors.New("message truncated")
+
+func (d *decoder) align(n int) {
+	d.pos = (d.pos + n - 1) / n * n
+}
+
+func (d *decoder) byte() (byte, error) {
+	if d.pos >= len(d.buf) {
+		return 0, errShortBuffer
+	}
+	b := d.buf[d.pos]
+	d.pos++
+	return b, nil
+}
+
+func (d *decoder) uint32() (uint32, error) {
+	d.align(4)
+	if d.pos+4 > len(d.buf) {
+		return 0, errShortBuffer
+	}
+	v := binary.LittleEndian.Uint32(d.buf[d.pos:])
+	d.pos += 4
+	return v, nil
+}
+
+func (d *decoder) bytes(n int) ([]byte, error) {
+	if n < 0 || d.pos+n+1 > len(d.buf) {
+		return nil, errShortBuffer
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n + 1
+	return b, nil
+}
+
+func (d *decoder) string() (string, error) {
+	n, err := d.uint32()
+	if err != nil {
+		return "", err
+	}
+	b, err := d.bytes(int(n))
+	return string(b), err
+}
+
+func (d *decoder) decode(sig string) (any, error) {
+	switch sig {
+	case "y":
+		return d.byte()
+	case "b":
+		v, err := d.uint32()
+		return v != 0, err
+	case "i":
+		v, err := d.uint32()
+		return int32(v), err
+	case "u":
+		return d.uint32()
+	case "s":
+		return d.string()
+	case "o":
+		v, err := d.string()
+		return ObjectPath(v), err
+	case "g":
+		n, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.bytes(int(n))
+		return Signature(b), err
+	case "v":
+		sig, err := d.decode("g")
+		if err != nil {
+			return nil, err
+		}
+		inner := string(sig.(Signature))
+		if _, rest, err := nextType(inner); err != nil || rest != "" {
+			return nil, fmt.Errorf("invalid variant signature %q", inner)
+		}
+		value, err := d.decode(inner)
+		return Variant{Signature(inner), value}, err
+	case "as":
+		var out []string
+		err := d.array(4, func() error {
+			s, err := d.string()
+			out = append(out, s)
+			return err
+		})
+		return out, err
+	case "a{sv}":
+		out := map[string]Variant{}
+		err := d.array(8, func() error {
+			d.align(8)
+			key, err := d.string()
+			if err != nil {
+				return err
+			}
+			value, err := d.decode("v")
+			if err != nil {
+				return err
+			}
+			out[key] = value.(Variant)
+			return nil
+		})
+		return out, err
+	}
+	return nil, fmt.Errorf("unsupported signature %q", sig)
+}
+
+func (d *decoder) array(elementAlignment int, element func() error) error {
+	n, err := d.uint32()
+	if err != nil {
+		return err
+	}
+	d.align(elementAlignment)
+	end := d.pos + int(n)
+	if end > len(d.buf) {
+		return errShortBuffer
+	}
+	for d.pos < end {
+		if err := element(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
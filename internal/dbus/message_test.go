@@ -0,0 +1,121 @@
+package dbus
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncode_Hello_MatchesReferenceBytes(t *testing.T) {
+	hello := &Message{
+		Type:        TypeMethodCall,
+		Serial:      1,
+		Path:        "/org/freedesktop/DBus",
+		Interface:   "org.freedesktop.DBus",
+		Member:      "Hello",
+		Destination: "org.freedesktop.DBus",
+	}
+
+	data, err := hello.Encode()
+
+	expected := "l\x01\x00\x01\x00\x00\x00\x00\x01\x00\x00\x00m\x00\x00\x00" +
+		"\x01\x01o\x00\x15\x00\x00\x00/org/freedesktop/DBus\x00\x00\x00" +
+		"\x02\x01s\x00\x14\x00\x00\x00org.freedesktop.DBus\x00\x00\x00\x00" +
+		"\x03\x01s\x00\x05\x00\x00\x00Hello\x00\x00\x00" +
+		"\x06\x01s\x00\x14\x00\x00\x00org.freedesktop.DBus\x00\x00\x00\x00"
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(expected), data)
+}
+
+func TestMessage_RoundTripsBodyTypes(t *testing.T) {
+	original := &Message{
+		Type:        TypeMethodCall,
+		Serial:      7,
+		Path:        "/org/freedesktop/Notifications",
+		Interface:   "org.freedesktop.Notifications",
+		Member:      "Notify",
+		Destination: "org.freedesktop.Notifications",
+		Signature:   "susssasa{sv}i",
+		Body: []any{
+			"Sway RM",
+			uint32(3),
+			"",
+			"Pairing code: ABC234",
+			"Expires in 5 minutes",
+			[]string{"default", "Open"},
+			map[string]Variant{
+				"urgency":   {"y", byte(1)},
+				"transient": {"b", true},
+				"category":  {"s", "device"},
+			},
+			int32(-1),
+		},
+	}
+	data, err := original.Encode()
+	assert.NoError(t, err)
+
+	decoded, err := ReadMessage(bytes.NewReader(data))
+
+	assert.NoError(t, err)
+	assert.Equal(t, original, decoded)
+}
+
+func TestEncode_BodyMismatch_ReturnsError(t *testing.T) {
+	tests := []struct {
+		name      string
+		signature Signature
+		body      []any
+		message   string
+	}{
+		{"wrong type", "u", []any{"three"}, `cannot encode string as "u"`},
+		{"too many values", "s", []any{"a", "b"}, "more values than signature types"},
+		{"too few values", "ss", []any{"a"}, `missing values for signature "s"`},
+		{"unsupported type", "x", []any{int64(1)}, `unsupported signature "x"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&Message{Type: TypeMethodCall, Signature: tt.signature, Body: tt.body}).Encode()
+
+			assert.ErrorContains(t, err, tt.message)
+		})
+	}
+}
+
+func TestReadMessage_Truncated_ReturnsError(t *testing.T) {
+	data, _ := (&Message{Type: TypeMethodReturn, Serial: 2, ReplySerial: 1, Signature: "s", Body: []any{"hello"}}).Encode()
+
+	_, err := ReadMessage(bytes.NewReader(data[:len(data)-3]))
+
+	assert.Error(t, err)
+}
+
+func TestReadMessage_BigEndian_ReturnsError(t *testing.T) {
+	data, _ := (&Message{Type: TypeSignal, Serial: 1}).Encode()
+	data[0] = 'B'
+
+	_, err := ReadMessage(bytes.NewReader(data))
+
+	assert.ErrorContains(t, err, "unsupported byte order")
+}
+
+func TestNextType_SplitsContainerTypes(t *testing.T) {
+	tests := []struct {
+		signature string
+		first     string
+		rest      string
+	}{
+		{"su", "s", "u"},
+		{"asi", "as", "i"},
+		{"a{sv}i", "a{sv}", "i"},
+		{"a(ii)s", "a(ii)", "s"},
+		{"aa{sa{sv}}", "aa{sa{sv}}", ""},
+	}
+	for _, tt := range tests {
+		first, rest, err := nextType(tt.signature)
+
+		assert.NoError(t, err)
+		assert.Equal(t, tt.first, first, tt.signature)
+		assert.Equal(t, tt.rest, rest, tt.signature)
+	}
+}
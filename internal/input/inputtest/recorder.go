@@ -0,0 +1,65 @@
+package inputtest
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/phasecurve/sway_rm/internal/input"
+)
+
+type Recorder struct {
+	mu     sync.Mutex
+	events []string
+	closed bool
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) MovePointer(dx, dy int) error {
+	return r.record("move %d %d", dx, dy)
+}
+
+func (r *Recorder) PressButton(button input.Button, pressed bool) error {
+	state := "up"
+	if pressed {
+		state = "down"
+	}
+	return r.record("button %d %s", button, state)
+}
+
+func (r *Recorder) Scroll(vertical, horizontal int) error {
+	return r.record("scroll %d %d", vertical, horizontal)
+}
+
+func (r *Recorder) PressKey(code input.KeyCode, pressed bool) error {
+	state := "up"
+	if pressed {
+		state = "down"
+	}
+	return r.record("key %d %s", code, state)
+}
+
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	return nil
+}
+
+func (r *Recorder) Events() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+func (r *Recorder) record(format string, args ...any) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return fmt.Errorf("recorder closed")
+	}
+	r.events = append(r.events, fmt.Sprintf(format, args...))
+	return nil
+}
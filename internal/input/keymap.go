@@ -0,0 +1,281 @@
+package input
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type KeyCode uint16
+
+const (
+	KeyEsc          KeyCode = 1
+	KeyMinus        KeyCode = 12
+	KeyEqual        KeyCode = 13
+	KeyBackspace    KeyCode = 14
+	KeyTab          KeyCode = 15
+	KeyLeftBrace    KeyCode = 26
+	KeyRightBrace   KeyCode = 27
+	KeyEnter        KeyCode = 28
+	KeyLeftCtrl     KeyCode = 29
+	KeySemicolon    KeyCode = 39
+	KeyApostrophe   KeyCode = 40
+	KeyGrave        KeyCode = 41
+	KeyLeftShift    KeyCode = 42
+	KeyBackslash    KeyCode = 43
+	KeyComma        KeyCode = 51
+	KeyDot          KeyCode = 52
+	KeySlash        KeyCode = 53
+	KeyLeftAlt      KeyCode = 56
+	KeySpace        KeyCode = 57
+	KeyCapsLock     KeyCode = 58
+	KeySysRq        KeyCode = 99
+	KeyRightAlt     KeyCode = 100
+	KeyHome         KeyCode = 102
+	KeyUp           KeyCode = 103
+	KeyPageUp       KeyCode = 104
+	KeyLeft         KeyCode = 105
+	KeyRight        KeyCode = 106
+	KeyEnd          KeyCode = 107
+	KeyDown         KeyCode = 108
+	KeyPageDown     KeyCode = 109
+	KeyInsert       KeyCode = 110
+	KeyDelete       KeyCode = 111
+	KeyMute         KeyCode = 113
+	KeyVolumeDown   KeyCode = 114
+	KeyVolumeUp     KeyCode = 115
+	KeyLeftMeta     KeyCode = 125
+	KeyNextSong     KeyCode = 163
+	KeyPlayPause    KeyCode = 164
+	KeyPreviousSong KeyCode = 165
+)
+
+var letterCodes = map[rune]KeyCode{
+	'q': 16, 'w': 17, 'e': 18, 'r': 19, 't': 20, 'y': 21, 'u': 22, 'i': 23, 'o': 24, 'p': 25,
+	'a': 30, 's': 31, 'd': 32, 'f': 33, 'g': 34, 'h': 35, 'j': 36, 'k': 37, 'l': 38,
+	'z': 44, 'x': 45, 'c': 46, 'v': 47, 'b': 48, 'n': 49, 'm': 50,
+}
+
+var digitCodes = map[rune]KeyCode{
+	'1': 2, '2': 3, '3': 4, '4': 5, '5': 6, '6': 7, '7': 8, '8': 9, '9': 10, '0': 11,
+}
+
+var functionKeyCodes = []KeyCode{59, 60, 61, 62, 63, 64, 65, 66, 67, 68, 87, 88}
+
+var modifierNames = map[string]KeyCode{
+	"super":   KeyLeftMeta,
+	"mod4":    KeyLeftMeta,
+	"meta":    KeyLeftMeta,
+	"shift":   KeyLeftShift,
+	"ctrl":    KeyLeftCtrl,
+	"control": KeyLeftCtrl,
+	"alt":     KeyLeftAlt,
+	"mod1":    KeyLeftAlt,
+	"altgr":   KeyRightAlt,
+}
+
+var namedKeys = map[string]KeyCode{
+	"escape":       KeyEsc,
+	"esc":          KeyEsc,
+	"minus":        KeyMinus,
+	"equal":        KeyEqual,
+	"backspace":    KeyBackspace,
+	"tab":          KeyTab,
+	"bracketleft":  KeyLeftBrace,
+	"bracketright": KeyRightBrace,
+	"enter":        KeyEnter,
+	"return":       KeyEnter,
+	"semicolon":    KeySemicolon,
+	"apostrophe":   KeyApostrophe,
+	"grave":        KeyGrave,
+	"backslash":    KeyBackslash,
+	"comma":        KeyComma,
+	"period":       KeyDot,
+	"slash":        KeySlash,
+	"space":        KeySpace,
+	"capslock":     KeyCapsLock,
+	"print":        KeySysRq,
+	"home":         KeyHome,
+	"up":           KeyUp,
+	"pageup":       KeyPageUp,
+	"prior":        KeyPageUp,
+	"left":         KeyLeft,
+	"right":        KeyRight,
+	"end":          KeyEnd,
+	"down":         KeyDown,
+	"pagedown":     KeyPageDown,
+	"next":         KeyPageDown,
+	"insert":       KeyInsert,
+	"delete":       KeyDelete,
+	"mute":         KeyMute,
+	"volumedown":   KeyVolumeDown,
+	"volumeup":     KeyVolumeUp,
+	"playpause":    KeyPlayPause,
+	"nextsong":     KeyNextSong,
+	"prevsong":     KeyPreviousSong,
+}
+
+var symbolCodes = map[rune]KeyStroke{
+	' ': {Code: KeySpace}, '\n': {Code: KeyEnter}, '\t': {Code: KeyTab},
+	'-': {Code: KeyMinus}, '_': {Code: KeyMinus, Shift: true},
+	'=': {Code: KeyEqual}, '+': {Code: KeyEqual, Shift: true},
+	'[': {Code: KeyLeftBrace}, '{': {Code: KeyLeftBrace, Shift: true},
+	']': {Code: KeyRightBrace}, '}': {Code: KeyRightBrace, Shift: true},
+	';': {Code: KeySemicolon}, ':': {Code: KeySemicolon, Shift: true},
+	'\'': {Code: KeyApostrophe}, '"': {Code: KeyApostrophe, Shift: true},
+	'`': {Code: KeyGrave}, '~': {Code: KeyGrave, Shift: true},
+	'\\': {Code: KeyBackslash}, '|': {Code: KeyBackslash, Shift: true},
+	',': {Code: KeyComma}, '<': {Code: KeyComma, Shift: true},
+	'.': {Code: KeyDot}, '>': {Code: KeyDot, Shift: true},
+	'/': {Code: KeySlash}, '?': {Code: KeySlash, Shift: true},
+	'!': {Code: 2, Shift: true}, '@': {Code: 3, Shift: true}, '#': {Code: 4, Shift: true},
+	'$': {Code: 5, Shift: true}, '%': {Code: 6, Shift: true}, '^': {Code: 7, Shift: true},
+	'&': {Code: 8, Shift: true}, '*': {Code: 9, Shift: true}, '(': {Code: 10, Shift: true},
+	')': {Code: 11, Shift: true},
+}
+
+type KeyStroke struct {
+	Code  KeyCode
+	Shift bool
+}
+
+type KeyCombo struct {
+	Modifiers []KeyCode
+	Key       KeyCode
+}
+
+func KeyCodeForName(name string) (KeyCode, bool) {
+	name = strings.ToLower(name)
+	if code, ok := modifierNames[name]; ok {
+		return code, true
+	}
+	if code, ok := namedKeys[name]; ok {
+		return code, true
+	}
+	runes := []rune(name)
+	if len(runes) == 1 {
+		if code, ok := letterCodes[runes[0]]; ok {
+			return code, true
+		}
+		if code, ok := digitCodes[runes[0]]; ok {
+			return code, true
+		}
+	}
+	if number, err := strconv.Atoi(strings.TrimPrefix(name, "f")); err == nil && strings.HasPrefix(name, "f") {
+		if number >= 1 && number <= len(functionKeyCodes) {
+			return functionKeyCodes[number-1], true
+		}
+	}
+	return 0, false
+}
+
+func ParseCombo(combo string) (KeyCombo, error) {
+	parts := strings.Split(combo, "+")
+	var parsed KeyCombo
+	for i, part := range parts {
+		name := strings.TrimSpace(part)
+		if name == "" {
+			return KeyCombo{}, fmt.Errorf("empty key in combo %q", combo)
+		}
+		if i == len(parts)-1 {
+			code, ok := KeyCodeForName(name)
+			if !ok {
+				return KeyCombo{}, fmt.Errorf("unknown key %q in combo %q", name, combo)
+			}
+			parsed.Key = code
+			continue
+		}
+		modifier, ok := modifierNames[strings.ToLower(name)]
+		if !ok {
+			return KeyCombo{}, fmt.Errorf("unknown modifier %q in combo %q", name, combo)
+		}
+		parsed.Modifiers = append(parsed.Modifiers, modifier)
+	}
+	return parsed, nil
+}
+
+func TextToStrokes(text string) ([]KeyStroke, error) {
+	strokes := make([]KeyStroke, 0, len(text))
+	for _, r := range text {
+		stroke, ok := strokeForRune(r)
+		if !ok {
+			return nil, fmt.Errorf("cannot type character %q", r)
+		}
+		strokes = append(strokes, stroke)
+	}
+	return strokes, nil
+}
+
+func strokeForRune(r rune) (KeyStroke, bool) {
+	if code, ok := letterCodes[r]; ok {
+		return KeyStroke{Code: code}, true
+	}
+	if r >= 'A' && r <= 'Z' {
+		return KeyStroke{Code: letterCodes[unicode.ToLower(r)], Shift: true}, true
+	}
+	if code, ok := digitCodes[r]; ok {
+		return KeyStroke{Code: code}, true
+	}
+	stroke, ok := symbolCodes[r]
+	return stroke, ok
+}
+
+func KeymapCodes() []KeyCode {
+	seen := make(map[KeyCode]bool)
+	for _, table := range []map[string]KeyCode{modifierNames, namedKeys} {
+		for _, code := range table {
+			seen[code] = true
+		}
+	}
+	for _, table := range []map[rune]KeyCode{letterCodes, digitCodes} {
+		for _, code := range table {
+			seen[code] = true
+		}
+	}
+	for _, code := range functionKeyCodes {
+		seen[code] = true
+	}
+	codes := make([]KeyCode, 0, len(seen))
+	for code := range seen {
+		codes = append(codes, code)
+	}
+	return codes
+}
+
+func TypeText(injector InputInjector, text string) error {
+	strokes, err := TextToStrokes(text)
+	if err != nil {
+		return err
+	}
+	for _, stroke := range strokes {
+		combo := KeyCombo{Key: stroke.Code}
+		if stroke.Shift {
+			combo.Modifiers = []KeyCode{KeyLeftShift}
+		}
+		if err := SendCombo(injector, combo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func SendCombo(injector InputInjector, combo KeyCombo) error {
+	for _, modifier := range combo.Modifiers {
+		if err := injector.PressKey(modifier, true); err != nil {
+			return err
+		}
+	}
+	if err := injector.PressKey(combo.Key, true); err != nil {
+		return err
+	}
+	if err := injector.PressKey(combo.Key, false); err != nil {
+		return err
+	}
+	for i := len(combo.Modifiers) - 1; i >= 0; i-- {
+		if err := injector.PressKey(combo.Modifiers[i], false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
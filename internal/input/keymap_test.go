@@ -0,0 +1,147 @@
+package input_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phasecurve/sway_rm/internal/input"
+	"github.com/phasecurve/sway_rm/internal/input/inputtest"
+)
+
+func TestKeyCodeForName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected input.KeyCode
+	}{
+		{"a", 30},
+		{"Q", 16},
+		{"0", 11},
+		{"1", 2},
+		{"F1", 59},
+		{"f10", 68},
+		{"F12", 88},
+		{"Enter", input.KeyEnter},
+		{"Return", input.KeyEnter},
+		{"esc", input.KeyEsc},
+		{"Super", input.KeyLeftMeta},
+		{"Mod4", input.KeyLeftMeta},
+		{"ctrl", input.KeyLeftCtrl},
+		{"PageDown", input.KeyPageDown},
+		{"space", input.KeySpace},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, ok := input.KeyCodeForName(tt.name)
+
+			assert.True(t, ok)
+			assert.Equal(t, tt.expected, code)
+		})
+	}
+}
+
+func TestKeyCodeForName_Unknown(t *testing.T) {
+	for _, name := range []string{"", "F13", "F0", "fx", "hyper", "ä", "ab"} {
+		t.Run(name, func(t *testing.T) {
+			_, ok := input.KeyCodeForName(name)
+
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestParseCombo(t *testing.T) {
+	tests := []struct {
+		combo    string
+		expected input.KeyCombo
+	}{
+		{"Super+Shift+q", input.KeyCombo{Modifiers: []input.KeyCode{input.KeyLeftMeta, input.KeyLeftShift}, Key: 16}},
+		{"ctrl+l", input.KeyCombo{Modifiers: []input.KeyCode{input.KeyLeftCtrl}, Key: 38}},
+		{"Alt + Tab", input.KeyCombo{Modifiers: []input.KeyCode{input.KeyLeftAlt}, Key: input.KeyTab}},
+		{"Enter", input.KeyCombo{Key: input.KeyEnter}},
+		{"Super", input.KeyCombo{Key: input.KeyLeftMeta}},
+		{"Mod4+2", input.KeyCombo{Modifiers: []input.KeyCode{input.KeyLeftMeta}, Key: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.combo, func(t *testing.T) {
+			combo, err := input.ParseCombo(tt.combo)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, combo)
+		})
+	}
+}
+
+func TestParseCombo_Invalid(t *testing.T) {
+	tests := []struct {
+		combo   string
+		message string
+	}{
+		{"", "empty key"},
+		{"Ctrl+", "empty key"},
+		{"q+Ctrl", "unknown modifier"},
+		{"Ctrl+Nope", "unknown key"},
+		{"Hyper+q", "unknown modifier"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.combo, func(t *testing.T) {
+			_, err := input.ParseCombo(tt.combo)
+
+			assert.ErrorContains(t, err, tt.message)
+		})
+	}
+}
+
+func TestTextToStrokes(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []input.KeyStroke
+	}{
+		{"a", []input.KeyStroke{{Code: 30}}},
+		{"A", []input.KeyStroke{{Code: 30, Shift: true}}},
+		{"7", []input.KeyStroke{{Code: 8}}},
+		{"&", []input.KeyStroke{{Code: 8, Shift: true}}},
+		{" ", []input.KeyStroke{{Code: input.KeySpace}}},
+		{"\n", []input.KeyStroke{{Code: input.KeyEnter}}},
+		{":/", []input.KeyStroke{{Code: input.KeySemicolon, Shift: true}, {Code: input.KeySlash}}},
+		{"\"?", []input.KeyStroke{{Code: input.KeyApostrophe, Shift: true}, {Code: input.KeySlash, Shift: true}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			strokes, err := input.TextToStrokes(tt.text)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, strokes)
+		})
+	}
+}
+
+func TestTextToStrokes_UnsupportedCharacter_ReturnsError(t *testing.T) {
+	_, err := input.TextToStrokes("café")
+
+	assert.ErrorContains(t, err, "cannot type character 'é'")
+}
+
+func TestTypeText_WrapsShiftedCharacters(t *testing.T) {
+	recorder := inputtest.NewRecorder()
+
+	err := input.TypeText(recorder, "Hi")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"key 42 down", "key 35 down", "key 35 up", "key 42 up",
+		"key 23 down", "key 23 up",
+	}, recorder.Events())
+}
+
+func TestSendCombo_ReleasesModifiersInReverse(t *testing.T) {
+	recorder := inputtest.NewRecorder()
+	combo, _ := input.ParseCombo("Super+Shift+q")
+
+	err := input.SendCombo(recorder, combo)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"key 125 down", "key 42 down", "key 16 down", "key 16 up", "key 42 up", "key 125 up",
+	}, recorder.Events())
+}
@@ -0,0 +1,119 @@
+package input
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+type Button uint8
+
+const (
+	ButtonLeft   Button = 0
+	ButtonRight  Button = 1
+	ButtonMiddle Button = 2
+)
+
+type PointerEventType uint8
+
+const (
+	PointerMove   PointerEventType = 1
+	PointerButton PointerEventType = 2
+	PointerClick  PointerEventType = 3
+	PointerScroll PointerEventType = 4
+)
+
+var pointerEventLengths = map[PointerEventType]int{
+	PointerMove:   5,
+	PointerButton: 3,
+	PointerClick:  2,
+	PointerScroll: 5,
+}
+
+var ErrUnsupported = errors.New("input injection not supported on this platform")
+
+type PointerEvent struct {
+	Type    PointerEventType
+	DX      int16
+	DY      int16
+	Button  Button
+	Pressed bool
+}
+
+type InputInjector interface {
+	MovePointer(dx, dy int) error
+	PressButton(button Button, pressed bool) error
+	Scroll(vertical, horizontal int) error
+	PressKey(code KeyCode, pressed bool) error
+	Close() error
+}
+
+func DecodePointerEvents(data []byte) ([]PointerEvent, error) {
+	var events []PointerEvent
+	for len(data) > 0 {
+		eventType := PointerEventType(data[0])
+		length, ok := pointerEventLengths[eventType]
+		if !ok {
+			return nil, fmt.Errorf("unknown pointer event type %d", eventType)
+		}
+		if len(data) < length {
+			return nil, fmt.Errorf("truncated pointer event type %d", eventType)
+		}
+
+		event := PointerEvent{Type: eventType}
+		switch eventType {
+		case PointerMove, PointerScroll:
+			event.DX = int16(binary.LittleEndian.Uint16(data[1:3]))
+			event.DY = int16(binary.LittleEndian.Uint16(data[3:5]))
+		case PointerButton:
+			event.Button = Button(data[1])
+			event.Pressed = data[2] != 0
+		case PointerClick:
+			event.Button = Button(data[1])
+		}
+		if event.Button > ButtonMiddle {
+			return nil, fmt.Errorf("unknown pointer button %d", event.Button)
+		}
+		events = append(events, event)
+		data = data[length:]
+	}
+	return events, nil
+}
+
+func EncodePointerEvents(events ...PointerEvent) []byte {
+	var data []byte
+	for _, event := range events {
+		switch event.Type {
+		case PointerMove, PointerScroll:
+			data = append(data, byte(event.Type))
+			data = binary.LittleEndian.AppendUint16(data, uint16(event.DX))
+			data = binary.LittleEndian.AppendUint16(data, uint16(event.DY))
+		case PointerButton:
+			pressed := byte(0)
+			if event.Pressed {
+				pressed = 1
+			}
+			data = append(data, byte(event.Type), byte(event.Button), pressed)
+		case PointerClick:
+			data = append(data, byte(event.Type), byte(event.Button))
+		}
+	}
+	return data
+}
+
+func DispatchPointerEvent(injector InputInjector, event PointerEvent) error {
+	switch event.Type {
+	case PointerMove:
+		return injector.MovePointer(int(event.DX), int(event.DY))
+	case PointerButton:
+		return injector.PressButton(event.Button, event.Pressed)
+	case PointerClick:
+		if err := injector.PressButton(event.Button, true); err != nil {
+			return err
+		}
+		return injector.PressButton(event.Button, false)
+	case PointerScroll:
+		return injector.Scroll(int(event.DY), int(event.DX))
+	}
+	return fmt.Errorf("unknown pointer event type %d", event.Type)
+}
@@ -0,0 +1,88 @@
+package input_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phasecurve/sway_rm/internal/input"
+	"github.com/phasecurve/sway_rm/internal/input/inputtest"
+)
+
+func TestDecodePointerEvents_DecodesEachEventType(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		expected input.PointerEvent
+	}{
+		{"move", []byte{1, 0x05, 0x00, 0xfd, 0xff}, input.PointerEvent{Type: input.PointerMove, DX: 5, DY: -3}},
+		{"button down", []byte{2, 1, 1}, input.PointerEvent{Type: input.PointerButton, Button: input.ButtonRight, Pressed: true}},
+		{"button up", []byte{2, 0, 0}, input.PointerEvent{Type: input.PointerButton, Button: input.ButtonLeft}},
+		{"click", []byte{3, 2}, input.PointerEvent{Type: input.PointerClick, Button: input.ButtonMiddle}},
+		{"scroll", []byte{4, 0x00, 0x00, 0x02, 0x00}, input.PointerEvent{Type: input.PointerScroll, DY: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := input.DecodePointerEvents(tt.data)
+
+			assert.NoError(t, err)
+			assert.Equal(t, []input.PointerEvent{tt.expected}, events)
+		})
+	}
+}
+
+func TestDecodePointerEvents_BatchedMessage(t *testing.T) {
+	data := input.EncodePointerEvents(
+		input.PointerEvent{Type: input.PointerMove, DX: -120, DY: 300},
+		input.PointerEvent{Type: input.PointerClick, Button: input.ButtonLeft},
+		input.PointerEvent{Type: input.PointerScroll, DX: 1, DY: -1},
+	)
+
+	events, err := input.DecodePointerEvents(data)
+
+	assert.NoError(t, err)
+	assert.Len(t, events, 3)
+	assert.Equal(t, int16(-120), events[0].DX)
+	assert.Equal(t, int16(300), events[0].DY)
+	assert.Equal(t, input.PointerClick, events[1].Type)
+	assert.Equal(t, int16(-1), events[2].DY)
+}
+
+func TestDecodePointerEvents_InvalidData_ReturnsError(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"unknown type", []byte{9, 0, 0}},
+		{"truncated move", []byte{1, 0x05, 0x00}},
+		{"unknown button", []byte{3, 7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := input.DecodePointerEvents(tt.data)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDispatchPointerEvent_DrivesInjector(t *testing.T) {
+	recorder := inputtest.NewRecorder()
+
+	for _, event := range []input.PointerEvent{
+		{Type: input.PointerMove, DX: 3, DY: 4},
+		{Type: input.PointerClick, Button: input.ButtonLeft},
+		{Type: input.PointerButton, Button: input.ButtonRight, Pressed: true},
+		{Type: input.PointerScroll, DX: -1, DY: 2},
+	} {
+		assert.NoError(t, input.DispatchPointerEvent(recorder, event))
+	}
+
+	assert.Equal(t, []string{
+		"move 3 4",
+		"button 0 down",
+		"button 0 up",
+		"button 1 down",
+		"scroll 2 -1",
+	}, recorder.Events())
+}
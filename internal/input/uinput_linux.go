@@ -0,0 +1,193 @@
+package input
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	uinputPath = "/dev/uinput"
+	deviceName = "sway-rm virtual input"
+
+	uiSetEvBit   = 0x40045564
+	uiSetKeyBit  = 0x40045565
+	uiSetRelBit  = 0x40045566
+	uiDevSetup   = 0x405c5503
+	uiDevCreate  = 0x5501
+	uiDevDestroy = 0x5502
+
+	evSyn = 0x00
+	evKey = 0x01
+	evRel = 0x02
+
+	synReport = 0
+	relX      = 0x00
+	relY      = 0x01
+	relHWheel = 0x06
+	relWheel  = 0x08
+	btnLeft   = 0x110
+	btnRight  = 0x111
+	btnMiddle = 0x112
+
+	busVirtual = 0x06
+)
+
+var buttonCodes = map[Button]uint16{
+	ButtonLeft:   btnLeft,
+	ButtonRight:  btnRight,
+	ButtonMiddle: btnMiddle,
+}
+
+type inputID struct {
+	BusType uint16
+	Vendor  uint16
+	Product uint16
+	Version uint16
+}
+
+type uinputSetup struct {
+	ID           inputID
+	Name         [80]byte
+	FFEffectsMax uint32
+}
+
+type inputEvent struct {
+	Time  syscall.Timeval
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+type UInputInjector struct {
+	mu     sync.Mutex
+	device *os.File
+}
+
+func NewUInputInjector() (*UInputInjector, error) {
+	device, err := os.OpenFile(uinputPath, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", uinputPath, err)
+	}
+
+	if err := configureDevice(device); err != nil {
+		device.Close()
+		return nil, err
+	}
+	return &UInputInjector{device: device}, nil
+}
+
+func configureDevice(device *os.File) error {
+	bits := map[uintptr][]uint16{
+		uiSetEvBit:  {evKey, evRel},
+		uiSetRelBit: {relX, relY, relWheel, relHWheel},
+	}
+	for _, code := range buttonCodes {
+		bits[uiSetKeyBit] = append(bits[uiSetKeyBit], code)
+	}
+	for _, code := range KeymapCodes() {
+		bits[uiSetKeyBit] = append(bits[uiSetKeyBit], uint16(code))
+	}
+	for request, codes := range bits {
+		for _, code := range codes {
+			if err := ioctl(device, request, uintptr(code)); err != nil {
+				return fmt.Errorf("failed to configure uinput device: %w", err)
+			}
+		}
+	}
+
+	setup := uinputSetup{ID: inputID{BusType: busVirtual, Vendor: 0x1, Product: 0x1, Version: 1}}
+	copy(setup.Name[:], deviceName)
+	if err := ioctlPointer(device, uiDevSetup, unsafe.Pointer(&setup)); err != nil {
+		return fmt.Errorf("failed to set up uinput device: %w", err)
+	}
+	if err := ioctl(device, uiDevCreate, 0); err != nil {
+		return fmt.Errorf("failed to create uinput device: %w", err)
+	}
+	return nil
+}
+
+func ioctl(device *os.File, request, value uintptr) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, device.Fd(), request, value)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func ioctlPointer(device *os.File, request uintptr, value unsafe.Pointer) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, device.Fd(), request, uintptr(value))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func (u *UInputInjector) MovePointer(dx, dy int) error {
+	return u.emit(
+		inputEvent{Type: evRel, Code: relX, Value: int32(dx)},
+		inputEvent{Type: evRel, Code: relY, Value: int32(dy)},
+	)
+}
+
+func (u *UInputInjector) PressButton(button Button, pressed bool) error {
+	code, ok := buttonCodes[button]
+	if !ok {
+		return fmt.Errorf("unknown pointer button %d", button)
+	}
+	value := int32(0)
+	if pressed {
+		value = 1
+	}
+	return u.emit(inputEvent{Type: evKey, Code: code, Value: value})
+}
+
+func (u *UInputInjector) PressKey(code KeyCode, pressed bool) error {
+	value := int32(0)
+	if pressed {
+		value = 1
+	}
+	return u.emit(inputEvent{Type: evKey, Code: uint16(code), Value: value})
+}
+
+func (u *UInputInjector) Scroll(vertical, horizontal int) error {
+	return u.emit(
+		inputEvent{Type: evRel, Code: relWheel, Value: int32(vertical)},
+		inputEvent{Type: evRel, Code: relHWheel, Value: int32(horizontal)},
+	)
+}
+
+func (u *UInputInjector) Close() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.device == nil {
+		return nil
+	}
+	ioctl(u.device, uiDevDestroy, 0)
+	err := u.device.Close()
+	u.device = nil
+	return err
+}
+
+func (u *UInputInjector) emit(events ...inputEvent) error {
+	var buf bytes.Buffer
+	for _, event := range append(events, inputEvent{Type: evSyn, Code: synReport}) {
+		if err := binary.Write(&buf, binary.NativeEndian, event); err != nil {
+			return err
+		}
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.device == nil {
+		return os.ErrClosed
+	}
+	if _, err := u.device.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write input events: %w", err)
+	}
+	return nil
+}
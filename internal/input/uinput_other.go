@@ -0,0 +1,29 @@
+//go:build !linux
+
+package input
+
+type UInputInjector struct{}
+
+func NewUInputInjector() (*UInputInjector, error) {
+	return nil, ErrUnsupported
+}
+
+func (u *UInputInjector) MovePointer(dx, dy int) error {
+	return ErrUnsupported
+}
+
+func (u *UInputInjector) PressButton(button Button, pressed bool) error {
+	return ErrUnsupported
+}
+
+func (u *UInputInjector) Scroll(vertical, horizontal int) error {
+	return ErrUnsupported
+}
+
+func (u *UInputInjector) PressKey(code KeyCode, pressed bool) error {
+	return ErrUnsupported
+}
+
+func (u *UInputInjector) Close() error {
+	return nil
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LocalOnly rejects requests that didn't come from this machine. It looks
+// at the connection's address rather than forwarding headers, so it can't
+// be spoofed, but everything looks local behind a reverse proxy on the
+// same host.
+//
+// The Host header must name this machine too, so a page on another site
+// can't reach these routes by rebinding its own domain to 127.0.0.1. Unsafe
+// methods are also refused when the browser says another origin sent them,
+// so a page open in the laptop's browser can't approve a pairing request.
+func LocalOnly() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !isLoopback(ctx.Request.RemoteAddr) || !isLocalHost(ctx.Request.Host) {
+			ctx.String(http.StatusForbidden, "Only available on the computer running sway_rm.")
+			ctx.Abort()
+			return
+		}
+		if !isSafeMethod(ctx.Request.Method) && isCrossOrigin(ctx.Request) {
+			ctx.String(http.StatusForbidden, "Only available from sway_rm's own pages.")
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+func isLoopback(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// isLocalHost reports whether a Host header, with or without a port, is
+// localhost or a loopback address.
+func isLocalHost(hostport string) bool {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = strings.TrimSuffix(strings.TrimPrefix(hostport, "["), "]")
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// isCrossOrigin reports whether the browser says the request came from a
+// page other than one this server sent. Requests without Origin or
+// Sec-Fetch-Site, such as curl's, aren't from a browser and are allowed.
+func isCrossOrigin(req *http.Request) bool {
+	switch req.Header.Get("Sec-Fetch-Site") {
+	case "", "same-origin", "none":
+	default:
+		return true
+	}
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	parsed, err := url.Parse(origin)
+	if err != nil || parsed.Host == "" {
+		return true
+	}
+	return !strings.EqualFold(parsed.Host, req.Host)
+}
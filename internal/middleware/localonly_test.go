@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func createLocalOnlyRouter() *gin.Engine {
+	router := gin.New()
+	local := router.Group("/", LocalOnly())
+	local.GET("/local", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	local.POST("/local", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	return router
+}
+
+func requestLocal(router *gin.Engine, method, remoteAddr, host string, headers map[string]string) int {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, "/local", nil)
+	req.RemoteAddr = remoteAddr
+	req.Host = host
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestLocalOnly_Host(t *testing.T) {
+	tests := []struct {
+		host string
+		want int
+	}{
+		{"localhost:8080", http.StatusNoContent},
+		{"LOCALHOST", http.StatusNoContent},
+		{"127.0.0.1:8080", http.StatusNoContent},
+		{"127.0.0.1", http.StatusNoContent},
+		{"[::1]:8080", http.StatusNoContent},
+		{"[::1]", http.StatusNoContent},
+		{"", http.StatusForbidden},
+		{"evil.example.com:8080", http.StatusForbidden},
+		{"192.168.1.20:8080", http.StatusForbidden},
+		{"localhost.evil.example.com", http.StatusForbidden},
+	}
+	router := createLocalOnlyRouter()
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			assert.Equal(t, tt.want, requestLocal(router, http.MethodGet, "127.0.0.1:40000", tt.host, nil))
+		})
+	}
+}
+
+func TestLocalOnly_RemoteAddr(t *testing.T) {
+	router := createLocalOnlyRouter()
+
+	assert.Equal(t, http.StatusNoContent, requestLocal(router, http.MethodGet, "[::1]:40000", "localhost:8080", nil))
+	assert.Equal(t, http.StatusForbidden, requestLocal(router, http.MethodGet, "192.168.1.20:40000", "localhost:8080", nil))
+	assert.Equal(t, http.StatusForbidden, requestLocal(router, http.MethodGet, "192.168.1.20:40000", "localhost:8080", map[string]string{"X-Forwarded-For": "127.0.0.1"}), "forwarding headers should not make a request local")
+}
+
+func TestLocalOnly_CrossOriginPost(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    int
+	}{
+		{"no browser headers", nil, http.StatusNoContent},
+		{"same origin", map[string]string{"Origin": "http://localhost:8080", "Sec-Fetch-Site": "same-origin"}, http.StatusNoContent},
+		{"typed into the address bar", map[string]string{"Sec-Fetch-Site": "none"}, http.StatusNoContent},
+		{"other site", map[string]string{"Origin": "https://evil.example.com", "Sec-Fetch-Site": "cross-site"}, http.StatusForbidden},
+		{"other site without fetch metadata", map[string]string{"Origin": "https://evil.example.com"}, http.StatusForbidden},
+		{"other local port", map[string]string{"Origin": "http://localhost:3000"}, http.StatusForbidden},
+		{"sandboxed page", map[string]string{"Origin": "null"}, http.StatusForbidden},
+		{"same site", map[string]string{"Sec-Fetch-Site": "same-site"}, http.StatusForbidden},
+		{"cross site without origin", map[string]string{"Sec-Fetch-Site": "cross-site"}, http.StatusForbidden},
+	}
+	router := createLocalOnlyRouter()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, requestLocal(router, http.MethodPost, "127.0.0.1:40000", "localhost:8080", tt.headers))
+		})
+	}
+}
+
+func TestLocalOnly_CrossOriginGet_Allowed(t *testing.T) {
+	router := createLocalOnlyRouter()
+
+	code := requestLocal(router, http.MethodGet, "127.0.0.1:40000", "localhost:8080", map[string]string{"Origin": "https://evil.example.com", "Sec-Fetch-Site": "cross-site"})
+
+	assert.Equal(t, http.StatusNoContent, code, "reads are already kept from other sites by the same-origin policy")
+}
@@ -1,9 +1,11 @@
 package middleware
 
 import (
+	"math"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/phasecurve/sway_rm/internal/clock"
 	"github.com/phasecurve/sway_rm/internal/security"
 )
 
@@ -11,10 +13,43 @@ type Logger interface {
 	Printf(format string, v ...interface{})
 }
 
-func PairRefresh(keyStore security.KeyStorer, logger Logger) gin.HandlerFunc {
+// RefreshPolicy controls how far PairRefresh extends sessions in use.
+type RefreshPolicy struct {
+	// Extension is how long a session stays valid after its last request.
+	Extension time.Duration
+	// MaxLifetime caps a session at this long after pairing, however often
+	// it's used. Zero means no cap.
+	MaxLifetime time.Duration
+}
+
+// Refreshed returns the expiry of a session used at now: Extension from
+// now, never earlier than the current expiry, and capped by MaxLifetime.
+func (p RefreshPolicy) Refreshed(key *security.APIKey, now time.Time) time.Time {
+	expiry := now.Add(p.Extension)
+	if key.TTL.After(expiry) {
+		expiry = key.TTL
+	}
+	return p.Cap(key.PairedAt, expiry)
+}
+
+// Cap limits expiry to MaxLifetime after pairedAt.
+func (p RefreshPolicy) Cap(pairedAt, expiry time.Time) time.Time {
+	if p.MaxLifetime <= 0 {
+		return expiry
+	}
+	if limit := pairedAt.Add(p.MaxLifetime); expiry.After(limit) {
+		return limit
+	}
+	return expiry
+}
+
+// PairRefresh slides the expiry of sessions forward as they're used and
+// keeps the api-key cookie's Max-Age in step with the stored expiry.
+// Tokens keep the expiry they were minted with.
+func PairRefresh(keyStore security.KeyStorer, clk clock.Clock, logger Logger, policy RefreshPolicy) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		apiKey, err := ctx.Cookie("api-key")
-		if err != nil {
+		apiKey, ok := presentedKey(ctx)
+		if !ok {
 			ctx.Next()
 			return
 		}
@@ -25,16 +60,34 @@ func PairRefresh(keyStore security.KeyStorer, logger Logger) gin.HandlerFunc {
 			return
 		}
 
-		if existingKey.TTL.Before(time.Now()) {
+		now := clk.Now()
+		if existingKey.Type == security.KeyTypeToken || !now.Before(existingKey.TTL) {
 			ctx.Next()
 			return
 		}
 
-		newExpiry := existingKey.TTL.Add(30 * time.Minute)
-		if err := keyStore.StoreAPIKey(apiKey, newExpiry); err != nil {
+		// Keys migrated from before pairing times were recorded start their
+		// maximum lifetime now.
+		if existingKey.PairedAt.IsZero() {
+			existingKey.PairedAt = now
+		}
+		existingKey.TTL = policy.Refreshed(existingKey, now)
+		existingKey.LastSeen = now
+		if err := keyStore.SaveAPIKey(existingKey); err != nil {
 			logger.Printf("failed to refresh API key TTL: %v", err)
+		} else if _, bearer := bearerKey(ctx); !bearer {
+			SetAPIKeyCookie(ctx, apiKey, existingKey.TTL, now)
 		}
 
 		ctx.Next()
 	}
 }
+
+// SetAPIKeyCookie sets the api-key cookie to expire with the stored key.
+func SetAPIKeyCookie(ctx *gin.Context, apiKey string, expiry, now time.Time) {
+	maxAge := int(math.Ceil(expiry.Sub(now).Seconds()))
+	if maxAge <= 0 {
+		maxAge = -1
+	}
+	ctx.SetCookie(apiKeyCookieName, apiKey, maxAge, "/", "", false, true)
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/phasecurve/sway_rm/internal/clock"
+	"github.com/phasecurve/sway_rm/internal/security"
+)
+
+const (
+	apiKeyCookieName = "api-key"
+	apiKeyContextKey = "apiKey"
+)
+
+// PairedKey resolves the request's bearer token or api-key cookie to a key
+// that hasn't expired at clk's now. presented reports whether the request
+// carried a key at all, so callers can tell an expired session from a
+// device that never paired.
+func PairedKey(ctx *gin.Context, keyStore security.KeyStorer, clk clock.Clock) (key *security.APIKey, presented bool) {
+	raw, ok := presentedKey(ctx)
+	if !ok {
+		return nil, false
+	}
+	key, err := keyStore.GetAPIKey(raw)
+	if err != nil || !clk.Now().Before(key.TTL) {
+		return nil, true
+	}
+	return key, true
+}
+
+// presentedKey returns the raw key from an Authorization: Bearer header,
+// falling back to the api-key cookie.
+func presentedKey(ctx *gin.Context) (string, bool) {
+	if token, ok := bearerKey(ctx); ok {
+		return token, true
+	}
+	raw, err := ctx.Cookie(apiKeyCookieName)
+	if err != nil {
+		return "", false
+	}
+	return raw, true
+}
+
+func bearerKey(ctx *gin.Context) (string, bool) {
+	scheme, token, found := strings.Cut(ctx.GetHeader("Authorization"), " ")
+	token = strings.TrimSpace(token)
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+// RequirePaired aborts requests without a valid paired key. API routes get
+// a 401 JSON error, pages are redirected to the pair form, and HTMX
+// requests are told to redirect the whole page. The resolved key is
+// available to handlers through APIKey.
+func RequirePaired(keyStore security.KeyStorer, clk clock.Clock) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key, _ := PairedKey(ctx, keyStore, clk)
+		if key == nil {
+			rejectUnpaired(ctx)
+			return
+		}
+		ctx.Set(apiKeyContextKey, key)
+		ctx.Next()
+	}
+}
+
+// APIKey returns the key stored by RequirePaired.
+func APIKey(ctx *gin.Context) (*security.APIKey, bool) {
+	value, ok := ctx.Get(apiKeyContextKey)
+	if !ok {
+		return nil, false
+	}
+	key, ok := value.(*security.APIKey)
+	return key, ok
+}
+
+func rejectUnpaired(ctx *gin.Context) {
+	htmx := ctx.GetHeader("HX-Request") == "true"
+	if htmx {
+		ctx.Header("HX-Redirect", "/")
+	}
+	switch {
+	case strings.HasPrefix(ctx.Request.URL.Path, "/api/"):
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "not paired"})
+	case htmx:
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+	default:
+		ctx.Redirect(http.StatusSeeOther, "/")
+		ctx.Abort()
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireScope aborts with 403 unless the key stored by RequirePaired
+// grants scope. It must run after RequirePaired.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key, ok := APIKey(ctx)
+		if !ok {
+			rejectUnpaired(ctx)
+			return
+		}
+		if !key.HasScope(scope) {
+			if strings.HasPrefix(ctx.Request.URL.Path, "/api/") {
+				ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "missing scope", "scope": scope})
+				return
+			}
+			ctx.String(http.StatusForbidden, "This device isn't allowed to use %s controls.", scope)
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,289 @@
+package mpv
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	socketEnvName        = "MPV_SOCKET"
+	defaultSocketPath    = "/tmp/mpvsocket"
+	defaultTimeout       = 2 * time.Second
+	subscriberBufferSize = 32
+	replySuccess         = "success"
+)
+
+var (
+	ErrNoSocket     = errors.New("mpv socket path not set")
+	ErrDisconnected = errors.New("mpv connection closed")
+)
+
+type Event struct {
+	Event  string          `json:"event"`
+	ID     int64           `json:"id,omitempty"`
+	Name   string          `json:"name,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	Reason string          `json:"reason,omitempty"`
+}
+
+type Player interface {
+	Command(args ...any) (json.RawMessage, error)
+	GetProperty(name string, value any) error
+	SetProperty(name string, value any) error
+	ObserveProperty(name string) (int64, error)
+	UnobserveProperty(id int64) error
+	Subscribe() (<-chan Event, func())
+}
+
+type request struct {
+	Command   []any `json:"command"`
+	RequestID int64 `json:"request_id"`
+}
+
+type message struct {
+	Event
+	Error     string `json:"error"`
+	RequestID *int64 `json:"request_id"`
+}
+
+func (m message) isEvent() bool {
+	return m.Event.Event != ""
+}
+
+type reply struct {
+	data json.RawMessage
+	err  error
+}
+
+type Client struct {
+	socketPath  string
+	timeout     time.Duration
+	mu          sync.Mutex
+	conn        net.Conn
+	nextID      int64
+	pending     map[int64]chan reply
+	observed    map[int64]string
+	subscribers map[chan Event]struct{}
+}
+
+func NewClient(socketPath string) *Client {
+	return &Client{
+		socketPath:  socketPath,
+		timeout:     defaultTimeout,
+		pending:     make(map[int64]chan reply),
+		observed:    make(map[int64]string),
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+func SocketPath() string {
+	if path := os.Getenv(socketEnvName); path != "" {
+		return path
+	}
+	return defaultSocketPath
+}
+
+func (c *Client) Command(args ...any) (json.RawMessage, error) {
+	c.mu.Lock()
+	if err := c.connect(); err != nil {
+		c.mu.Unlock()
+		return nil, err
+	}
+	id, ch, err := c.send(args)
+	c.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case r := <-ch:
+		if r.err != nil {
+			return nil, fmt.Errorf("mpv command %v failed: %w", args, r.err)
+		}
+		return r.data, nil
+	case <-time.After(c.timeout):
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return nil, fmt.Errorf("mpv command %v timed out", args)
+	}
+}
+
+func (c *Client) GetProperty(name string, value any) error {
+	data, err := c.Command("get_property", name)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return fmt.Errorf("failed to decode mpv property %q: %w", name, err)
+	}
+	return nil
+}
+
+func (c *Client) SetProperty(name string, value any) error {
+	_, err := c.Command("set_property", name, value)
+	return err
+}
+
+func (c *Client) ObserveProperty(name string) (int64, error) {
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	c.mu.Unlock()
+
+	if _, err := c.Command("observe_property", id, name); err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	c.observed[id] = name
+	c.mu.Unlock()
+	return id, nil
+}
+
+func (c *Client) UnobserveProperty(id int64) error {
+	c.mu.Lock()
+	delete(c.observed, id)
+	c.mu.Unlock()
+
+	_, err := c.Command("unobserve_property", id)
+	return err
+}
+
+func (c *Client) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBufferSize)
+	c.mu.Lock()
+	c.subscribers[ch] = struct{}{}
+	c.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if _, ok := c.subscribers[ch]; ok {
+				delete(c.subscribers, ch)
+				close(ch)
+			}
+		})
+	}
+	return ch, unsubscribe
+}
+
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for ch := range c.subscribers {
+		delete(c.subscribers, ch)
+		close(ch)
+	}
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+func (c *Client) connect() error {
+	if c.conn != nil {
+		return nil
+	}
+	if c.socketPath == "" {
+		return ErrNoSocket
+	}
+	conn, err := net.Dial("unix", c.socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to connect to mpv: %w", err)
+	}
+	c.conn = conn
+	go c.read(conn)
+
+	for id, name := range c.observed {
+		if _, _, err := c.send([]any{"observe_property", id, name}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) send(args []any) (int64, chan reply, error) {
+	c.nextID++
+	id := c.nextID
+	payload, err := json.Marshal(request{Command: args, RequestID: id})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	ch := make(chan reply, 1)
+	c.pending[id] = ch
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	if _, err := c.conn.Write(append(payload, '\n')); err != nil {
+		delete(c.pending, id)
+		c.conn.Close()
+		c.conn = nil
+		return 0, nil, fmt.Errorf("failed to write mpv command: %w", err)
+	}
+	return id, ch, nil
+}
+
+func (c *Client) read(conn net.Conn) {
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var msg message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			continue
+		}
+		if msg.isEvent() {
+			c.publish(msg.Event)
+			continue
+		}
+		if msg.RequestID != nil {
+			c.resolve(*msg.RequestID, msg)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == conn {
+		c.conn.Close()
+		c.conn = nil
+	}
+	for id, ch := range c.pending {
+		ch <- reply{err: ErrDisconnected}
+		delete(c.pending, id)
+	}
+}
+
+func (c *Client) resolve(id int64, msg message) {
+	c.mu.Lock()
+	ch, ok := c.pending[id]
+	delete(c.pending, id)
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+	if msg.Error != replySuccess {
+		ch <- reply{err: errors.New(msg.Error)}
+		return
+	}
+	ch <- reply{data: msg.Data}
+}
+
+func (c *Client) publish(event Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for ch := range c.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
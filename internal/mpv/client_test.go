@@ -0,0 +1,162 @@
+package mpv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phasecurve/sway_rm/internal/mpv/mpvtest"
+)
+
+func createTestClient(t *testing.T) (*Client, *mpvtest.Server) {
+	fake := mpvtest.NewServer(t)
+	client := NewClient(fake.SocketPath)
+	t.Cleanup(func() {
+		client.Close()
+	})
+	return client, fake
+}
+
+func receiveEvent(t *testing.T, events <-chan Event) Event {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for mpv event")
+		return Event{}
+	}
+}
+
+func TestCommand_SendsCommandArguments(t *testing.T) {
+	client, fake := createTestClient(t)
+
+	_, err := client.Command("seek", 10, "relative")
+
+	assert.NoError(t, err)
+	assert.Equal(t, [][]any{{"seek", float64(10), "relative"}}, fake.Commands())
+}
+
+func TestGetProperty_DecodesValue(t *testing.T) {
+	client, fake := createTestClient(t)
+	fake.SetProperty("volume", 65.5)
+
+	var volume float64
+	err := client.GetProperty("volume", &volume)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 65.5, volume)
+}
+
+func TestGetProperty_Unavailable_ReturnsError(t *testing.T) {
+	client, _ := createTestClient(t)
+
+	var title string
+	err := client.GetProperty("media-title", &title)
+
+	assert.ErrorContains(t, err, "property unavailable")
+}
+
+func TestSetProperty_UpdatesMPV(t *testing.T) {
+	client, fake := createTestClient(t)
+
+	err := client.SetProperty("pause", true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, true, fake.Property("pause"))
+}
+
+func TestCommand_ConcurrentRequests_CorrelatedByRequestID(t *testing.T) {
+	client, fake := createTestClient(t)
+	fake.SetProperty("volume", 40.0)
+	fake.SetProperty("speed", 1.5)
+
+	results := make(chan float64, 20)
+	for i := 0; i < 10; i++ {
+		go func() {
+			var volume float64
+			client.GetProperty("volume", &volume)
+			results <- volume
+		}()
+		go func() {
+			var speed float64
+			client.GetProperty("speed", &speed)
+			results <- speed
+		}()
+	}
+
+	counts := map[float64]int{}
+	for i := 0; i < 20; i++ {
+		counts[<-results]++
+	}
+	assert.Equal(t, map[float64]int{40.0: 10, 1.5: 10}, counts, "each reply should reach the request that asked for it")
+}
+
+func TestObserveProperty_PublishesPropertyChanges(t *testing.T) {
+	client, fake := createTestClient(t)
+	events, unsubscribe := client.Subscribe()
+	defer unsubscribe()
+
+	id, err := client.ObserveProperty("pause")
+	assert.NoError(t, err)
+	receiveEvent(t, events)
+
+	fake.SetProperty("pause", true)
+
+	event := receiveEvent(t, events)
+	assert.Equal(t, "property-change", event.Event)
+	assert.Equal(t, id, event.ID, "event should carry the observer id")
+	assert.Equal(t, "pause", event.Name)
+	assert.JSONEq(t, "true", string(event.Data))
+}
+
+func TestSubscribe_ReceivesAsyncEvents(t *testing.T) {
+	client, fake := createTestClient(t)
+	events, unsubscribe := client.Subscribe()
+	defer unsubscribe()
+	_, err := client.Command("client_name")
+	assert.NoError(t, err)
+
+	fake.Emit(map[string]any{"event": "end-file", "reason": "eof"})
+
+	event := receiveEvent(t, events)
+	assert.Equal(t, "end-file", event.Event)
+	assert.Equal(t, "eof", event.Reason)
+}
+
+func TestClient_NoSocketPath_ReturnsErrNoSocket(t *testing.T) {
+	client := NewClient("")
+
+	_, err := client.Command("stop")
+
+	assert.ErrorIs(t, err, ErrNoSocket)
+}
+
+func TestClient_MPVRestarted_ReconnectsAndReobserves(t *testing.T) {
+	client, fake := createTestClient(t)
+	_, err := client.ObserveProperty("media-title")
+	assert.NoError(t, err)
+
+	assert.NoError(t, fake.Restart())
+	assert.Eventually(t, func() bool {
+		_, err := client.Command("client_name")
+		return err == nil
+	}, time.Second, 10*time.Millisecond, "client should redial after mpv restarts")
+
+	assert.Eventually(t, func() bool {
+		return fake.Observers("media-title") == 1
+	}, time.Second, 10*time.Millisecond, "observed properties should be restored on reconnect")
+}
+
+func TestUnobserveProperty_StopsObserving(t *testing.T) {
+	client, fake := createTestClient(t)
+	id, err := client.ObserveProperty("volume")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.Observers("volume"))
+
+	err = client.UnobserveProperty(id)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fake.Observers("volume"), "mpv should stop sending changes")
+	assert.Empty(t, client.observed, "property should not be re-observed on reconnect")
+}
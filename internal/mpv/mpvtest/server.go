@@ -0,0 +1,234 @@
+package mpvtest
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+type request struct {
+	Command   []any `json:"command"`
+	RequestID int64 `json:"request_id"`
+}
+
+type fakeConn struct {
+	net.Conn
+	writeMu sync.Mutex
+}
+
+func (c *fakeConn) write(v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err = c.Conn.Write(append(payload, '\n'))
+	return err
+}
+
+type observer struct {
+	conn *fakeConn
+	id   int64
+}
+
+type Server struct {
+	SocketPath string
+	listener   net.Listener
+	mu         sync.Mutex
+	properties map[string]any
+	commands   [][]any
+	conns      []*fakeConn
+	observers  map[string][]observer
+}
+
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	dir := TempDir(t)
+	return NewServerAt(t, filepath.Join(dir, "mpv.sock"))
+}
+
+func NewServerAt(t testing.TB, socketPath string) *Server {
+	t.Helper()
+	s := &Server{
+		SocketPath: socketPath,
+		properties: map[string]any{"pid": os.Getpid()},
+		observers:  make(map[string][]observer),
+	}
+	if err := s.listen(); err != nil {
+		t.Fatalf("failed to listen on fake mpv socket: %v", err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TempDir(t testing.TB) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "mpvtest")
+	if err != nil {
+		t.Fatalf("failed to create socket dir: %v", err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	return dir
+}
+
+func (s *Server) SetProperty(name string, value any) {
+	s.mu.Lock()
+	s.properties[name] = value
+	observers := append([]observer(nil), s.observers[name]...)
+	s.mu.Unlock()
+	for _, o := range observers {
+		o.conn.write(propertyChange(o.id, name, value))
+	}
+}
+
+func (s *Server) Property(name string) any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.properties[name]
+}
+
+func (s *Server) Commands() [][]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]any(nil), s.commands...)
+}
+
+func (s *Server) Observers(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.observers[name])
+}
+
+func (s *Server) Emit(event map[string]any) {
+	s.mu.Lock()
+	conns := append([]*fakeConn(nil), s.conns...)
+	s.mu.Unlock()
+	for _, conn := range conns {
+		conn.write(event)
+	}
+}
+
+func (s *Server) Restart() error {
+	s.Close()
+	os.Remove(s.SocketPath)
+	return s.listen()
+}
+
+func (s *Server) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener != nil {
+		s.listener.Close()
+		s.listener = nil
+	}
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+	s.observers = make(map[string][]observer)
+}
+
+func (s *Server) listen() error {
+	listener, err := net.Listen("unix", s.SocketPath)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+	go s.serve(listener)
+	return nil
+}
+
+func (s *Server) serve(listener net.Listener) {
+	for {
+		raw, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		conn := &fakeConn{Conn: raw}
+		s.mu.Lock()
+		s.conns = append(s.conns, conn)
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn *fakeConn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var req request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil || len(req.Command) == 0 {
+			conn.write(map[string]any{"error": "invalid parameter", "request_id": req.RequestID})
+			continue
+		}
+		response, event := s.execute(conn, req)
+		if err := conn.write(response); err != nil {
+			return
+		}
+		if event != nil {
+			conn.write(event)
+		}
+	}
+}
+
+func (s *Server) execute(conn *fakeConn, req request) (map[string]any, map[string]any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commands = append(s.commands, req.Command)
+
+	response := map[string]any{"error": "success", "request_id": req.RequestID}
+	var event map[string]any
+	name, _ := argument(req.Command, 1).(string)
+	switch req.Command[0] {
+	case "get_property":
+		value, ok := s.properties[name]
+		if !ok {
+			response["error"] = "property unavailable"
+			break
+		}
+		response["data"] = value
+	case "set_property":
+		s.properties[name] = argument(req.Command, 2)
+	case "observe_property":
+		id, _ := argument(req.Command, 1).(float64)
+		name, _ = argument(req.Command, 2).(string)
+		s.observers[name] = append(s.observers[name], observer{conn: conn, id: int64(id)})
+		event = propertyChange(int64(id), name, s.properties[name])
+	case "unobserve_property":
+		id, _ := argument(req.Command, 1).(float64)
+		for name, observers := range s.observers {
+			kept := observers[:0]
+			for _, o := range observers {
+				if o.conn != conn || o.id != int64(id) {
+					kept = append(kept, o)
+				}
+			}
+			s.observers[name] = kept
+		}
+	}
+	return response, event
+}
+
+func argument(command []any, index int) any {
+	if index >= len(command) {
+		return nil
+	}
+	return command[index]
+}
+
+func propertyChange(id int64, name string, value any) map[string]any {
+	event := map[string]any{"event": "property-change", "id": id, "name": name}
+	if value != nil {
+		event["data"] = value
+	}
+	return event
+}
@@ -0,0 +1,257 @@
+package mpv
+
+import (
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/phasecurve/sway_rm/internal/clock"
+)
+
+const (
+	defaultPositionThrottle = time.Second
+	defaultPingInterval     = 5 * time.Second
+)
+
+var nowPlayingProperties = []string{"time-pos", "duration", "pause", "volume", "media-title", "chapter"}
+
+type NowPlaying struct {
+	Title    string  `json:"title"`
+	Position float64 `json:"position"`
+	Duration float64 `json:"duration"`
+	Paused   bool    `json:"paused"`
+	Volume   float64 `json:"volume"`
+	Chapter  int     `json:"chapter"`
+	Active   bool    `json:"active"`
+}
+
+type NowPlayingSubscriber interface {
+	State() NowPlaying
+	Subscribe() (<-chan NowPlaying, func())
+}
+
+type Tracker struct {
+	player           Player
+	clock            clock.Clock
+	positionThrottle time.Duration
+	pingInterval     time.Duration
+	mu               sync.Mutex
+	state            NowPlaying
+	lastPosition     time.Time
+	positionPending  bool
+	observed         map[string]int64
+	subscribers      map[chan NowPlaying]struct{}
+	done             chan struct{}
+	stopped          chan struct{}
+	started          bool
+}
+
+func NewTracker(player Player) *Tracker {
+	return &Tracker{
+		player:           player,
+		clock:            clock.Real{},
+		positionThrottle: defaultPositionThrottle,
+		pingInterval:     defaultPingInterval,
+		observed:         make(map[string]int64),
+		subscribers:      make(map[chan NowPlaying]struct{}),
+		done:             make(chan struct{}),
+		stopped:          make(chan struct{}),
+	}
+}
+
+func (t *Tracker) Start() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.started {
+		return
+	}
+	t.started = true
+	go t.run()
+}
+
+func (t *Tracker) Close() {
+	t.mu.Lock()
+	select {
+	case <-t.done:
+		t.mu.Unlock()
+		return
+	default:
+	}
+	close(t.done)
+	started := t.started
+	t.mu.Unlock()
+	if started {
+		<-t.stopped
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for ch := range t.subscribers {
+		delete(t.subscribers, ch)
+		close(ch)
+	}
+}
+
+func (t *Tracker) State() NowPlaying {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.state
+}
+
+func (t *Tracker) Subscribe() (<-chan NowPlaying, func()) {
+	ch := make(chan NowPlaying, subscriberBufferSize)
+	t.mu.Lock()
+	t.subscribers[ch] = struct{}{}
+	t.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if _, ok := t.subscribers[ch]; ok {
+				delete(t.subscribers, ch)
+				close(ch)
+			}
+		})
+	}
+	return ch, unsubscribe
+}
+
+func (t *Tracker) run() {
+	defer close(t.stopped)
+	events, unsubscribe := t.player.Subscribe()
+	defer unsubscribe()
+	defer t.unobserve()
+
+	t.observe()
+	ticker := time.NewTicker(t.pingInterval)
+	defer ticker.Stop()
+	// trailing fires when the time-pos throttle window closes with an
+	// unpublished position, so seeks and pauses don't leave it stale.
+	var trailing <-chan time.Time
+
+	for {
+		select {
+		case <-t.done:
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if event.Event != "property-change" {
+				continue
+			}
+			if wait := t.apply(event.Name, event.Data); wait > 0 && trailing == nil {
+				trailing = time.After(wait)
+			}
+		case <-trailing:
+			trailing = nil
+			t.flushPosition()
+		case <-ticker.C:
+			t.observe()
+			t.ping()
+		}
+	}
+}
+
+func (t *Tracker) observe() {
+	for _, name := range nowPlayingProperties {
+		if _, ok := t.observed[name]; ok {
+			continue
+		}
+		if id, err := t.player.ObserveProperty(name); err == nil {
+			t.observed[name] = id
+		}
+	}
+}
+
+func (t *Tracker) unobserve() {
+	for name, id := range t.observed {
+		t.player.UnobserveProperty(id)
+		delete(t.observed, name)
+	}
+}
+
+func (t *Tracker) ping() {
+	var paused bool
+	if err := t.player.GetProperty("pause", &paused); err != nil {
+		t.mu.Lock()
+		wasActive := t.state.Active
+		t.state = NowPlaying{}
+		t.mu.Unlock()
+		if wasActive {
+			t.publish()
+		}
+	}
+}
+
+// apply records a property change and publishes it. A throttled time-pos
+// change isn't published; apply returns how long until it may be instead.
+// Changes to properties other clients observe are ignored, so they can't
+// mark the tracker active.
+func (t *Tracker) apply(name string, data json.RawMessage) time.Duration {
+	if !slices.Contains(nowPlayingProperties, name) {
+		return 0
+	}
+	t.mu.Lock()
+	state := &t.state
+	state.Active = true
+	switch name {
+	case "time-pos":
+		state.Position = decodeOrZero[float64](data)
+		now := t.clock.Now()
+		if elapsed := now.Sub(t.lastPosition); elapsed < t.positionThrottle {
+			t.positionPending = true
+			t.mu.Unlock()
+			return t.positionThrottle - elapsed
+		}
+		t.lastPosition = now
+	case "duration":
+		state.Duration = decodeOrZero[float64](data)
+	case "pause":
+		state.Paused = decodeOrZero[bool](data)
+	case "volume":
+		state.Volume = decodeOrZero[float64](data)
+	case "media-title":
+		state.Title = decodeOrZero[string](data)
+	case "chapter":
+		state.Chapter = decodeOrZero[int](data)
+	}
+	t.positionPending = false
+	t.mu.Unlock()
+	t.publish()
+	return 0
+}
+
+func (t *Tracker) flushPosition() {
+	t.mu.Lock()
+	if !t.positionPending {
+		t.mu.Unlock()
+		return
+	}
+	t.positionPending = false
+	t.lastPosition = t.clock.Now()
+	t.mu.Unlock()
+	t.publish()
+}
+
+func (t *Tracker) publish() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for ch := range t.subscribers {
+		select {
+		case ch <- t.state:
+		default:
+		}
+	}
+}
+
+func decodeOrZero[T any](data json.RawMessage) T {
+	var value T
+	if len(data) > 0 {
+		json.Unmarshal(data, &value)
+	}
+	return value
+}
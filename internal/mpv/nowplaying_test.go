@@ -0,0 +1,165 @@
+package mpv
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phasecurve/sway_rm/internal/clock/clocktest"
+	"github.com/phasecurve/sway_rm/internal/mpv/mpvtest"
+)
+
+func createTestTracker(t *testing.T) (*Tracker, *mpvtest.Server) {
+	client, fake := createTestClient(t)
+	tracker := NewTracker(client)
+	t.Cleanup(tracker.Close)
+	return tracker, fake
+}
+
+func waitForObservers(t *testing.T, fake *mpvtest.Server) {
+	assert.Eventually(t, func() bool {
+		for _, name := range nowPlayingProperties {
+			if fake.Observers(name) != 1 {
+				return false
+			}
+		}
+		return true
+	}, time.Second, 5*time.Millisecond, "tracker should observe every now-playing property")
+}
+
+func receiveState(t *testing.T, states <-chan NowPlaying, match func(NowPlaying) bool) NowPlaying {
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case state := <-states:
+			if match(state) {
+				return state
+			}
+		case <-deadline:
+			t.Fatal("timed out waiting for now-playing state")
+			return NowPlaying{}
+		}
+	}
+}
+
+func TestTracker_PropertyChanges_UpdateState(t *testing.T) {
+	tracker, fake := createTestTracker(t)
+	states, unsubscribe := tracker.Subscribe()
+	defer unsubscribe()
+	tracker.Start()
+	waitForObservers(t, fake)
+
+	fake.SetProperty("media-title", "Big Buck Bunny")
+	fake.SetProperty("duration", 596.5)
+	fake.SetProperty("volume", 80.0)
+	fake.SetProperty("chapter", 2)
+	fake.SetProperty("pause", true)
+
+	state := receiveState(t, states, func(s NowPlaying) bool { return s.Paused })
+	assert.True(t, state.Active)
+	assert.Equal(t, "Big Buck Bunny", state.Title)
+	assert.Equal(t, 596.5, state.Duration)
+	assert.Equal(t, 80.0, state.Volume)
+	assert.Equal(t, 2, state.Chapter)
+	assert.Equal(t, state, tracker.State(), "snapshot should match the last published state")
+}
+
+func TestTracker_TimePos_IsThrottled(t *testing.T) {
+	tracker, fake := createTestTracker(t)
+	tracker.positionThrottle = time.Hour
+	fake.SetProperty("time-pos", 1.0)
+	states, unsubscribe := tracker.Subscribe()
+	defer unsubscribe()
+	tracker.Start()
+	waitForObservers(t, fake)
+
+	receiveState(t, states, func(s NowPlaying) bool { return s.Position == 1.0 })
+	fake.SetProperty("time-pos", 1.5)
+	fake.SetProperty("time-pos", 2.0)
+	fake.SetProperty("pause", true)
+
+	state := receiveState(t, states, func(s NowPlaying) bool {
+		assert.True(t, s.Paused || s.Position == 1.0, "throttled time-pos updates should not be published on their own")
+		return s.Paused
+	})
+	assert.Equal(t, 2.0, state.Position, "next published state should carry the latest position")
+}
+
+func TestTracker_TimePosAfterWindow_PublishedImmediately(t *testing.T) {
+	tracker, _ := createTestTracker(t)
+	fakeClock := clocktest.NewFake(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	tracker.clock = fakeClock
+	tracker.positionThrottle = time.Second
+	states, unsubscribe := tracker.Subscribe()
+	defer unsubscribe()
+
+	assert.Zero(t, tracker.apply("time-pos", json.RawMessage("1")))
+	assert.Equal(t, 1.0, receiveState(t, states, func(NowPlaying) bool { return true }).Position)
+
+	fakeClock.Advance(300 * time.Millisecond)
+	assert.Equal(t, 700*time.Millisecond, tracker.apply("time-pos", json.RawMessage("2")),
+		"a change inside the window should wait for the rest of it")
+	assert.Equal(t, 2.0, tracker.State().Position, "the snapshot should still carry the latest position")
+	assert.Empty(t, states)
+
+	fakeClock.Advance(time.Second)
+	assert.Zero(t, tracker.apply("time-pos", json.RawMessage("3")))
+	assert.Equal(t, 3.0, receiveState(t, states, func(NowPlaying) bool { return true }).Position, "a change after the window should be published at once")
+}
+
+func TestTracker_UntrackedProperty_StaysInactive(t *testing.T) {
+	tracker, _ := createTestTracker(t)
+	states, unsubscribe := tracker.Subscribe()
+	defer unsubscribe()
+
+	tracker.apply("speed", json.RawMessage("2"))
+
+	assert.False(t, tracker.State().Active, "a property the tracker doesn't observe should not mark it active")
+	assert.Empty(t, states)
+}
+
+func TestTracker_TimePosInWindow_PublishedWhenWindowCloses(t *testing.T) {
+	tracker, fake := createTestTracker(t)
+	tracker.positionThrottle = 50 * time.Millisecond
+	fake.SetProperty("time-pos", 1.0)
+	states, unsubscribe := tracker.Subscribe()
+	defer unsubscribe()
+	tracker.Start()
+	waitForObservers(t, fake)
+
+	receiveState(t, states, func(s NowPlaying) bool { return s.Position == 1.0 })
+	fake.SetProperty("time-pos", 42.0)
+
+	state := receiveState(t, states, func(s NowPlaying) bool { return s.Position == 42.0 })
+	assert.Equal(t, 42.0, state.Position, "last position in the window should be sent once it closes")
+}
+
+func TestTracker_MPVGone_ResetsState(t *testing.T) {
+	tracker, fake := createTestTracker(t)
+	tracker.pingInterval = 10 * time.Millisecond
+	states, unsubscribe := tracker.Subscribe()
+	defer unsubscribe()
+	tracker.Start()
+	waitForObservers(t, fake)
+	fake.SetProperty("media-title", "Big Buck Bunny")
+	receiveState(t, states, func(s NowPlaying) bool { return s.Title != "" })
+
+	fake.Close()
+
+	state := receiveState(t, states, func(s NowPlaying) bool { return !s.Active })
+	assert.Empty(t, state.Title, "state should be cleared when mpv goes away")
+}
+
+func TestTracker_Close_UnobservesProperties(t *testing.T) {
+	tracker, fake := createTestTracker(t)
+	tracker.Start()
+	waitForObservers(t, fake)
+
+	tracker.Close()
+
+	for _, name := range nowPlayingProperties {
+		assert.Equal(t, 0, fake.Observers(name), "%s should no longer be observed", name)
+	}
+}
@@ -0,0 +1,440 @@
+package mpv
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	globEnvName         = "MPV_SOCKET_GLOB"
+	defaultSocketGlob   = "/tmp/mpv-*.sock"
+	defaultScanInterval = 5 * time.Second
+)
+
+var (
+	ErrUnknownInstance = errors.New("unknown mpv instance")
+	ErrNoInstance      = errors.New("no mpv instance available")
+)
+
+type Instance struct {
+	ID         string `json:"id"`
+	SocketPath string `json:"socket_path"`
+	Title      string `json:"title"`
+	Active     bool   `json:"active"`
+}
+
+type InstanceRegistry interface {
+	Instances() []Instance
+	Player(id string) (Player, error)
+	NowPlaying(id string) (NowPlayingSubscriber, error)
+	SetActive(id string) error
+}
+
+// instance owns its client and, once anyone asks for it, a now-playing
+// tracker that lives as long as the instance does.
+type instance struct {
+	client  *Client
+	title   string
+	tracker *Tracker
+}
+
+func (inst *instance) close() {
+	if inst.tracker != nil {
+		inst.tracker.Close()
+	}
+	inst.client.Close()
+}
+
+type Registry struct {
+	patterns     []string
+	scanInterval time.Duration
+	mu           sync.Mutex
+	instances    map[string]*instance
+	active       string
+	nextID       int64
+	observed     map[int64]string
+	activeIDs    map[int64]int64
+	subscribers  map[chan Event]struct{}
+	stopForward  func()
+	started      bool
+	done         chan struct{}
+	stopped      chan struct{}
+}
+
+func NewRegistry(patterns ...string) *Registry {
+	return &Registry{
+		patterns:     patterns,
+		scanInterval: defaultScanInterval,
+		instances:    make(map[string]*instance),
+		observed:     make(map[int64]string),
+		activeIDs:    make(map[int64]int64),
+		subscribers:  make(map[chan Event]struct{}),
+		done:         make(chan struct{}),
+		stopped:      make(chan struct{}),
+	}
+}
+
+func SocketGlob() string {
+	if glob := os.Getenv(globEnvName); glob != "" {
+		return glob
+	}
+	return defaultSocketGlob
+}
+
+func instanceID(socketPath string) string {
+	base := filepath.Base(socketPath)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+func (r *Registry) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.started {
+		return
+	}
+	r.started = true
+	go r.run()
+}
+
+func (r *Registry) Close() {
+	r.mu.Lock()
+	select {
+	case <-r.done:
+		r.mu.Unlock()
+		return
+	default:
+	}
+	close(r.done)
+	started := r.started
+	r.mu.Unlock()
+	if started {
+		<-r.stopped
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopForward != nil {
+		r.stopForward()
+		r.stopForward = nil
+	}
+	for id, inst := range r.instances {
+		inst.close()
+		delete(r.instances, id)
+	}
+	for ch := range r.subscribers {
+		delete(r.subscribers, ch)
+		close(ch)
+	}
+}
+
+func (r *Registry) run() {
+	defer close(r.stopped)
+	r.Scan()
+	ticker := time.NewTicker(r.scanInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+			r.Scan()
+		}
+	}
+}
+
+func (r *Registry) Scan() {
+	paths := make(map[string]string)
+	for _, pattern := range r.patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			continue
+		}
+		for _, path := range matches {
+			paths[instanceID(path)] = path
+		}
+	}
+
+	r.mu.Lock()
+	candidates := make(map[string]*instance, len(paths))
+	for id, path := range paths {
+		inst, ok := r.instances[id]
+		if !ok {
+			inst = &instance{client: NewClient(path)}
+		}
+		candidates[id] = inst
+	}
+	for id, inst := range r.instances {
+		if _, ok := candidates[id]; !ok {
+			candidates[id] = inst
+		}
+	}
+	r.mu.Unlock()
+
+	alive := make(map[string]string)
+	for id, inst := range candidates {
+		if title, ok := probe(inst.client); ok {
+			alive[id] = title
+		}
+	}
+
+	r.mu.Lock()
+	var dead []*instance
+	for id, inst := range candidates {
+		title, ok := alive[id]
+		if !ok {
+			dead = append(dead, inst)
+			delete(r.instances, id)
+			continue
+		}
+		inst.title = title
+		r.instances[id] = inst
+	}
+	if _, ok := r.instances[r.active]; !ok {
+		r.activate(r.firstInstanceID())
+	}
+	r.mu.Unlock()
+
+	for _, inst := range dead {
+		inst.close()
+	}
+}
+
+func probe(client *Client) (string, bool) {
+	var pid int
+	if err := client.GetProperty("pid", &pid); err != nil {
+		return "", false
+	}
+	var title string
+	client.GetProperty("media-title", &title)
+	return title, true
+}
+
+func (r *Registry) firstInstanceID() string {
+	ids := make([]string, 0, len(r.instances))
+	for id := range r.instances {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	if len(ids) == 0 {
+		return ""
+	}
+	return ids[0]
+}
+
+func (r *Registry) Instances() []Instance {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	instances := make([]Instance, 0, len(r.instances))
+	for id, inst := range r.instances {
+		instances = append(instances, Instance{
+			ID:         id,
+			SocketPath: inst.client.socketPath,
+			Title:      inst.title,
+			Active:     id == r.active,
+		})
+	}
+	slices.SortFunc(instances, func(a, b Instance) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return instances
+}
+
+func (r *Registry) Player(id string) (Player, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id == "" {
+		id = r.active
+	}
+	inst, ok := r.instances[id]
+	if !ok {
+		if id == "" {
+			return nil, ErrNoInstance
+		}
+		return nil, ErrUnknownInstance
+	}
+	return inst.client, nil
+}
+
+// NowPlaying returns the tracker for an instance, starting it on first use
+// so every stream watching that instance shares one set of observers.
+func (r *Registry) NowPlaying(id string) (NowPlayingSubscriber, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id == "" {
+		id = r.active
+	}
+	inst, ok := r.instances[id]
+	if !ok {
+		if id == "" {
+			return nil, ErrNoInstance
+		}
+		return nil, ErrUnknownInstance
+	}
+	if inst.tracker == nil {
+		inst.tracker = NewTracker(inst.client)
+		inst.tracker.Start()
+	}
+	return inst.tracker, nil
+}
+
+func (r *Registry) SetActive(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.instances[id]; !ok {
+		return ErrUnknownInstance
+	}
+	if id != r.active {
+		r.activate(id)
+	}
+	return nil
+}
+
+func (r *Registry) activate(id string) {
+	if previous, ok := r.instances[r.active]; ok {
+		for _, clientID := range r.activeIDs {
+			go previous.client.UnobserveProperty(clientID)
+		}
+	}
+	if r.stopForward != nil {
+		r.stopForward()
+		r.stopForward = nil
+	}
+	r.activeIDs = make(map[int64]int64)
+	r.active = id
+
+	inst, ok := r.instances[id]
+	if !ok {
+		return
+	}
+	events, unsubscribe := inst.client.Subscribe()
+	r.stopForward = unsubscribe
+	go r.forward(id, events)
+
+	observed := make(map[int64]string, len(r.observed))
+	for registryID, name := range r.observed {
+		observed[registryID] = name
+	}
+	go r.observeActive(id, inst.client, observed)
+}
+
+func (r *Registry) observeActive(id string, client *Client, observed map[int64]string) {
+	for registryID, name := range observed {
+		clientID, err := client.ObserveProperty(name)
+		if err != nil {
+			continue
+		}
+		r.mu.Lock()
+		if r.active == id {
+			r.activeIDs[registryID] = clientID
+		} else {
+			go client.UnobserveProperty(clientID)
+		}
+		r.mu.Unlock()
+	}
+}
+
+func (r *Registry) forward(id string, events <-chan Event) {
+	for event := range events {
+		r.mu.Lock()
+		if r.active != id {
+			r.mu.Unlock()
+			continue
+		}
+		for ch := range r.subscribers {
+			select {
+			case ch <- event:
+			default:
+			}
+		}
+		r.mu.Unlock()
+	}
+}
+
+func (r *Registry) activePlayer() (*Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	inst, ok := r.instances[r.active]
+	if !ok {
+		return nil, ErrNoInstance
+	}
+	return inst.client, nil
+}
+
+func (r *Registry) Command(args ...any) (json.RawMessage, error) {
+	client, err := r.activePlayer()
+	if err != nil {
+		return nil, err
+	}
+	return client.Command(args...)
+}
+
+func (r *Registry) GetProperty(name string, value any) error {
+	client, err := r.activePlayer()
+	if err != nil {
+		return err
+	}
+	return client.GetProperty(name, value)
+}
+
+func (r *Registry) SetProperty(name string, value any) error {
+	client, err := r.activePlayer()
+	if err != nil {
+		return err
+	}
+	return client.SetProperty(name, value)
+}
+
+func (r *Registry) ObserveProperty(name string) (int64, error) {
+	r.mu.Lock()
+	r.nextID++
+	registryID := r.nextID
+	r.observed[registryID] = name
+	active := r.active
+	inst, ok := r.instances[active]
+	r.mu.Unlock()
+
+	if ok {
+		go r.observeActive(active, inst.client, map[int64]string{registryID: name})
+	}
+	return registryID, nil
+}
+
+func (r *Registry) UnobserveProperty(id int64) error {
+	r.mu.Lock()
+	delete(r.observed, id)
+	clientID, ok := r.activeIDs[id]
+	delete(r.activeIDs, id)
+	inst, active := r.instances[r.active]
+	r.mu.Unlock()
+
+	if ok && active {
+		return inst.client.UnobserveProperty(clientID)
+	}
+	return nil
+}
+
+func (r *Registry) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBufferSize)
+	r.mu.Lock()
+	r.subscribers[ch] = struct{}{}
+	r.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			if _, ok := r.subscribers[ch]; ok {
+				delete(r.subscribers, ch)
+				close(ch)
+			}
+		})
+	}
+	return ch, unsubscribe
+}
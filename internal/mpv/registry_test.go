@@ -0,0 +1,181 @@
+package mpv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phasecurve/sway_rm/internal/mpv/mpvtest"
+)
+
+func createTestRegistry(t *testing.T, names ...string) (*Registry, map[string]*mpvtest.Server) {
+	dir := mpvtest.TempDir(t)
+	fakes := make(map[string]*mpvtest.Server)
+	for _, name := range names {
+		fakes[name] = mpvtest.NewServerAt(t, filepath.Join(dir, name+".sock"))
+	}
+	registry := NewRegistry(filepath.Join(dir, "mpv-*.sock"))
+	t.Cleanup(registry.Close)
+	return registry, fakes
+}
+
+func TestRegistry_Scan_FindsLiveInstances(t *testing.T) {
+	registry, fakes := createTestRegistry(t, "mpv-eDP-1", "mpv-HDMI-A-1")
+	fakes["mpv-eDP-1"].SetProperty("media-title", "Big Buck Bunny")
+
+	registry.Scan()
+
+	instances := registry.Instances()
+	assert.Len(t, instances, 2)
+	assert.Equal(t, "mpv-HDMI-A-1", instances[0].ID, "instances should be sorted by id")
+	assert.True(t, instances[0].Active, "first instance should become active")
+	assert.Equal(t, "Big Buck Bunny", instances[1].Title, "instances should report their media title")
+	assert.Equal(t, fakes["mpv-eDP-1"].SocketPath, instances[1].SocketPath)
+}
+
+func TestRegistry_Scan_IgnoresStaleSocketFiles(t *testing.T) {
+	registry, _ := createTestRegistry(t)
+	dir := filepath.Dir(registry.patterns[0])
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "mpv-stale.sock"), nil, 0600))
+
+	registry.Scan()
+
+	assert.Empty(t, registry.Instances(), "sockets nobody listens on should not be registered")
+}
+
+func TestRegistry_Scan_PrunesDeadInstances(t *testing.T) {
+	registry, fakes := createTestRegistry(t, "mpv-a", "mpv-b")
+	registry.Scan()
+	assert.NoError(t, registry.SetActive("mpv-a"))
+
+	fakes["mpv-a"].Close()
+	registry.Scan()
+
+	instances := registry.Instances()
+	assert.Len(t, instances, 1, "dead instance should be pruned")
+	assert.Equal(t, "mpv-b", instances[0].ID)
+	assert.True(t, instances[0].Active, "active target should move to a live instance")
+}
+
+func TestRegistry_SetActive_UnknownInstance_ReturnsError(t *testing.T) {
+	registry, _ := createTestRegistry(t, "mpv-a")
+	registry.Scan()
+
+	err := registry.SetActive("mpv-missing")
+
+	assert.ErrorIs(t, err, ErrUnknownInstance)
+}
+
+func TestRegistry_Command_GoesToActiveInstance(t *testing.T) {
+	registry, fakes := createTestRegistry(t, "mpv-a", "mpv-b")
+	registry.Scan()
+
+	assert.NoError(t, registry.SetActive("mpv-b"))
+	_, err := registry.Command("stop")
+
+	assert.NoError(t, err)
+	assert.Contains(t, fakes["mpv-b"].Commands(), []any{"stop"}, "active instance should receive the command")
+	assert.NotContains(t, fakes["mpv-a"].Commands(), []any{"stop"}, "inactive instance should not receive the command")
+}
+
+func TestRegistry_Player_ReturnsRequestedInstance(t *testing.T) {
+	registry, fakes := createTestRegistry(t, "mpv-a", "mpv-b")
+	registry.Scan()
+
+	player, err := registry.Player("mpv-b")
+	assert.NoError(t, err)
+	_, err = player.Command("stop")
+
+	assert.NoError(t, err)
+	assert.Contains(t, fakes["mpv-b"].Commands(), []any{"stop"})
+	_, err = registry.Player("mpv-missing")
+	assert.ErrorIs(t, err, ErrUnknownInstance)
+}
+
+func TestRegistry_NowPlaying_SharedPerInstance(t *testing.T) {
+	registry, fakes := createTestRegistry(t, "mpv-a", "mpv-b")
+	registry.Scan()
+
+	first, err := registry.NowPlaying("mpv-b")
+	assert.NoError(t, err)
+	second, err := registry.NowPlaying("mpv-b")
+	assert.NoError(t, err)
+
+	assert.Same(t, first, second, "streams for the same instance should share a tracker")
+	assert.Eventually(t, func() bool { return fakes["mpv-b"].Observers("media-title") == 1 }, time.Second, 5*time.Millisecond,
+		"the shared tracker should observe the instance once")
+	_, err = registry.NowPlaying("mpv-missing")
+	assert.ErrorIs(t, err, ErrUnknownInstance)
+}
+
+func TestRegistry_PrunedInstance_ClosesTracker(t *testing.T) {
+	registry, fakes := createTestRegistry(t, "mpv-a", "mpv-b")
+	registry.Scan()
+	tracker, err := registry.NowPlaying("mpv-a")
+	assert.NoError(t, err)
+	states, unsubscribe := tracker.Subscribe()
+	defer unsubscribe()
+
+	fakes["mpv-a"].Close()
+	registry.Scan()
+
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-states:
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatal("tracker of a pruned instance should be closed")
+		}
+	}
+}
+
+func TestRegistry_NoInstances_ReturnsErrNoInstance(t *testing.T) {
+	registry, _ := createTestRegistry(t)
+	registry.Scan()
+
+	_, err := registry.Command("stop")
+
+	assert.ErrorIs(t, err, ErrNoInstance)
+}
+
+func TestRegistry_SwitchingActive_ForwardsNewInstanceProperties(t *testing.T) {
+	registry, fakes := createTestRegistry(t, "mpv-a", "mpv-b")
+	fakes["mpv-b"].SetProperty("media-title", "Sintel")
+	registry.Scan()
+	events, unsubscribe := registry.Subscribe()
+	defer unsubscribe()
+	_, err := registry.ObserveProperty("media-title")
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return fakes["mpv-a"].Observers("media-title") == 1 }, time.Second, 5*time.Millisecond)
+
+	assert.NoError(t, registry.SetActive("mpv-b"))
+
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case event := <-events:
+			if string(event.Data) == `"Sintel"` {
+				assert.Eventually(t, func() bool { return fakes["mpv-a"].Observers("media-title") == 0 }, time.Second, 5*time.Millisecond, "previous instance should be unobserved")
+				return
+			}
+		case <-deadline:
+			t.Fatal("did not receive properties from the newly active instance")
+		}
+	}
+}
+
+func TestRegistry_Start_ScansPeriodically(t *testing.T) {
+	registry, _ := createTestRegistry(t)
+	registry.scanInterval = 10 * time.Millisecond
+	registry.Start()
+
+	mpvtest.NewServerAt(t, filepath.Join(filepath.Dir(registry.patterns[0]), "mpv-late.sock"))
+
+	assert.Eventually(t, func() bool { return len(registry.Instances()) == 1 }, time.Second, 10*time.Millisecond, "new sockets should be discovered")
+}
@@ -0,0 +1,97 @@
+package publish
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/phasecurve/sway_rm/internal/dbus"
+)
+
+const (
+	notificationsName   = "org.freedesktop.Notifications"
+	notificationsPath   = dbus.ObjectPath("/org/freedesktop/Notifications")
+	notificationAppName = "Sway RM"
+	urgencyNormal       = byte(1)
+	urgencyCritical     = byte(2)
+)
+
+// Desktop shows codes as freedesktop notifications. Each new code replaces
+// the previous notification rather than stacking up.
+type Desktop struct {
+	address string
+	mu      sync.Mutex
+	lastID  uint32
+}
+
+func NewDesktop(address string) *Desktop {
+	return &Desktop{address: address}
+}
+
+func (d *Desktop) PublishCode(code Code) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	body := fmt.Sprintf("Expires at %s", code.Expiry.Format(time.Kitchen))
+	if code.Link != "" {
+		body += "\n" + code.Link
+	}
+	id, err := d.notify(d.lastID, "Pairing code: "+code.Code, body, urgencyNormal, expireTimeout(code.ExpiresIn))
+	if err != nil {
+		return fmt.Errorf("failed to publish pairing code notification: %w", err)
+	}
+	d.lastID = id
+	return nil
+}
+
+// PublishRequest shows a separate, critical notification so it isn't
+// replaced by the next code and stays up until dismissed.
+func (d *Desktop) PublishRequest(request Request) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, err := d.notify(0, request.summary(), request.instructions(), urgencyCritical, -1); err != nil {
+		return fmt.Errorf("failed to publish pairing request notification: %w", err)
+	}
+	return nil
+}
+
+func (d *Desktop) notify(replacesID uint32, summary, body string, urgency byte, timeout int32) (uint32, error) {
+	conn, err := dbus.Dial(d.address)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	hints := map[string]dbus.Variant{
+		"urgency": {Signature: "y", Value: urgency},
+	}
+	reply, err := conn.Call(notificationsName, notificationsPath, notificationsName, "Notify", "susssasa{sv}i",
+		notificationAppName,
+		replacesID,
+		"",
+		summary,
+		body,
+		[]string{},
+		hints,
+		timeout,
+	)
+	if err != nil {
+		return 0, err
+	}
+	var id uint32
+	if len(reply) == 1 {
+		id, _ = reply[0].(uint32)
+	}
+	return id, nil
+}
+
+// expireTimeout converts a lifetime to milliseconds, where -1 leaves the
+// timeout to the notification server.
+func expireTimeout(lifetime time.Duration) int32 {
+	if lifetime <= 0 {
+		return -1
+	}
+	return int32(min(lifetime.Milliseconds(), math.MaxInt32))
+}
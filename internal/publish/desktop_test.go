@@ -0,0 +1,79 @@
+package publish
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phasecurve/sway_rm/internal/dbus"
+	"github.com/phasecurve/sway_rm/internal/dbus/dbustest"
+)
+
+func createNotificationBus(t *testing.T) *dbustest.Bus {
+	bus := dbustest.NewBus(t)
+	nextID := uint32(40)
+	bus.Handle("org.freedesktop.Notifications", "Notify", func(call *dbus.Message) (dbus.Signature, []any, error) {
+		nextID++
+		return "u", []any{nextID}, nil
+	})
+	return bus
+}
+
+func TestDesktop_SendsNotification(t *testing.T) {
+	bus := createNotificationBus(t)
+
+	err := NewDesktop(bus.Address()).PublishCode(testCode)
+
+	assert.NoError(t, err)
+	calls := bus.Calls()
+	if !assert.Len(t, calls, 1) {
+		return
+	}
+	call := calls[0]
+	assert.Equal(t, "org.freedesktop.Notifications", call.Destination)
+	assert.Equal(t, dbus.ObjectPath("/org/freedesktop/Notifications"), call.Path)
+	assert.Equal(t, dbus.Signature("susssasa{sv}i"), call.Signature)
+	assert.Equal(t, "Sway RM", call.Body[0], "app name")
+	assert.Equal(t, uint32(0), call.Body[1], "first notification should not replace anything")
+	assert.Equal(t, "Pairing code: ABC234", call.Body[3], "summary")
+	assert.Equal(t, "Expires at 8:05PM\n"+testCode.Link, call.Body[4], "body")
+	assert.Equal(t, int32(300000), call.Body[7], "notification should expire with the code")
+}
+
+func TestDesktop_NewCode_ReplacesPreviousNotification(t *testing.T) {
+	bus := createNotificationBus(t)
+	desktop := NewDesktop(bus.Address())
+
+	assert.NoError(t, desktop.PublishCode(testCode))
+	assert.NoError(t, desktop.PublishCode(testCode))
+
+	calls := bus.Calls()
+	if assert.Len(t, calls, 2) {
+		assert.Equal(t, uint32(41), calls[1].Body[1], "second notification should replace the first")
+	}
+}
+
+func TestDesktop_NotificationServiceError_ReturnsError(t *testing.T) {
+	bus := dbustest.NewBus(t)
+	bus.Handle("org.freedesktop.Notifications", "Notify", func(call *dbus.Message) (dbus.Signature, []any, error) {
+		return "", nil, &dbus.Error{Name: "org.freedesktop.DBus.Error.ServiceUnknown", Message: "no notification daemon"}
+	})
+
+	err := NewDesktop(bus.Address()).PublishCode(testCode)
+
+	assert.ErrorContains(t, err, "no notification daemon")
+}
+
+func TestDesktop_NoBus_ReturnsError(t *testing.T) {
+	err := NewDesktop("unix:path=" + t.TempDir() + "/missing").PublishCode(testCode)
+
+	assert.ErrorContains(t, err, "failed to publish pairing code notification")
+}
+
+func TestExpireTimeout(t *testing.T) {
+	assert.Equal(t, int32(-1), expireTimeout(0), "unknown lifetime should use the server default")
+	assert.Equal(t, int32(-1), expireTimeout(-time.Second))
+	assert.Equal(t, int32(1500), expireTimeout(1500*time.Millisecond))
+	assert.Equal(t, int32(2147483647), expireTimeout(1000*time.Hour), "should clamp to int32")
+}
@@ -0,0 +1,40 @@
+package publish
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const execTimeout = 10 * time.Second
+
+// Exec runs a command for each code, passing it in the environment as
+// SWAY_RM_PAIRING_CODE, SWAY_RM_PAIRING_LINK and SWAY_RM_PAIRING_EXPIRY
+// (RFC 3339).
+type Exec struct {
+	command []string
+	timeout time.Duration
+}
+
+func NewExec(command ...string) *Exec {
+	return &Exec{command: command, timeout: execTimeout}
+}
+
+func (e *Exec) PublishCode(code Code) error {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, e.command[0], e.command[1:]...)
+	cmd.Env = append(os.Environ(),
+		"SWAY_RM_PAIRING_CODE="+code.Code,
+		"SWAY_RM_PAIRING_LINK="+code.Link,
+		"SWAY_RM_PAIRING_EXPIRY="+code.Expiry.Format(time.RFC3339),
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("pairing code hook %s failed: %w: %s", e.command[0], err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
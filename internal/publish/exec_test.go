@@ -0,0 +1,39 @@
+package publish
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExec_PassesCodeInEnvironment(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "hook-output")
+	script := `printf '%s|%s|%s' "$SWAY_RM_PAIRING_CODE" "$SWAY_RM_PAIRING_LINK" "$SWAY_RM_PAIRING_EXPIRY" > "$0"`
+
+	err := NewExec("sh", "-c", script, out).PublishCode(testCode)
+
+	assert.NoError(t, err)
+	content, _ := os.ReadFile(out)
+	assert.Equal(t, "ABC234|"+testCode.Link+"|2025-06-01T20:05:00Z", string(content))
+}
+
+func TestExec_CommandFails_ReturnsOutput(t *testing.T) {
+	err := NewExec("sh", "-c", "echo no display >&2; exit 3").PublishCode(testCode)
+
+	assert.ErrorContains(t, err, "pairing code hook sh failed")
+	assert.ErrorContains(t, err, "no display")
+}
+
+func TestExec_CommandHangs_TimesOut(t *testing.T) {
+	hook := NewExec("sleep", "10")
+	hook.timeout = 50 * time.Millisecond
+
+	start := time.Now()
+	err := hook.PublishCode(testCode)
+
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second, "a hung hook should not block pairing")
+}
@@ -0,0 +1,65 @@
+package publish
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// File writes the code, and the link if any, one per line. A FIFO gets the
+// lines written to whoever is reading it; a regular file is replaced
+// atomically and kept private.
+type File struct {
+	path string
+}
+
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+func (f *File) PublishCode(code Code) error {
+	content := code.Code + "\n"
+	if code.Link != "" {
+		content += code.Link + "\n"
+	}
+	if info, err := os.Stat(f.path); err == nil && info.Mode()&os.ModeNamedPipe != 0 {
+		return f.writeFIFO(content)
+	}
+	return f.writeFile(content)
+}
+
+func (f *File) writeFIFO(content string) error {
+	fifo, err := os.OpenFile(f.path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if errors.Is(err, syscall.ENXIO) {
+		return fmt.Errorf("no reader on pairing code fifo %s", f.path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open pairing code fifo: %w", err)
+	}
+	defer fifo.Close()
+	if _, err := fifo.WriteString(content); err != nil {
+		return fmt.Errorf("failed to write pairing code fifo: %w", err)
+	}
+	return nil
+}
+
+func (f *File) writeFile(content string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), "."+filepath.Base(f.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write pairing code file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write pairing code file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write pairing code file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("failed to write pairing code file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package publish
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFile_RegularFile_WritesCodeAndLink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pairing-code")
+
+	err := NewFile(path).PublishCode(testCode)
+
+	assert.NoError(t, err)
+	content, _ := os.ReadFile(path)
+	assert.Equal(t, "ABC234\n"+testCode.Link+"\n", string(content))
+	info, _ := os.Stat(path)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "code file should be private")
+}
+
+func TestFile_RegularFile_ReplacesPreviousCode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pairing-code")
+	file := NewFile(path)
+	assert.NoError(t, file.PublishCode(testCode))
+
+	assert.NoError(t, file.PublishCode(Code{Code: "XYZ789"}))
+
+	content, _ := os.ReadFile(path)
+	assert.Equal(t, "XYZ789\n", string(content))
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	assert.Len(t, entries, 1, "temporary files should be cleaned up")
+}
+
+func TestFile_MissingDirectory_ReturnsError(t *testing.T) {
+	err := NewFile(filepath.Join(t.TempDir(), "missing", "pairing-code")).PublishCode(testCode)
+
+	assert.ErrorContains(t, err, "failed to write pairing code file")
+}
+
+func TestFile_FIFO_WritesToReader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pairing-fifo")
+	assert.NoError(t, syscall.Mkfifo(path, 0600))
+	reader, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	assert.NoError(t, err)
+	defer reader.Close()
+
+	err = NewFile(path).PublishCode(testCode)
+
+	assert.NoError(t, err)
+	line, _ := bufio.NewReader(reader).ReadString('\n')
+	assert.Equal(t, "ABC234\n", line)
+	info, _ := os.Stat(path)
+	assert.NotZero(t, info.Mode()&os.ModeNamedPipe, "the fifo should not be replaced by a file")
+}
+
+func TestFile_FIFOWithoutReader_ReturnsError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pairing-fifo")
+	assert.NoError(t, syscall.Mkfifo(path, 0600))
+
+	err := NewFile(path).PublishCode(testCode)
+
+	assert.ErrorContains(t, err, "no reader on pairing code fifo")
+}
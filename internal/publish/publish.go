@@ -0,0 +1,100 @@
+// Package publish delivers pairing codes to wherever the person at the
+// desktop will see them.
+package publish
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/phasecurve/sway_rm/internal/dbus"
+)
+
+const (
+	specEnvName = "PAIRING_PUBLISHERS"
+	defaultSpec = "stdout"
+)
+
+// Code is a freshly issued pairing code. Link is empty when QR pairing is
+// not configured.
+type Code struct {
+	Code      string
+	Link      string
+	Expiry    time.Time
+	ExpiresIn time.Duration
+}
+
+type CodePublisher interface {
+	PublishCode(code Code) error
+}
+
+type multi []CodePublisher
+
+// All publishes to every publisher, even if some fail.
+func All(publishers ...CodePublisher) CodePublisher {
+	if len(publishers) == 1 {
+		return publishers[0]
+	}
+	return multi(publishers)
+}
+
+func (m multi) PublishCode(code Code) error {
+	var errs []error
+	for _, publisher := range m {
+		if err := publisher.PublishCode(code); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Spec returns the publisher list from $PAIRING_PUBLISHERS, defaulting to
+// the terminal.
+func Spec() string {
+	if spec := os.Getenv(specEnvName); spec != "" {
+		return spec
+	}
+	return defaultSpec
+}
+
+// Parse builds publishers from a comma separated spec. Entries are
+// "stdout", "notify", "file:<path>" and "exec:<command> [args...]". An exec
+// entry takes the rest of the spec, commas included, so it has to come last.
+func Parse(spec string, stdout io.Writer) ([]CodePublisher, error) {
+	var publishers []CodePublisher
+	rest := spec
+	for {
+		entry, tail, more := strings.Cut(rest, ",")
+		entry = strings.TrimSpace(entry)
+		if strings.HasPrefix(entry, "exec:") {
+			entry, more = strings.TrimSpace(rest), false
+		}
+		kind, arg, _ := strings.Cut(entry, ":")
+		switch kind {
+		case "stdout":
+			publishers = append(publishers, NewTerminal(stdout))
+		case "notify":
+			publishers = append(publishers, NewDesktop(dbus.SessionBusAddress()))
+		case "file":
+			if arg == "" {
+				return nil, fmt.Errorf("publisher %q needs a path", entry)
+			}
+			publishers = append(publishers, NewFile(arg))
+		case "exec":
+			command := strings.Fields(arg)
+			if len(command) == 0 {
+				return nil, fmt.Errorf("publisher %q needs a command", entry)
+			}
+			publishers = append(publishers, NewExec(command...))
+		default:
+			return nil, fmt.Errorf("unknown pairing code publisher %q", entry)
+		}
+		if !more {
+			return publishers, nil
+		}
+		rest = tail
+	}
+}
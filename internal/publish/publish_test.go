@@ -0,0 +1,141 @@
+package publish
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testCode = Code{
+	Code:      "ABC234",
+	Link:      "http://192.168.1.20:8080/pair?token=tok",
+	Expiry:    time.Date(2025, 6, 1, 20, 5, 0, 0, time.UTC),
+	ExpiresIn: 5 * time.Minute,
+}
+
+type recordingPublisher struct {
+	codes []Code
+	err   error
+}
+
+func (r *recordingPublisher) PublishCode(code Code) error {
+	r.codes = append(r.codes, code)
+	return r.err
+}
+
+func TestAll_PublishesToEveryPublisherDespiteFailures(t *testing.T) {
+	failing := &recordingPublisher{err: errors.New("bus unavailable")}
+	working := &recordingPublisher{}
+
+	err := All(failing, working).PublishCode(testCode)
+
+	assert.ErrorContains(t, err, "bus unavailable")
+	assert.Equal(t, []Code{testCode}, failing.codes)
+	assert.Equal(t, []Code{testCode}, working.codes, "a failing publisher should not stop the others")
+}
+
+func TestAll_SinglePublisher_ReturnedAsIs(t *testing.T) {
+	only := &recordingPublisher{}
+
+	assert.Same(t, only, All(only))
+}
+
+func TestParse_BuildsPublishers(t *testing.T) {
+	var stdout bytes.Buffer
+
+	publishers, err := Parse("stdout, notify,file:/run/user/1000/sway-rm-code,exec:notify-send -u critical", &stdout)
+
+	assert.NoError(t, err)
+	if assert.Len(t, publishers, 4) {
+		assert.IsType(t, &Terminal{}, publishers[0])
+		assert.IsType(t, &Desktop{}, publishers[1])
+		assert.Equal(t, NewFile("/run/user/1000/sway-rm-code"), publishers[2])
+		assert.Equal(t, []string{"notify-send", "-u", "critical"}, publishers[3].(*Exec).command)
+	}
+}
+
+func TestParse_ExecWithCommas_TakesRestOfSpec(t *testing.T) {
+	publishers, err := Parse("stdout,exec:notify-send -h string:x-canonical-private-synchronous:a,b Pairing", &bytes.Buffer{})
+
+	assert.NoError(t, err)
+	if assert.Len(t, publishers, 2) {
+		assert.IsType(t, &Terminal{}, publishers[0])
+		assert.Equal(t, []string{"notify-send", "-h", "string:x-canonical-private-synchronous:a,b", "Pairing"}, publishers[1].(*Exec).command,
+			"commas after exec: belong to the command")
+	}
+}
+
+func TestParse_InvalidEntries_ReturnError(t *testing.T) {
+	tests := []struct {
+		spec    string
+		message string
+	}{
+		{"stdout,carrier-pigeon", `unknown pairing code publisher "carrier-pigeon"`},
+		{"file:", `publisher "file:" needs a path`},
+		{"exec:  ", `publisher "exec:" needs a command`},
+		{"", `unknown pairing code publisher ""`},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.spec, &bytes.Buffer{})
+
+		assert.ErrorContains(t, err, tt.message, tt.spec)
+	}
+}
+
+func TestSpec_DefaultsToStdout(t *testing.T) {
+	t.Setenv("PAIRING_PUBLISHERS", "")
+
+	assert.Equal(t, "stdout", Spec())
+}
+
+func TestSpec_ReadsEnvironment(t *testing.T) {
+	t.Setenv("PAIRING_PUBLISHERS", "notify,stdout")
+
+	assert.Equal(t, "notify,stdout", Spec())
+}
+
+func TestTerminal_PrintsBoxAndQRCode(t *testing.T) {
+	var out bytes.Buffer
+
+	err := NewTerminal(&out).PublishCode(testCode)
+
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "Pairing code: ABC234")
+	assert.Contains(t, out.String(), "█", "should render the QR code")
+	assert.Contains(t, out.String(), testCode.Link)
+}
+
+func TestTerminal_LongCode_WidensBox(t *testing.T) {
+	var out bytes.Buffer
+	code := strings.Repeat("ABCDEFGH", 4)
+
+	err := NewTerminal(&out).PublishCode(Code{Code: code})
+
+	assert.NoError(t, err)
+	var widths []int
+	for _, line := range strings.Split(out.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			widths = append(widths, utf8.RuneCountInString(line))
+		}
+	}
+	if assert.Len(t, widths, 3) {
+		assert.Equal(t, widths[0], widths[1], "the code line should fit the box")
+		assert.Equal(t, widths[0], widths[2])
+	}
+	assert.Contains(t, out.String(), "║  Pairing code: "+code+"  ║")
+}
+
+func TestTerminal_NoLink_PrintsCodeOnly(t *testing.T) {
+	var out bytes.Buffer
+
+	err := NewTerminal(&out).PublishCode(Code{Code: "ABC234"})
+
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "Pairing code: ABC234")
+	assert.NotContains(t, out.String(), "scan")
+}
@@ -0,0 +1,51 @@
+package publish
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Request is a device asking to pair without a code. ReviewURL, when set,
+// is where the desktop user can approve or deny it.
+type Request struct {
+	DeviceName string
+	RemoteIP   string
+	CheckCode  string
+	ReviewURL  string
+	Expiry     time.Time
+}
+
+// RequestPublisher is implemented by publishers that can also announce
+// pairing requests waiting for approval.
+type RequestPublisher interface {
+	PublishRequest(request Request) error
+}
+
+func (m multi) PublishRequest(request Request) error {
+	var errs []error
+	for _, publisher := range m {
+		if rp, ok := publisher.(RequestPublisher); ok {
+			if err := rp.PublishRequest(request); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r Request) summary() string {
+	name := r.DeviceName
+	if name == "" {
+		name = "An unnamed device"
+	}
+	return fmt.Sprintf("%s at %s wants to pair", name, r.RemoteIP)
+}
+
+func (r Request) instructions() string {
+	where := "/pair/requests on this computer"
+	if r.ReviewURL != "" {
+		where = r.ReviewURL
+	}
+	return fmt.Sprintf("Check code %s. Approve or deny at %s", r.CheckCode, where)
+}
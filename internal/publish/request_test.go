@@ -0,0 +1,80 @@
+package publish
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testRequest = Request{
+	DeviceName: "Guest phone",
+	RemoteIP:   "192.168.1.30",
+	CheckCode:  "XYZ789",
+	ReviewURL:  "http://localhost:8080/pair/requests",
+	Expiry:     time.Date(2025, 6, 1, 20, 2, 0, 0, time.UTC),
+}
+
+type recordingRequestPublisher struct {
+	recordingPublisher
+	requests []Request
+	err      error
+}
+
+func (r *recordingRequestPublisher) PublishRequest(request Request) error {
+	r.requests = append(r.requests, request)
+	return r.err
+}
+
+func TestAll_PublishRequest_OnlyToRequestPublishers(t *testing.T) {
+	failing := &recordingRequestPublisher{err: errors.New("bus unavailable")}
+	working := &recordingRequestPublisher{}
+	codesOnly := &recordingPublisher{}
+
+	publisher, ok := All(failing, codesOnly, working).(RequestPublisher)
+
+	assert.True(t, ok)
+	assert.ErrorContains(t, publisher.PublishRequest(testRequest), "bus unavailable")
+	assert.Equal(t, []Request{testRequest}, working.requests, "a failing publisher should not stop the others")
+	assert.Empty(t, codesOnly.codes)
+}
+
+func TestTerminal_PublishRequest_PrintsReviewLink(t *testing.T) {
+	var out bytes.Buffer
+
+	assert.NoError(t, NewTerminal(&out).PublishRequest(testRequest))
+
+	assert.Contains(t, out.String(), "Guest phone at 192.168.1.30 wants to pair")
+	assert.Contains(t, out.String(), "Check code XYZ789. Approve or deny at http://localhost:8080/pair/requests")
+}
+
+func TestTerminal_PublishRequest_NoNameOrURL(t *testing.T) {
+	var out bytes.Buffer
+
+	assert.NoError(t, NewTerminal(&out).PublishRequest(Request{RemoteIP: "192.168.1.30", CheckCode: "XYZ789"}))
+
+	assert.Contains(t, out.String(), "An unnamed device at 192.168.1.30 wants to pair")
+	assert.Contains(t, out.String(), "/pair/requests on this computer")
+}
+
+func TestDesktop_PublishRequest_SendsCriticalNotification(t *testing.T) {
+	bus := createNotificationBus(t)
+	desktop := NewDesktop(bus.Address())
+	assert.NoError(t, desktop.PublishCode(testCode))
+
+	assert.NoError(t, desktop.PublishRequest(testRequest))
+	assert.NoError(t, desktop.PublishCode(testCode))
+
+	calls := bus.Calls()
+	if !assert.Len(t, calls, 3) {
+		return
+	}
+	request := calls[1]
+	assert.Equal(t, uint32(0), request.Body[1], "requests should not replace the code notification")
+	assert.Equal(t, "Guest phone at 192.168.1.30 wants to pair", request.Body[3])
+	assert.Equal(t, "Check code XYZ789. Approve or deny at http://localhost:8080/pair/requests", request.Body[4])
+	assert.Equal(t, int32(-1), request.Body[7])
+	assert.Equal(t, uint32(41), calls[2].Body[1], "the next code should still replace the last code notification")
+}
@@ -0,0 +1,46 @@
+package publish
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/phasecurve/sway_rm/internal/qr"
+)
+
+type Terminal struct {
+	out io.Writer
+}
+
+func NewTerminal(out io.Writer) *Terminal {
+	return &Terminal{out: out}
+}
+
+func (t *Terminal) PublishCode(code Code) error {
+	label := "  Pairing code: " + code.Code + "  "
+	border := strings.Repeat("═", utf8.RuneCountInString(label))
+	if _, err := fmt.Fprintf(t.out, `
+
+		╔%s╗
+		║%s║
+		╚%s╝
+
+`, border, label, border); err != nil {
+		return err
+	}
+	if code.Link == "" {
+		return nil
+	}
+	symbol, err := qr.Encode([]byte(code.Link), qr.Medium)
+	if err != nil {
+		return fmt.Errorf("failed to encode pairing link: %w", err)
+	}
+	_, err = fmt.Fprintf(t.out, "Or scan to pair:\n\n%s\n%s\n\n", symbol.HalfBlocks(qr.QuietZone), code.Link)
+	return err
+}
+
+func (t *Terminal) PublishRequest(request Request) error {
+	_, err := fmt.Fprintf(t.out, "\n%s. %s\n\n", request.summary(), request.instructions())
+	return err
+}
@@ -0,0 +1,462 @@
+// Package qr encodes byte strings as QR codes (ISO/IEC 18004) in byte mode.
+// Only versions 1 to 10 are supported, which is plenty for pairing URLs.
+package qr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+type Level int
+
+const (
+	Low Level = iota
+	Medium
+	Quartile
+	High
+)
+
+const MaxVersion = 10
+
+var ErrDataTooLong = errors.New("data too long for a QR code")
+
+// formatBits are the two error correction bits written into the format info.
+var formatBits = [...]uint{Low: 1, Medium: 0, Quartile: 3, High: 2}
+
+type blockLayout struct {
+	ecPerBlock int
+	groups     [2]struct{ blocks, dataCodewords int }
+}
+
+func layout(ecPerBlock, blocks1, data1, blocks2, data2 int) blockLayout {
+	l := blockLayout{ecPerBlock: ecPerBlock}
+	l.groups[0].blocks, l.groups[0].dataCodewords = blocks1, data1
+	l.groups[1].blocks, l.groups[1].dataCodewords = blocks2, data2
+	return l
+}
+
+func (l blockLayout) dataCodewords() int {
+	return l.groups[0].blocks*l.groups[0].dataCodewords + l.groups[1].blocks*l.groups[1].dataCodewords
+}
+
+// blockLayouts is indexed by version-1 then Level.
+var blockLayouts = [MaxVersion][4]blockLayout{
+	{layout(7, 1, 19, 0, 0), layout(10, 1, 16, 0, 0), layout(13, 1, 13, 0, 0), layout(17, 1, 9, 0, 0)},
+	{layout(10, 1, 34, 0, 0), layout(16, 1, 28, 0, 0), layout(22, 1, 22, 0, 0), layout(28, 1, 16, 0, 0)},
+	{layout(15, 1, 55, 0, 0), layout(26, 1, 44, 0, 0), layout(18, 2, 17, 0, 0), layout(22, 2, 13, 0, 0)},
+	{layout(20, 1, 80, 0, 0), layout(18, 2, 32, 0, 0), layout(26, 2, 24, 0, 0), layout(16, 4, 9, 0, 0)},
+	{layout(26, 1, 108, 0, 0), layout(24, 2, 43, 0, 0), layout(18, 2, 15, 2, 16), layout(22, 2, 11, 2, 12)},
+	{layout(18, 2, 68, 0, 0), layout(16, 4, 27, 0, 0), layout(24, 4, 19, 0, 0), layout(28, 4, 15, 0, 0)},
+	{layout(20, 2, 78, 0, 0), layout(18, 4, 31, 0, 0), layout(18, 2, 14, 4, 15), layout(26, 4, 13, 1, 14)},
+	{layout(24, 2, 97, 0, 0), layout(22, 2, 38, 2, 39), layout(22, 4, 18, 2, 19), layout(26, 4, 14, 2, 15)},
+	{layout(30, 2, 116, 0, 0), layout(22, 3, 36, 2, 37), layout(20, 4, 16, 4, 17), layout(24, 4, 12, 4, 13)},
+	{layout(18, 2, 68, 2, 69), layout(26, 4, 43, 1, 44), layout(24, 6, 19, 2, 20), layout(28, 6, 15, 2, 16)},
+}
+
+var alignmentPositions = [MaxVersion][]int{
+	nil,
+	{6, 18},
+	{6, 22},
+	{6, 26},
+	{6, 30},
+	{6, 34},
+	{6, 22, 38},
+	{6, 24, 42},
+	{6, 26, 46},
+	{6, 28, 50},
+}
+
+// Code is an encoded QR symbol. Modules are addressed by column x and row y
+// from the top-left corner; the quiet zone is not included.
+type Code struct {
+	Version int
+	Level   Level
+	Size    int
+	Mask    int
+
+	modules  []bool
+	function []bool
+}
+
+// Encode picks the smallest version that fits data at the given level.
+func Encode(data []byte, level Level) (*Code, error) {
+	if level < Low || level > High {
+		return nil, fmt.Errorf("invalid error correction level %d", level)
+	}
+	for version := 1; version <= MaxVersion; version++ {
+		if len(data) <= Capacity(version, level) {
+			return encode(data, version, level), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %d bytes", ErrDataTooLong, len(data))
+}
+
+// Capacity returns how many bytes fit in a symbol of the given version and level.
+func Capacity(version int, level Level) int {
+	bits := blockLayouts[version-1][level].dataCodewords()*8 - 4 - countBits(version)
+	return bits / 8
+}
+
+func (c *Code) Dark(x, y int) bool {
+	if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+		return false
+	}
+	return c.modules[y*c.Size+x]
+}
+
+func countBits(version int) int {
+	if version < 10 {
+		return 8
+	}
+	return 16
+}
+
+func encode(data []byte, version int, level Level) *Code {
+	size := version*4 + 17
+	c := &Code{
+		Version:  version,
+		Level:    level,
+		Size:     size,
+		modules:  make([]bool, size*size),
+		function: make([]bool, size*size),
+	}
+	c.drawFunctionPatterns()
+	c.drawCodewords(c.codewords(data))
+
+	best, bestPenalty := 0, math.MaxInt
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormatBits(mask)
+		if penalty := c.penalty(); penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		c.applyMask(mask)
+	}
+	c.Mask = best
+	c.applyMask(best)
+	c.drawFormatBits(best)
+	c.function = nil
+	return c
+}
+
+func (c *Code) set(x, y int, dark bool) {
+	c.modules[y*c.Size+x] = dark
+}
+
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.set(x, y, dark)
+	c.function[y*c.Size+x] = true
+}
+
+func (c *Code) isFunction(x, y int) bool {
+	return c.function[y*c.Size+x]
+}
+
+func (c *Code) drawFunctionPatterns() {
+	for i := 0; i < c.Size; i++ {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+	c.drawFinder(3, 3)
+	c.drawFinder(c.Size-4, 3)
+	c.drawFinder(3, c.Size-4)
+
+	positions := alignmentPositions[c.Version-1]
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			c.drawAlignment(x, y)
+		}
+	}
+
+	// Reserve the format areas now so codewords flow around them.
+	c.drawFormatBits(0)
+	c.drawVersionBits()
+}
+
+// drawFinder draws a finder pattern and its separator centred on (cx, cy).
+func (c *Code) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			c.setFunction(x, y, dist != 2 && dist != 4)
+		}
+	}
+}
+
+func (c *Code) drawAlignment(cx, cy int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+func (c *Code) drawFormatBits(mask int) {
+	bits := formatInfo(c.Level, mask)
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(i))
+	}
+	c.setFunction(8, 7, bit(6))
+	c.setFunction(8, 8, bit(7))
+	c.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		c.setFunction(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.Size-15+i, bit(i))
+	}
+	c.setFunction(8, c.Size-8, true)
+}
+
+func (c *Code) drawVersionBits() {
+	if c.Version < 7 {
+		return
+	}
+	bits := versionInfo(c.Version)
+	for i := 0; i < 18; i++ {
+		dark := bits>>i&1 == 1
+		a, b := c.Size-11+i%3, i/3
+		c.setFunction(a, b, dark)
+		c.setFunction(b, a, dark)
+	}
+}
+
+// formatInfo returns the 15-bit BCH protected format word for level and mask.
+func formatInfo(level Level, mask int) uint {
+	data := formatBits[level]<<3 | uint(mask)
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// versionInfo returns the 18-bit BCH protected version word.
+func versionInfo(version int) uint {
+	rem := uint(version)
+	for i := 0; i < 12; i++ {
+		rem = rem<<1 ^ (rem>>11)*0x1f25
+	}
+	return uint(version)<<12 | rem
+}
+
+// codewords builds the data codewords for data and interleaves them with
+// their error correction blocks.
+func (c *Code) codewords(data []byte) []byte {
+	l := blockLayouts[c.Version-1][c.Level]
+	capacity := l.dataCodewords() * 8
+
+	var w bitWriter
+	w.write(0b0100, 4)
+	w.write(uint(len(data)), countBits(c.Version))
+	for _, b := range data {
+		w.write(uint(b), 8)
+	}
+	w.write(0, min(4, capacity-w.len()))
+	w.write(0, (8-w.len()%8)%8)
+	for pad := uint(0xec); w.len() < capacity; pad ^= 0xec ^ 0x11 {
+		w.write(pad, 8)
+	}
+	bytes := w.bytes()
+
+	divisor := rsDivisor(l.ecPerBlock)
+	var dataBlocks, ecBlocks [][]byte
+	for _, group := range l.groups {
+		for i := 0; i < group.blocks; i++ {
+			block := bytes[:group.dataCodewords]
+			bytes = bytes[group.dataCodewords:]
+			dataBlocks = append(dataBlocks, block)
+			ecBlocks = append(ecBlocks, rsRemainder(block, divisor))
+		}
+	}
+
+	var out []byte
+	longest := l.groups[0].dataCodewords
+	if l.groups[1].blocks > 0 {
+		longest = l.groups[1].dataCodewords
+	}
+	for i := 0; i < longest; i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				out = append(out, block[i])
+			}
+		}
+	}
+	for i := 0; i < l.ecPerBlock; i++ {
+		for _, block := range ecBlocks {
+			out = append(out, block[i])
+		}
+	}
+	return out
+}
+
+// drawCodewords places codewords in the zigzag order of two-module columns,
+// starting at the bottom right. Leftover modules stay light as remainder bits.
+func (c *Code) drawCodewords(codewords []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < c.Size; vert++ {
+			y := vert
+			if upward {
+				y = c.Size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if c.isFunction(x, y) || i >= len(codewords)*8 {
+					continue
+				}
+				c.set(x, y, codewords[i/8]>>(7-i%8)&1 == 1)
+				i++
+			}
+		}
+	}
+}
+
+// applyMask flips data modules selected by mask; applying it twice undoes it.
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.isFunction(x, y) {
+				continue
+			}
+			var flip bool
+			switch mask {
+			case 0:
+				flip = (x+y)%2 == 0
+			case 1:
+				flip = y%2 == 0
+			case 2:
+				flip = x%3 == 0
+			case 3:
+				flip = (x+y)%3 == 0
+			case 4:
+				flip = (x/3+y/2)%2 == 0
+			case 5:
+				flip = x*y%2+x*y%3 == 0
+			case 6:
+				flip = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				flip = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if flip {
+				i := y*c.Size + x
+				c.modules[i] = !c.modules[i]
+			}
+		}
+	}
+}
+
+var finderLike = [2][11]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+// penalty scores the symbol with the four mask evaluation rules; lower is
+// easier for scanners to read.
+func (c *Code) penalty() int {
+	penalty := 0
+	for _, horizontal := range []bool{true, false} {
+		at := func(line, i int) bool {
+			if horizontal {
+				return c.Dark(i, line)
+			}
+			return c.Dark(line, i)
+		}
+		for line := 0; line < c.Size; line++ {
+			run := 1
+			for i := 1; i <= c.Size; i++ {
+				if i < c.Size && at(line, i) == at(line, i-1) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					penalty += 3 + run - 5
+				}
+				run = 1
+			}
+			for i := 0; i+11 <= c.Size; i++ {
+				for _, pattern := range finderLike {
+					matched := true
+					for k, dark := range pattern {
+						if at(line, i+k) != dark {
+							matched = false
+							break
+						}
+					}
+					if matched {
+						penalty += 40
+					}
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.Dark(x, y) {
+				dark++
+			}
+			if x+1 < c.Size && y+1 < c.Size {
+				d := c.Dark(x, y)
+				if d == c.Dark(x+1, y) && d == c.Dark(x, y+1) && d == c.Dark(x+1, y+1) {
+					penalty += 3
+				}
+			}
+		}
+	}
+	total := c.Size * c.Size
+	deviation := abs(dark*20-total*10) / total
+	penalty += deviation * 10
+	return penalty
+}
+
+type bitWriter struct {
+	bits []bool
+}
+
+func (w *bitWriter) write(value uint, n int) {
+	for i := n - 1; i >= 0; i-- {
+		w.bits = append(w.bits, value>>i&1 == 1)
+	}
+}
+
+func (w *bitWriter) len() int {
+	return len(w.bits)
+}
+
+func (w *bitWriter) bytes() []byte {
+	out := make([]byte, (len(w.bits)+7)/8)
+	for i, bit := range w.bits {
+		if bit {
+			out[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return out
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,277 @@
+package qr
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+func TestRSRemainder_HelloWorld1M_MatchesReference(t *testing.T) {
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+
+	ec := rsRemainder(data, rsDivisor(10))
+
+	assert.Equal(t, []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}, ec)
+}
+
+func TestFormatInfo_MatchesReferenceTable(t *testing.T) {
+	tests := []struct {
+		level    Level
+		mask     int
+		expected uint
+	}{
+		{Low, 0, 0b111011111000100},
+		{Medium, 0, 0b101010000010010},
+		{Quartile, 0, 0b011010101011111},
+		{High, 0, 0b001011010001001},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, formatInfo(tt.level, tt.mask), "level %d mask %d", tt.level, tt.mask)
+	}
+}
+
+func TestVersionInfo_MatchesReferenceTable(t *testing.T) {
+	assert.Equal(t, uint(0b000111110010010100), versionInfo(7))
+	assert.Equal(t, uint(0b001000010110111100), versionInfo(8))
+}
+
+func TestBlockLayouts_FillEachVersion(t *testing.T) {
+	for version := 1; version <= MaxVersion; version++ {
+		size := version*4 + 17
+		c := &Code{Version: version, Size: size, modules: make([]bool, size*size), function: make([]bool, size*size)}
+		c.drawFunctionPatterns()
+		dataModules := 0
+		for _, function := range c.function {
+			if !function {
+				dataModules++
+			}
+		}
+		for level := Low; level <= High; level++ {
+			l := blockLayouts[version-1][level]
+			blocks := l.groups[0].blocks + l.groups[1].blocks
+			assert.Equal(t, dataModules/8, l.dataCodewords()+blocks*l.ecPerBlock, "version %d level %d", version, level)
+		}
+	}
+}
+
+func TestEncode_PicksSmallestVersion(t *testing.T) {
+	tests := []struct {
+		length  int
+		level   Level
+		version int
+	}{
+		{1, Medium, 1},
+		{14, Medium, 1},
+		{15, Medium, 2},
+		{84, Medium, 5},
+		{85, Medium, 6},
+		{271, Low, 10},
+	}
+	for _, tt := range tests {
+		code, err := Encode([]byte(strings.Repeat("a", tt.length)), tt.level)
+
+		assert.NoError(t, err)
+		assert.Equal(t, tt.version, code.Version, "%d bytes at level %d", tt.length, tt.level)
+		assert.Equal(t, tt.version*4+17, code.Size)
+	}
+}
+
+func TestEncode_TooLong_ReturnsError(t *testing.T) {
+	_, err := Encode([]byte(strings.Repeat("a", 272)), Low)
+
+	assert.ErrorIs(t, err, ErrDataTooLong)
+}
+
+func TestEncode_RoundTripsThroughDecoder(t *testing.T) {
+	inputs := []string{
+		"",
+		"HELLO WORLD",
+		"http://192.168.1.20:8080/pair?token=3q2-7wAAAAAAAAAAAAAAAA",
+		strings.Repeat("sway remote ", 15),
+	}
+	for _, input := range inputs {
+		for level := Low; level <= High; level++ {
+			code, err := Encode([]byte(input), level)
+			if err != nil {
+				continue
+			}
+
+			decoded := decode(t, code)
+
+			assert.Equal(t, input, decoded, "version %d level %d mask %d", code.Version, level, code.Mask)
+		}
+	}
+}
+
+func TestHalfBlocks_MatchesGolden(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		level Level
+	}{
+		{"hello", "HELLO WORLD", Medium},
+		{"pair_url", "http://192.168.1.20:8080/pair?token=3q2-7wAAAAAAAAAAAAAAAA", Medium},
+		{"version7", strings.Repeat("x", 115), Medium},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := Encode([]byte(tt.data), tt.level)
+			assert.NoError(t, err)
+
+			rendered := code.HalfBlocks(QuietZone)
+
+			golden := filepath.Join("testdata", tt.name+".golden")
+			if *update {
+				assert.NoError(t, os.WriteFile(golden, []byte(rendered), 0644))
+			}
+			expected, err := os.ReadFile(golden)
+			assert.NoError(t, err)
+			assert.Equal(t, string(expected), rendered)
+		})
+	}
+}
+
+func TestHalfBlocks_QuietZoneIsLight(t *testing.T) {
+	code, _ := Encode([]byte("x"), Low)
+
+	lines := strings.Split(strings.TrimSuffix(code.HalfBlocks(2), "\n"), "\n")
+
+	assert.Len(t, lines, (code.Size+4+1)/2)
+	assert.Equal(t, strings.Repeat("█", code.Size+4), lines[0], "first line should be all quiet zone")
+	for _, line := range lines[:len(lines)-1] {
+		assert.True(t, strings.HasPrefix(line, "██"), "each line should start with the quiet zone")
+	}
+	assert.Equal(t, strings.Repeat("▀", code.Size+4), lines[len(lines)-1], "odd final row should only fill the top half")
+}
+
+// decode reads a code back the way a scanner would: format info from the
+// grid, unmask, de-interleave, check the error correction and parse the
+// byte mode segment.
+func decode(t *testing.T, code *Code) string {
+	t.Helper()
+	reference := &Code{Version: code.Version, Size: code.Size, modules: make([]bool, len(code.modules)), function: make([]bool, len(code.modules))}
+	reference.drawFunctionPatterns()
+
+	var format uint
+	for i := 0; i < 8; i++ {
+		if code.Dark(code.Size-1-i, 8) {
+			format |= 1 << i
+		}
+	}
+	for i := 8; i < 15; i++ {
+		if code.Dark(8, code.Size-15+i) {
+			format |= 1 << i
+		}
+	}
+	level, mask := Level(-1), -1
+	for l := Low; l <= High; l++ {
+		for m := 0; m < 8; m++ {
+			if formatInfo(l, m) == format {
+				level, mask = l, m
+			}
+		}
+	}
+	if !assert.NotEqual(t, -1, mask, "format info should be readable") {
+		return ""
+	}
+
+	unmasked := &Code{Version: code.Version, Size: code.Size, modules: append([]bool(nil), code.modules...), function: reference.function}
+	unmasked.applyMask(mask)
+
+	var bits []bool
+	for right := code.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < code.Size; vert++ {
+			y := vert
+			if (right+1)&2 == 0 {
+				y = code.Size - 1 - vert
+			}
+			for x := right; x > right-2; x-- {
+				if !reference.isFunction(x, y) {
+					bits = append(bits, unmasked.Dark(x, y))
+				}
+			}
+		}
+	}
+	raw := make([]byte, len(bits)/8)
+	for i := range raw {
+		for j := 0; j < 8; j++ {
+			if bits[i*8+j] {
+				raw[i] |= 1 << (7 - j)
+			}
+		}
+	}
+
+	l := blockLayouts[code.Version-1][level]
+	var blocks [][]byte
+	for _, group := range l.groups {
+		for i := 0; i < group.blocks; i++ {
+			blocks = append(blocks, make([]byte, 0, group.dataCodewords+l.ecPerBlock))
+		}
+	}
+	pos := 0
+	for i := 0; i < l.groups[0].dataCodewords+1; i++ {
+		for b := range blocks {
+			dataLen := l.groups[0].dataCodewords
+			if b >= l.groups[0].blocks {
+				dataLen = l.groups[1].dataCodewords
+			}
+			if i < dataLen {
+				blocks[b] = append(blocks[b], raw[pos])
+				pos++
+			}
+		}
+	}
+	for i := 0; i < l.ecPerBlock; i++ {
+		for b := range blocks {
+			blocks[b] = append(blocks[b], raw[pos])
+			pos++
+		}
+	}
+
+	var data []byte
+	for _, block := range blocks {
+		root := byte(1)
+		for i := 0; i < l.ecPerBlock; i++ {
+			var syndrome byte
+			for _, b := range block {
+				syndrome = gfMultiply(syndrome, root) ^ b
+			}
+			assert.Zero(t, syndrome, "syndrome %d should be zero", i)
+			root = gfMultiply(root, 0x02)
+		}
+		data = append(data, block[:len(block)-l.ecPerBlock]...)
+	}
+
+	var r bitReader
+	r.data = data
+	assert.Equal(t, uint(0b0100), r.read(4), "should use byte mode")
+	length := int(r.read(countBits(code.Version)))
+	out := make([]byte, length)
+	for i := range out {
+		out[i] = byte(r.read(8))
+	}
+	return string(out)
+}
+
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *bitReader) read(n int) uint {
+	var v uint
+	for i := 0; i < n; i++ {
+		v = v<<1 | uint(r.data[r.pos/8]>>(7-r.pos%8)&1)
+		r.pos++
+	}
+	return v
+}
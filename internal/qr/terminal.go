@@ -0,0 +1,32 @@
+package qr
+
+import "strings"
+
+const QuietZone = 4
+
+// HalfBlocks renders the code two module rows per line using Unicode half
+// blocks, with a quiet zone of the given width. Light modules are drawn as
+// blocks so the code reads correctly on the usual light-on-dark terminal.
+func (c *Code) HalfBlocks(quiet int) string {
+	var b strings.Builder
+	for y := -quiet; y < c.Size+quiet; y += 2 {
+		for x := -quiet; x < c.Size+quiet; x++ {
+			top, bottom := !c.Dark(x, y), !c.Dark(x, y+1)
+			if y+1 >= c.Size+quiet {
+				bottom = false
+			}
+			switch {
+			case top && bottom:
+				b.WriteRune('█')
+			case top:
+				b.WriteRune('▀')
+			case bottom:
+				b.WriteRune('▄')
+			default:
+				b.WriteRune(' ')
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
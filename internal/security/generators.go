@@ -0,0 +1,63 @@
+package security
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+const (
+	// Excludes 0/O and 1/I so codes read off a terminal can't be mistyped.
+	// 32 symbols means each random byte maps onto the alphabet without bias.
+	ShortCodeAlphabet      = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+	DefaultShortCodeLength = 6
+	apiKeyBytes            = 32
+	// Pairing tokens only live as long as a pairing code, so 128 bits is
+	// plenty and keeps the pairing URL short enough for a small QR code.
+	pairingTokenBytes = 16
+)
+
+func GenerateShortCode() (string, error) {
+	return generateShortCode(rand.Reader, DefaultShortCodeLength)
+}
+
+func NewShortCodeGenerator(length int) func() (string, error) {
+	return func() (string, error) {
+		return generateShortCode(rand.Reader, length)
+	}
+}
+
+func GenerateAPIKey() (string, error) {
+	return generateAPIKey(rand.Reader)
+}
+
+func GeneratePairingToken() (string, error) {
+	return generateToken(rand.Reader, pairingTokenBytes, "pairing token")
+}
+
+func generateShortCode(r io.Reader, length int) (string, error) {
+	if length < 1 {
+		return "", fmt.Errorf("invalid pairing code length %d", length)
+	}
+	bytes := make([]byte, length)
+	if _, err := io.ReadFull(r, bytes); err != nil {
+		return "", fmt.Errorf("failed to generate pairing code: %w", err)
+	}
+	for i, b := range bytes {
+		bytes[i] = ShortCodeAlphabet[int(b)%len(ShortCodeAlphabet)]
+	}
+	return string(bytes), nil
+}
+
+func generateAPIKey(r io.Reader) (string, error) {
+	return generateToken(r, apiKeyBytes, "api key")
+}
+
+func generateToken(r io.Reader, size int, what string) (string, error) {
+	bytes := make([]byte, size)
+	if _, err := io.ReadFull(r, bytes); err != nil {
+		return "", fmt.Errorf("failed to generate %s: %w", what, err)
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
@@ -1,102 +1,117 @@
 package security
 
 import (
+	"encoding/base64"
+	"errors"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestGenerateShortCode_Returns6Characters(t *testing.T) {
-	code := GenerateShortCode()
+	code, err := GenerateShortCode()
 
+	assert.NoError(t, err)
 	assert.Len(t, code, 6, "should return 6-character code")
 }
 
-func TestGenerateShortCode_ContainsOnlyValidHexCharacters(t *testing.T) {
-	code := GenerateShortCode()
+func TestGenerateShortCode_ContainsOnlyAlphabetCharacters(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		code, _ := GenerateShortCode()
+		for _, char := range code {
+			assert.Contains(t, ShortCodeAlphabet, string(char), "should only contain characters from the pairing alphabet")
+		}
+	}
+}
 
-	validChars := "0123456789ABCDEF"
-	for _, char := range code {
-		assert.Contains(t, validChars, string(char), "should only contain valid hex characters (0-9, A-F)")
+func TestShortCodeAlphabet_ExcludesAmbiguousCharacters(t *testing.T) {
+	for _, ambiguous := range []string{"0", "O", "1", "I"} {
+		assert.NotContains(t, ShortCodeAlphabet, ambiguous, "alphabet should not contain %s", ambiguous)
 	}
+	assert.Len(t, ShortCodeAlphabet, 32, "alphabet size should divide 256 so codes stay unbiased")
 }
 
 func TestGenerateShortCode_IsUppercase(t *testing.T) {
-	code := GenerateShortCode()
+	code, _ := GenerateShortCode()
 
-	for _, char := range code {
-		if char >= 'A' && char <= 'F' {
-			assert.True(t, char >= 'A' && char <= 'F', "letters should be uppercase")
-		}
-	}
-	assert.NotContains(t, code, "a", "should not contain lowercase a")
-	assert.NotContains(t, code, "b", "should not contain lowercase b")
-	assert.NotContains(t, code, "c", "should not contain lowercase c")
-	assert.NotContains(t, code, "d", "should not contain lowercase d")
-	assert.NotContains(t, code, "e", "should not contain lowercase e")
-	assert.NotContains(t, code, "f", "should not contain lowercase f")
+	assert.Equal(t, strings.ToUpper(code), code, "letters should be uppercase")
 }
 
 func TestGenerateShortCode_GeneratesDifferentCodes(t *testing.T) {
 	codes := make(map[string]bool)
 
 	for i := 0; i < 100; i++ {
-		code := GenerateShortCode()
+		code, _ := GenerateShortCode()
 		codes[code] = true
 	}
 
 	assert.Greater(t, len(codes), 95, "should generate different codes (at least 95% unique in 100 attempts)")
 }
 
-func TestGenerateShortCode_NoInvalidCharacters(t *testing.T) {
-	invalidChars := []string{"G", "H", "J", "Z", "g", "h", "j", "z", "!", "@", " "}
+func TestNewShortCodeGenerator_ConfigurableLength(t *testing.T) {
+	for _, length := range []int{1, 4, 8, 12} {
+		code, err := NewShortCodeGenerator(length)()
 
-	for i := 0; i < 20; i++ {
-		code := GenerateShortCode()
-		for _, invalid := range invalidChars {
-			assert.NotContains(t, code, invalid, "should not contain invalid character: %s", invalid)
-		}
+		assert.NoError(t, err)
+		assert.Len(t, code, length)
 	}
 }
 
-func TestGenerateAPIKey_Returns12Characters(t *testing.T) {
-	key := GenerateAPIKey()
+func TestNewShortCodeGenerator_InvalidLength_ReturnsError(t *testing.T) {
+	_, err := NewShortCodeGenerator(0)()
 
-	assert.Len(t, key, 12, "should return 12-character key")
+	assert.ErrorContains(t, err, "invalid pairing code length 0")
 }
 
-func TestGenerateAPIKey_ContainsOnlyValidHexCharacters(t *testing.T) {
-	key := GenerateAPIKey()
+func TestGenerateShortCode_RandomSourceFails_ReturnsError(t *testing.T) {
+	code, err := generateShortCode(iotest.ErrReader(errors.New("entropy exhausted")), 6)
 
-	validChars := "0123456789abcdef"
-	for _, char := range key {
-		assert.Contains(t, validChars, string(char), "should only contain valid lowercase hex characters (0-9, a-f)")
-	}
+	assert.ErrorContains(t, err, "entropy exhausted")
+	assert.Empty(t, code)
 }
 
-func TestGenerateAPIKey_IsLowercase(t *testing.T) {
-	key := GenerateAPIKey()
+func TestGenerateAPIKey_Is256BitBase64URL(t *testing.T) {
+	key, err := GenerateAPIKey()
+	assert.NoError(t, err)
 
-	for _, char := range key {
-		if char >= 'a' && char <= 'f' {
-			assert.True(t, char >= 'a' && char <= 'f', "letters should be lowercase")
-		}
-	}
-	assert.NotContains(t, key, "A", "should not contain uppercase A")
-	assert.NotContains(t, key, "B", "should not contain uppercase B")
-	assert.NotContains(t, key, "C", "should not contain uppercase C")
-	assert.NotContains(t, key, "D", "should not contain uppercase D")
-	assert.NotContains(t, key, "E", "should not contain uppercase E")
-	assert.NotContains(t, key, "F", "should not contain uppercase F")
-}
+	decoded, err := base64.RawURLEncoding.DecodeString(key)
 
-func TestGenerateAPIKey_NoInvalidCharacters(t *testing.T) {
-	invalidChars := []string{"G", "H", "J", "Z", "g", "h", "j", "z", "!", "@", " "}
+	assert.NoError(t, err, "key should be unpadded base64url")
+	assert.Len(t, decoded, 32, "key should carry 256 bits")
+	assert.Len(t, key, 43)
+}
 
+func TestGenerateAPIKey_IsCookieSafe(t *testing.T) {
 	for i := 0; i < 20; i++ {
-		key := GenerateAPIKey()
-		for _, invalid := range invalidChars {
-			assert.NotContains(t, key, invalid, "should not contain invalid character: %s", invalid)
-		}
+		key, _ := GenerateAPIKey()
+		assert.NotContains(t, key, "+", "key should not contain +")
+		assert.NotContains(t, key, "/", "key should not contain /")
+		assert.NotContains(t, key, "=", "key should not contain padding")
 	}
 }
+
+func TestGenerateAPIKey_GeneratesDifferentKeys(t *testing.T) {
+	first, _ := GenerateAPIKey()
+	second, _ := GenerateAPIKey()
+
+	assert.NotEqual(t, first, second)
+}
+
+func TestGenerateAPIKey_RandomSourceFails_ReturnsError(t *testing.T) {
+	key, err := generateAPIKey(iotest.ErrReader(errors.New("entropy exhausted")))
+
+	assert.ErrorContains(t, err, "failed to generate api key")
+	assert.Empty(t, key)
+}
+
+func TestGeneratePairingToken_Is128BitBase64URL(t *testing.T) {
+	token, err := GeneratePairingToken()
+	assert.NoError(t, err)
+
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+
+	assert.NoError(t, err, "token should be URL-safe base64")
+	assert.Len(t, decoded, 16, "token should carry 128 bits of entropy")
+}
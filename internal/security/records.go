@@ -0,0 +1,151 @@
+package security
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	apiKeysBucketName = "apiKeys"
+	metaBucketName    = "meta"
+	keyFormatName     = "keyFormat"
+	hmacKeyFormat     = "hmac-sha256"
+	keyRecordVersion  = 2
+	// scopedRecordVersion is the first record version that stores scopes.
+	scopedRecordVersion = 2
+)
+
+// unscopedKeyScopes is what keys stored before scopes existed are given:
+// media only, like a newly paired device, rather than full access nobody
+// chose to grant them.
+var unscopedKeyScopes = []string{ScopeMedia}
+
+type keyRecord struct {
+	Version    int       `json:"version"`
+	Type       KeyType   `json:"type,omitempty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	DeviceName string    `json:"device_name,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	PairedAt   time.Time `json:"paired_at"`
+	LastSeen   time.Time `json:"last_seen"`
+	RemoteIP   string    `json:"remote_ip,omitempty"`
+	Scopes     []string  `json:"scopes,omitempty"`
+}
+
+func encodeKeyRecord(key *APIKey) ([]byte, error) {
+	return json.Marshal(keyRecord{
+		Version:    keyRecordVersion,
+		Type:       keyType(key.Type),
+		ExpiresAt:  key.TTL,
+		DeviceName: key.DeviceName,
+		UserAgent:  key.UserAgent,
+		PairedAt:   key.PairedAt,
+		LastSeen:   key.LastSeen,
+		RemoteIP:   key.RemoteIP,
+		Scopes:     key.Scopes,
+	})
+}
+
+func decodeKeyRecord(hash string, data []byte) (*APIKey, error) {
+	if isLegacyKeyRecord(data) {
+		var expiresAt time.Time
+		if err := expiresAt.UnmarshalBinary(data); err != nil {
+			return nil, err
+		}
+		return &APIKey{ID: keyID(hash), Type: KeyTypeSession, TTL: expiresAt, Scopes: slices.Clone(unscopedKeyScopes)}, nil
+	}
+
+	var record keyRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	if record.Version > keyRecordVersion {
+		return nil, fmt.Errorf("unsupported api key record version %d", record.Version)
+	}
+	if record.Version < scopedRecordVersion && len(record.Scopes) == 0 {
+		record.Scopes = slices.Clone(unscopedKeyScopes)
+	}
+	return &APIKey{
+		ID:         keyID(hash),
+		Type:       keyType(record.Type),
+		TTL:        record.ExpiresAt,
+		DeviceName: record.DeviceName,
+		UserAgent:  record.UserAgent,
+		PairedAt:   record.PairedAt,
+		LastSeen:   record.LastSeen,
+		RemoteIP:   record.RemoteIP,
+		Scopes:     record.Scopes,
+	}, nil
+}
+
+// keyType treats keys saved before types were recorded as sessions.
+func keyType(t KeyType) KeyType {
+	if t == "" {
+		return KeyTypeSession
+	}
+	return t
+}
+
+// Keys stored before records were versioned hold only the expiry as
+// time.Time binary, which never starts with a JSON object brace.
+func isLegacyKeyRecord(data []byte) bool {
+	return len(data) == 0 || data[0] != '{'
+}
+
+type storedKey struct {
+	name []byte
+	data []byte
+}
+
+// Older databases hold plaintext keys and/or legacy time-only records;
+// both are rewritten in place so lookups only ever deal with hashed,
+// versioned entries.
+func migrateKeyRecords(db *bolt.DB, hashKey func(string) string) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists([]byte(metaBucketName))
+		if err != nil {
+			return err
+		}
+		hashed := string(meta.Get([]byte(keyFormatName))) == hmacKeyFormat
+
+		if b := tx.Bucket([]byte(apiKeysBucketName)); b != nil {
+			var pending []storedKey
+			b.ForEach(func(name, data []byte) error {
+				if !hashed || isLegacyKeyRecord(data) {
+					pending = append(pending, storedKey{
+						name: append([]byte(nil), name...),
+						data: append([]byte(nil), data...),
+					})
+				}
+				return nil
+			})
+
+			for _, entry := range pending {
+				key, err := decodeKeyRecord(string(entry.name), entry.data)
+				if err != nil {
+					return fmt.Errorf("key %s: %w", keyID(string(entry.name)), err)
+				}
+				data, err := encodeKeyRecord(key)
+				if err != nil {
+					return err
+				}
+				name := entry.name
+				if !hashed {
+					if err := b.Delete(name); err != nil {
+						return err
+					}
+					name = []byte(hashKey(string(entry.name)))
+				}
+				if err := b.Put(name, data); err != nil {
+					return err
+				}
+			}
+		}
+
+		return meta.Put([]byte(keyFormatName), []byte(hmacKeyFormat))
+	})
+}
@@ -0,0 +1,44 @@
+package security
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Scopes limit what a paired device or token may control. Admin grants
+// every other scope as well as managing devices and tokens. There is no
+// system scope yet because nothing touches networking or power; add one
+// alongside the first route that does, so it never grants nothing.
+const (
+	ScopeMedia   = "media"
+	ScopeInput   = "input"
+	ScopeWindows = "windows"
+	ScopeAdmin   = "admin"
+)
+
+var AllScopes = []string{ScopeMedia, ScopeInput, ScopeWindows, ScopeAdmin}
+
+func ValidScope(scope string) bool {
+	return slices.Contains(AllScopes, scope)
+}
+
+// ParseScopes accepts repeated and/or comma separated scope lists and
+// returns the known scopes sorted with duplicates removed.
+func ParseScopes(values ...string) ([]string, error) {
+	var scopes []string
+	for _, value := range values {
+		for _, scope := range strings.Fields(strings.ReplaceAll(value, ",", " ")) {
+			if !ValidScope(scope) {
+				return nil, fmt.Errorf("unknown scope %q", scope)
+			}
+			scopes = append(scopes, scope)
+		}
+	}
+	slices.Sort(scopes)
+	return slices.Compact(scopes), nil
+}
+
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, ScopeAdmin) || slices.Contains(k.Scopes, scope)
+}
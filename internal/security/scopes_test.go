@@ -0,0 +1,89 @@
+package security
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseScopes_RepeatedAndCommaSeparated_SortedUnique(t *testing.T) {
+	scopes, err := ParseScopes("media,input", "media", " windows ")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"input", "media", "windows"}, scopes)
+}
+
+func TestParseScopes_UnknownScope_ReturnsError(t *testing.T) {
+	_, err := ParseScopes("media", "network")
+
+	assert.ErrorContains(t, err, `unknown scope "network"`)
+}
+
+func TestParseScopes_Empty_ReturnsNoScopes(t *testing.T) {
+	scopes, err := ParseScopes("", " , ")
+
+	assert.NoError(t, err)
+	assert.Empty(t, scopes)
+}
+
+func TestHasScope(t *testing.T) {
+	tests := []struct {
+		name     string
+		scopes   []string
+		scope    string
+		expected bool
+	}{
+		{"granted", []string{ScopeMedia}, ScopeMedia, true},
+		{"not granted", []string{ScopeMedia}, ScopeWindows, false},
+		{"admin grants everything", []string{ScopeAdmin}, ScopeInput, true},
+		{"no scopes", nil, ScopeMedia, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := &APIKey{Scopes: tt.scopes}
+
+			assert.Equal(t, tt.expected, key.HasScope(tt.scope))
+		})
+	}
+}
+
+func TestGetAPIKey_RecordFromBeforeScopes_GetsMedia(t *testing.T) {
+	keyStore, db := createTestKeyStore(t)
+	putRawKey(t, db, keyStore.hashKey("old-phone"), []byte(`{"version":1,"expires_at":"2099-01-01T00:00:00Z"}`))
+
+	key, err := keyStore.GetAPIKey("old-phone")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{ScopeMedia}, key.Scopes, "devices paired before scopes existed should not get full access")
+}
+
+func TestGetAPIKey_ScopedRecordWithoutScopes_HasNoAccess(t *testing.T) {
+	keyStore, db := createTestKeyStore(t)
+	putRawKey(t, db, keyStore.hashKey("locked-down"), []byte(`{"version":2,"expires_at":"2099-01-01T00:00:00Z"}`))
+
+	key, err := keyStore.GetAPIKey("locked-down")
+
+	assert.NoError(t, err)
+	assert.Empty(t, key.Scopes)
+}
+
+func TestStoreAPIKey_NewKey_GetsGivenScopes(t *testing.T) {
+	keyStore, _ := createTestKeyStore(t)
+
+	assert.NoError(t, keyStore.StoreAPIKey("phone", time.Now().Add(time.Hour), []string{ScopeInput, ScopeMedia}))
+	key, err := keyStore.GetAPIKey("phone")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{ScopeInput, ScopeMedia}, key.Scopes)
+}
+
+func TestSaveAPIKey_RoundTripsScopes(t *testing.T) {
+	keyStore, _ := createTestKeyStore(t)
+
+	assert.NoError(t, keyStore.SaveAPIKey(&APIKey{Key: "guest", Scopes: []string{ScopeMedia}}))
+	key, err := keyStore.GetAPIKey("guest")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{ScopeMedia}, key.Scopes)
+}
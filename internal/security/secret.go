@@ -0,0 +1,102 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	secretsBucketName = "secrets"
+	hmacSecretName    = "apiKeyHMAC"
+	secretSize        = 32
+	keyIDLength       = 16
+)
+
+func (k *KeyStore) hashKey(apiKey string) string {
+	mac := hmac.New(sha256.New, k.secret)
+	mac.Write([]byte(apiKey))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func keyID(hash string) string {
+	if len(hash) <= keyIDLength {
+		return hash
+	}
+	return hash[:keyIDLength]
+}
+
+func loadSecret(db *bolt.DB, path string) ([]byte, error) {
+	if path != "" {
+		return loadSecretFile(path)
+	}
+	return loadSecretBucket(db)
+}
+
+func loadSecretFile(path string) ([]byte, error) {
+	secret, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		secret, err = newSecret()
+		if err != nil {
+			return nil, err
+		}
+		if err := writeSecretFile(path, secret); err != nil {
+			return nil, err
+		}
+		return secret, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(secret) != secretSize {
+		return nil, fmt.Errorf("secret file %s has %d bytes, expected %d", path, len(secret), secretSize)
+	}
+	return secret, nil
+}
+
+func writeSecretFile(path string, secret []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(secret); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func loadSecretBucket(db *bolt.DB) ([]byte, error) {
+	var secret []byte
+	err := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(secretsBucketName))
+		if err != nil {
+			return err
+		}
+		if stored := b.Get([]byte(hmacSecretName)); stored != nil {
+			secret = append([]byte(nil), stored...)
+			return nil
+		}
+		secret, err = newSecret()
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(hmacSecretName), secret)
+	})
+	return secret, err
+}
+
+func newSecret() ([]byte, error) {
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
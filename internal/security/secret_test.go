@@ -0,0 +1,135 @@
+package security
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+)
+
+func rawKeyNames(db *bolt.DB) []string {
+	var names []string
+	db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(apiKeysBucketName)); b != nil {
+			b.ForEach(func(name, _ []byte) error {
+				names = append(names, string(name))
+				return nil
+			})
+		}
+		return nil
+	})
+	return names
+}
+
+func TestStoreAPIKey_StoresKeyedHashNotPlaintext(t *testing.T) {
+	keyStore, db := createTestKeyStore(t)
+
+	assert.NoError(t, keyStore.StoreAPIKey("plaintext-cookie", time.Now().Add(time.Hour), []string{ScopeMedia}))
+
+	names := rawKeyNames(db)
+	assert.Len(t, names, 1)
+	assert.NotContains(t, names[0], "plaintext-cookie", "raw api key should not be written to bbolt")
+	assert.Len(t, names[0], 64, "keys should be stored as hex HMAC-SHA256")
+	assert.True(t, keyStore.ValidateAPIKey("plaintext-cookie"), "lookup should hash the presented key")
+}
+
+func TestNewKeyStore_PlaintextKeys_RehashedOnStartup(t *testing.T) {
+	db := openTestDB(t)
+	putRawKey(t, db, "json-key", []byte(`{"version":1,"expires_at":"2999-01-01T00:00:00Z","device_name":"Phone"}`))
+	legacy, _ := time.Now().Add(time.Hour).MarshalBinary()
+	putRawKey(t, db, "legacy-key", legacy)
+
+	keyStore, err := NewKeyStore(db)
+	assert.NoError(t, err)
+
+	names := rawKeyNames(db)
+	assert.ElementsMatch(t, []string{keyStore.hashKey("json-key"), keyStore.hashKey("legacy-key")}, names)
+	stored, err := keyStore.GetAPIKey("json-key")
+	assert.NoError(t, err)
+	assert.Equal(t, "Phone", stored.DeviceName, "metadata should survive rehashing")
+	assert.True(t, keyStore.ValidateAPIKey("legacy-key"))
+}
+
+func TestNewKeyStore_Reopened_DoesNotRehashAgain(t *testing.T) {
+	db := openTestDB(t)
+	putRawKey(t, db, "phone-key", []byte(`{"version":1,"expires_at":"2999-01-01T00:00:00Z"}`))
+	first, err := NewKeyStore(db)
+	assert.NoError(t, err)
+
+	second, err := NewKeyStore(db)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{first.hashKey("phone-key")}, rawKeyNames(db))
+	assert.True(t, second.ValidateAPIKey("phone-key"), "key should stay valid across restarts")
+}
+
+func TestNewKeyStore_SecretStoredInSeparateBucket(t *testing.T) {
+	db := openTestDB(t)
+	first, _ := NewKeyStore(db)
+	first.StoreAPIKey("phone-key", time.Now().Add(time.Hour), []string{ScopeMedia})
+
+	second, err := NewKeyStore(db)
+
+	assert.NoError(t, err)
+	assert.Equal(t, first.secret, second.secret, "the secret should be persisted")
+	assert.Len(t, second.secret, secretSize)
+	assert.True(t, second.ValidateAPIKey("phone-key"))
+}
+
+func TestNewKeyStore_SecretFile_CreatedWithOwnerOnlyPermissions(t *testing.T) {
+	secretPath := filepath.Join(t.TempDir(), "apikeys.secret")
+
+	keyStore, err := NewKeyStore(openTestDB(t), WithSecretFile(secretPath))
+
+	assert.NoError(t, err)
+	info, err := os.Stat(secretPath)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	contents, _ := os.ReadFile(secretPath)
+	assert.Equal(t, keyStore.secret, contents)
+}
+
+func TestNewKeyStore_SecretFile_ReusedAcrossDatabases(t *testing.T) {
+	secretPath := filepath.Join(t.TempDir(), "apikeys.secret")
+	first, _ := NewKeyStore(openTestDB(t), WithSecretFile(secretPath))
+
+	second, err := NewKeyStore(openTestDB(t), WithSecretFile(secretPath))
+
+	assert.NoError(t, err)
+	assert.Equal(t, first.hashKey("phone-key"), second.hashKey("phone-key"))
+}
+
+func TestNewKeyStore_SecretFileWrongSize_ReturnsError(t *testing.T) {
+	secretPath := filepath.Join(t.TempDir(), "apikeys.secret")
+	os.WriteFile(secretPath, []byte("too short"), 0600)
+
+	_, err := NewKeyStore(openTestDB(t), WithSecretFile(secretPath))
+
+	assert.ErrorContains(t, err, "failed to load api key secret")
+}
+
+func TestValidateAPIKey_DifferentSecret_RejectsCopiedDatabase(t *testing.T) {
+	db := openTestDB(t)
+	original, _ := NewKeyStore(db, WithSecretFile(filepath.Join(t.TempDir(), "original.secret")))
+	original.StoreAPIKey("phone-key", time.Now().Add(time.Hour), []string{ScopeMedia})
+
+	attacker, err := NewKeyStore(db, WithSecretFile(filepath.Join(t.TempDir(), "other.secret")))
+
+	assert.NoError(t, err)
+	assert.False(t, attacker.ValidateAPIKey("phone-key"), "a copied database without the secret should not validate keys")
+	for _, name := range rawKeyNames(db) {
+		assert.False(t, strings.Contains(name, "phone-key"))
+	}
+}
+
+func TestSaveAPIKey_MissingKey_ReturnsError(t *testing.T) {
+	keyStore, _ := createTestKeyStore(t)
+
+	err := keyStore.SaveAPIKey(&APIKey{DeviceName: "Listed device"})
+
+	assert.Error(t, err, "keys without their raw value cannot be hashed")
+}
@@ -1,109 +1,239 @@
 package security
 
 import (
-	"crypto/rand"
-	"encoding/hex"
 	"errors"
-	"strings"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"sync"
 	"time"
 
 	bolt "go.etcd.io/bbolt"
+
+	"github.com/phasecurve/sway_rm/internal/clock"
 )
 
-const apiKeysBucketName = "apiKeys"
+var ErrKeyNotFound = errors.New("api key not in store")
+
+// KeyType tells browser sessions created by pairing apart from tokens
+// minted for scripts. Sessions slide their expiry while in use; tokens keep
+// the expiry they were minted with.
+type KeyType string
+
+const (
+	KeyTypeSession KeyType = "session"
+	KeyTypeToken   KeyType = "token"
+)
 
 type APIKey struct {
-	Key string
-	TTL time.Time
+	ID         string
+	Key        string
+	Type       KeyType
+	TTL        time.Time
+	DeviceName string
+	UserAgent  string
+	PairedAt   time.Time
+	LastSeen   time.Time
+	RemoteIP   string
+	Scopes     []string
 }
 
 type KeyStorer interface {
 	GetAPIKey(apiKey string) (*APIKey, error)
-	ValidateAPIKey(apiKey string) bool
-	StoreAPIKey(apiKey string, expiresAt time.Time) error
+	StoreAPIKey(apiKey string, expiresAt time.Time, scopes []string) error
+	SaveAPIKey(key *APIKey) error
+	ListAPIKeys() ([]*APIKey, error)
+	DeleteAPIKey(id string) error
 }
 
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+type KeyStoreOption func(*KeyStore)
+
 type KeyStore struct {
-	db *bolt.DB
+	db            *bolt.DB
+	clock         clock.Clock
+	logger        Logger
+	sweepInterval time.Duration
+	secretFile    string
+	secret        []byte
+	mu            sync.Mutex
+	stats         SweepStats
+	started       bool
+	done          chan struct{}
+	stopped       chan struct{}
 }
 
-func (k *KeyStore) GetAPIKey(apiKey string) (*APIKey, error) {
-	var expiresAt time.Time
+func WithClock(c clock.Clock) KeyStoreOption {
+	return func(k *KeyStore) {
+		k.clock = c
+	}
+}
+
+func WithSweepInterval(interval time.Duration) KeyStoreOption {
+	return func(k *KeyStore) {
+		k.sweepInterval = interval
+	}
+}
 
+func WithSecretFile(path string) KeyStoreOption {
+	return func(k *KeyStore) {
+		k.secretFile = path
+	}
+}
+
+func WithLogger(logger Logger) KeyStoreOption {
+	return func(k *KeyStore) {
+		k.logger = logger
+	}
+}
+
+func NewKeyStore(db *bolt.DB, opts ...KeyStoreOption) (*KeyStore, error) {
+	k := &KeyStore{
+		db:            db,
+		clock:         clock.Real{},
+		logger:        log.Default(),
+		sweepInterval: defaultSweepInterval,
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(k)
+	}
+
+	secret, err := loadSecret(db, k.secretFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load api key secret: %w", err)
+	}
+	k.secret = secret
+	if err := migrateKeyRecords(db, k.hashKey); err != nil {
+		return nil, fmt.Errorf("failed to migrate api keys: %w", err)
+	}
+	return k, nil
+}
+
+func (k *KeyStore) GetAPIKey(apiKey string) (*APIKey, error) {
+	var key *APIKey
 	if err := k.db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(apiKeysBucketName))
-		if b == nil {
-			return nil
-		}
-		expiryBytes := b.Get([]byte(apiKey))
-		if expiryBytes == nil {
-			return nil
-		}
+		var err error
+		key, err = getKeyRecord(tx, k.hashKey(apiKey))
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	key.Key = apiKey
+	return key, nil
+}
 
-		if err := expiresAt.UnmarshalBinary(expiryBytes); err != nil {
+// ValidateAPIKey reports whether a key is stored and hasn't expired.
+func (k *KeyStore) ValidateAPIKey(apiKey string) bool {
+	key, err := k.GetAPIKey(apiKey)
+	if err != nil {
+		return false
+	}
+	return k.clock.Now().Before(key.TTL)
+}
+
+// StoreAPIKey sets a key's expiry and scopes, creating the key if needed
+// and keeping the rest of an existing key's metadata.
+func (k *KeyStore) StoreAPIKey(apiKey string, expiresAt time.Time, scopes []string) error {
+	hash := k.hashKey(apiKey)
+	return k.db.Update(func(tx *bolt.Tx) error {
+		key, err := getKeyRecord(tx, hash)
+		if errors.Is(err, ErrKeyNotFound) {
+			key = &APIKey{PairedAt: k.clock.Now()}
+		} else if err != nil {
 			return err
 		}
-		return nil
-	}); err != nil {
-		return nil, errors.New("api key not in store")
-	}
-	return &APIKey{Key: apiKey, TTL: expiresAt}, nil
+		key.TTL = expiresAt
+		key.Scopes = slices.Clone(scopes)
+		return putKeyRecord(tx, hash, key)
+	})
 }
 
-func NewKeyStore(db *bolt.DB) *KeyStore {
-	return &KeyStore{db: db}
+// SaveAPIKey stores key under its hash and sets key.ID to the ID it's
+// listed and deleted by.
+func (k *KeyStore) SaveAPIKey(key *APIKey) error {
+	if key.Key == "" {
+		return errors.New("api key has no value")
+	}
+	hash := k.hashKey(key.Key)
+	if err := k.db.Update(func(tx *bolt.Tx) error {
+		return putKeyRecord(tx, hash, key)
+	}); err != nil {
+		return err
+	}
+	key.ID = keyID(hash)
+	return nil
 }
 
-func (k *KeyStore) ValidateAPIKey(apiKey string) bool {
-	var isValid bool
+func (k *KeyStore) ListAPIKeys() ([]*APIKey, error) {
+	var keys []*APIKey
 	if err := k.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(apiKeysBucketName))
 		if b == nil {
 			return nil
 		}
-		expiryBytes := b.Get([]byte(apiKey))
-		if expiryBytes == nil {
+		return b.ForEach(func(hash, data []byte) error {
+			key, err := decodeKeyRecord(string(hash), data)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, key)
 			return nil
-		}
-
-		var expiresAt time.Time
-		if err := expiresAt.UnmarshalBinary(expiryBytes); err != nil {
-			return err
-		}
-
-		isValid = time.Now().Before(expiresAt)
-		return nil
+		})
 	}); err != nil {
-		return false
+		return nil, err
 	}
-
-	return isValid
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].PairedAt.Before(keys[j].PairedAt)
+	})
+	return keys, nil
 }
 
-func (k *KeyStore) StoreAPIKey(apiKey string, expiresAt time.Time) error {
+func (k *KeyStore) DeleteAPIKey(id string) error {
 	return k.db.Update(func(tx *bolt.Tx) error {
-		b, err := tx.CreateBucketIfNotExists([]byte(apiKeysBucketName))
-		if err != nil {
-			return err
+		b := tx.Bucket([]byte(apiKeysBucketName))
+		if b == nil {
+			return ErrKeyNotFound
 		}
-		expiresAtBin, err := expiresAt.MarshalBinary()
-		if err != nil {
-			return err
+		var match []byte
+		b.ForEach(func(hash, _ []byte) error {
+			if keyID(string(hash)) == id {
+				match = append([]byte(nil), hash...)
+			}
+			return nil
+		})
+		if match == nil {
+			return ErrKeyNotFound
 		}
-		return b.Put([]byte(apiKey), expiresAtBin)
+		return b.Delete(match)
 	})
 }
 
-func GenerateShortCode() string {
-	bytes := make([]byte, 3)
-	rand.Read(bytes)
-	shortCode := hex.EncodeToString(bytes)
-	return strings.ToUpper(shortCode)
+func getKeyRecord(tx *bolt.Tx, hash string) (*APIKey, error) {
+	b := tx.Bucket([]byte(apiKeysBucketName))
+	if b == nil {
+		return nil, ErrKeyNotFound
+	}
+	data := b.Get([]byte(hash))
+	if data == nil {
+		return nil, ErrKeyNotFound
+	}
+	return decodeKeyRecord(hash, data)
 }
 
-func GenerateAPIKey() string {
-	bytes := make([]byte, 6)
-	rand.Read(bytes)
-	apiKey := hex.EncodeToString(bytes)
-	return strings.ToLower(apiKey)
+func putKeyRecord(tx *bolt.Tx, hash string, key *APIKey) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(apiKeysBucketName))
+	if err != nil {
+		return err
+	}
+	data, err := encodeKeyRecord(key)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(hash), data)
 }
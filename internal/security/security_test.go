@@ -16,11 +16,12 @@ func TestValidateAPIKey_ValidKey_ReturnsTrue(t *testing.T) {
 	assert.NoError(t, err)
 	defer db.Close()
 
-	keyStore := NewKeyStore(db)
+	keyStore, err := NewKeyStore(db)
+	assert.NoError(t, err)
 
 	apiKey := "test-api-key-123"
 	expiresAt := time.Now().Add(1 * time.Hour)
-	err = keyStore.StoreAPIKey(apiKey, expiresAt)
+	err = keyStore.StoreAPIKey(apiKey, expiresAt, []string{ScopeMedia})
 	assert.NoError(t, err)
 
 	valid := keyStore.ValidateAPIKey(apiKey)
@@ -35,9 +36,225 @@ func TestValidateAPIKey_NonExistentKey_ReturnsFalse(t *testing.T) {
 	assert.NoError(t, err)
 	defer db.Close()
 
-	keyStore := NewKeyStore(db)
+	keyStore, err := NewKeyStore(db)
+	assert.NoError(t, err)
 
 	valid := keyStore.ValidateAPIKey("non-existent-key")
 
 	assert.False(t, valid, "non-existent API key should return false")
 }
+
+func createTestKeyStore(t *testing.T) (*KeyStore, *bolt.DB) {
+	db := openTestDB(t)
+	keyStore, err := NewKeyStore(db)
+	assert.NoError(t, err)
+	return keyStore, db
+}
+
+func TestSaveAPIKey_RoundTripsDeviceMetadata(t *testing.T) {
+	keyStore, _ := createTestKeyStore(t)
+	pairedAt := time.Date(2025, 3, 1, 20, 0, 0, 0, time.UTC)
+	key := &APIKey{
+		Key:        "phone-key",
+		TTL:        pairedAt.Add(time.Hour),
+		DeviceName: "Living room phone",
+		UserAgent:  "Mozilla/5.0 (Android 14)",
+		PairedAt:   pairedAt,
+		LastSeen:   pairedAt.Add(10 * time.Minute),
+		RemoteIP:   "192.168.1.20",
+		Scopes:     []string{"media", "input"},
+	}
+
+	assert.NoError(t, keyStore.SaveAPIKey(key))
+	stored, err := keyStore.GetAPIKey("phone-key")
+
+	assert.NoError(t, err)
+	assert.True(t, key.TTL.Equal(stored.TTL), "expiry should round trip")
+	assert.True(t, key.PairedAt.Equal(stored.PairedAt), "paired-at should round trip")
+	assert.True(t, key.LastSeen.Equal(stored.LastSeen), "last-seen should round trip")
+	assert.Equal(t, key.DeviceName, stored.DeviceName)
+	assert.Equal(t, key.UserAgent, stored.UserAgent)
+	assert.Equal(t, key.RemoteIP, stored.RemoteIP)
+	assert.Equal(t, key.Scopes, stored.Scopes)
+}
+
+func TestStoreAPIKey_ExistingKey_KeepsMetadata(t *testing.T) {
+	keyStore, _ := createTestKeyStore(t)
+	assert.NoError(t, keyStore.SaveAPIKey(&APIKey{Key: "phone-key", TTL: time.Now().Add(time.Hour), DeviceName: "Tablet"}))
+	newExpiry := time.Now().Add(2 * time.Hour)
+
+	assert.NoError(t, keyStore.StoreAPIKey("phone-key", newExpiry, []string{ScopeMedia}))
+	stored, err := keyStore.GetAPIKey("phone-key")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Tablet", stored.DeviceName, "updating the expiry should not drop device metadata")
+	assert.True(t, newExpiry.Equal(stored.TTL), "expiry should be updated")
+}
+
+func TestGetAPIKey_NonExistentKey_ReturnsError(t *testing.T) {
+	keyStore, _ := createTestKeyStore(t)
+
+	key, err := keyStore.GetAPIKey("missing")
+
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.Nil(t, key)
+}
+
+func openTestDB(t *testing.T) *bolt.DB {
+	dbPath := filepath.Join(t.TempDir(), "apiKeys.db")
+	db, err := bolt.Open(dbPath, 0600, nil)
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close()
+	})
+	return db
+}
+
+func putRawKey(t *testing.T, db *bolt.DB, name string, data []byte) {
+	assert.NoError(t, db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(apiKeysBucketName))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(name), data)
+	}))
+}
+
+func getRawKey(db *bolt.DB, name string) []byte {
+	var raw []byte
+	db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(apiKeysBucketName)); b != nil {
+			raw = append(raw, b.Get([]byte(name))...)
+		}
+		return nil
+	})
+	return raw
+}
+
+func TestNewKeyStore_LegacyTimeRecords_MigratedInPlace(t *testing.T) {
+	db := openTestDB(t)
+	expiresAt := time.Now().Add(time.Hour).Round(0)
+	legacy, _ := expiresAt.MarshalBinary()
+	putRawKey(t, db, "old-key", legacy)
+
+	keyStore, err := NewKeyStore(db)
+	assert.NoError(t, err)
+
+	raw := getRawKey(db, keyStore.hashKey("old-key"))
+	assert.False(t, isLegacyKeyRecord(raw), "legacy record should be rewritten in the versioned format")
+	assert.Contains(t, string(raw), `"version":2`)
+	assert.Contains(t, string(raw), `"scopes":["media"]`, "migrated keys should get the default scope, not full access")
+	stored, err := keyStore.GetAPIKey("old-key")
+	assert.NoError(t, err)
+	assert.True(t, expiresAt.Equal(stored.TTL), "migrated record should keep its expiry")
+	assert.True(t, keyStore.ValidateAPIKey("old-key"), "migrated key should still be valid")
+}
+
+func TestNewKeyStore_CorruptLegacyRecord_ReturnsError(t *testing.T) {
+	db := openTestDB(t)
+	putRawKey(t, db, "broken", []byte{0xff})
+
+	_, err := NewKeyStore(db)
+
+	assert.ErrorContains(t, err, "failed to migrate api keys")
+}
+
+func TestGetAPIKey_NewerRecordVersion_ReturnsError(t *testing.T) {
+	keyStore, db := createTestKeyStore(t)
+	putRawKey(t, db, keyStore.hashKey("future"), []byte(`{"version":99}`))
+
+	_, err := keyStore.GetAPIKey("future")
+
+	assert.ErrorContains(t, err, "unsupported api key record version 99")
+	assert.False(t, keyStore.ValidateAPIKey("future"))
+}
+
+func TestListAPIKeys_ReturnsKeysOrderedByPairedAt(t *testing.T) {
+	keyStore, _ := createTestKeyStore(t)
+	now := time.Now()
+	assert.NoError(t, keyStore.SaveAPIKey(&APIKey{Key: "b-key", DeviceName: "Second", PairedAt: now}))
+	assert.NoError(t, keyStore.SaveAPIKey(&APIKey{Key: "a-key", DeviceName: "Third", PairedAt: now.Add(time.Minute)}))
+	assert.NoError(t, keyStore.SaveAPIKey(&APIKey{Key: "c-key", DeviceName: "First", PairedAt: now.Add(-time.Minute)}))
+
+	keys, err := keyStore.ListAPIKeys()
+
+	assert.NoError(t, err)
+	var names []string
+	for _, key := range keys {
+		names = append(names, key.DeviceName)
+		assert.NotEmpty(t, key.ID, "listed keys should carry their device id")
+		assert.Empty(t, key.Key, "listed keys cannot recover the raw key")
+	}
+	assert.Equal(t, []string{"First", "Second", "Third"}, names)
+}
+
+func TestListAPIKeys_EmptyStore_ReturnsNoKeys(t *testing.T) {
+	keyStore, _ := createTestKeyStore(t)
+
+	keys, err := keyStore.ListAPIKeys()
+
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestDeleteAPIKey_KnownID_RemovesOnlyThatKey(t *testing.T) {
+	keyStore, _ := createTestKeyStore(t)
+	assert.NoError(t, keyStore.StoreAPIKey("keep-me", time.Now().Add(time.Hour), []string{ScopeMedia}))
+	assert.NoError(t, keyStore.StoreAPIKey("revoke-me", time.Now().Add(time.Hour), []string{ScopeMedia}))
+
+	revoked, _ := keyStore.GetAPIKey("revoke-me")
+
+	err := keyStore.DeleteAPIKey(revoked.ID)
+
+	assert.NoError(t, err)
+	assert.False(t, keyStore.ValidateAPIKey("revoke-me"), "revoked key should no longer validate")
+	assert.True(t, keyStore.ValidateAPIKey("keep-me"), "other keys should be untouched")
+}
+
+func TestDeleteAPIKey_UnknownID_ReturnsNotFound(t *testing.T) {
+	keyStore, _ := createTestKeyStore(t)
+	assert.NoError(t, keyStore.StoreAPIKey("some-key", time.Now().Add(time.Hour), []string{ScopeMedia}))
+
+	err := keyStore.DeleteAPIKey("does-not-exist")
+
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestSaveAPIKey_SetsListedID(t *testing.T) {
+	keyStore, _ := createTestKeyStore(t)
+	key := &APIKey{Key: "new-key", TTL: time.Now().Add(time.Hour)}
+
+	assert.NoError(t, keyStore.SaveAPIKey(key))
+	keys, err := keyStore.ListAPIKeys()
+
+	assert.NoError(t, err)
+	if assert.Len(t, keys, 1) {
+		assert.Equal(t, keys[0].ID, key.ID)
+	}
+	assert.NotEmpty(t, key.ID)
+}
+
+func TestSaveAPIKey_Token_KeepsTypeMarker(t *testing.T) {
+	keyStore, db := createTestKeyStore(t)
+
+	assert.NoError(t, keyStore.SaveAPIKey(&APIKey{Key: "script-token", Type: KeyTypeToken, TTL: time.Now().Add(time.Hour)}))
+
+	stored, err := keyStore.GetAPIKey("script-token")
+	assert.NoError(t, err)
+	assert.Equal(t, KeyTypeToken, stored.Type)
+	assert.Contains(t, string(getRawKey(db, keyStore.hashKey("script-token"))), `"type":"token"`)
+}
+
+func TestGetAPIKey_UntypedRecord_IsSession(t *testing.T) {
+	keyStore, db := createTestKeyStore(t)
+	putRawKey(t, db, keyStore.hashKey("old-session"), []byte(`{"version":1,"expires_at":"2099-01-01T00:00:00Z"}`))
+	assert.NoError(t, keyStore.StoreAPIKey("new-session", time.Now().Add(time.Hour), []string{ScopeMedia}))
+
+	old, err := keyStore.GetAPIKey("old-session")
+	assert.NoError(t, err)
+	created, err := keyStore.GetAPIKey("new-session")
+	assert.NoError(t, err)
+
+	assert.Equal(t, KeyTypeSession, old.Type, "records from before type markers should be sessions")
+	assert.Equal(t, KeyTypeSession, created.Type, "keys stored without a type should be sessions")
+}
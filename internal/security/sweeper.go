@@ -0,0 +1,102 @@
+package security
+
+import (
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const defaultSweepInterval = 10 * time.Minute
+
+type SweepStats struct {
+	Sweeps     int
+	Reaped     int
+	LastReaped int
+	LastSweep  time.Time
+}
+
+func (k *KeyStore) Start() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.started || k.sweepInterval <= 0 {
+		return
+	}
+	k.started = true
+	go k.run()
+}
+
+func (k *KeyStore) Close() {
+	k.mu.Lock()
+	select {
+	case <-k.done:
+		k.mu.Unlock()
+		return
+	default:
+	}
+	close(k.done)
+	started := k.started
+	k.mu.Unlock()
+	if started {
+		<-k.stopped
+	}
+}
+
+func (k *KeyStore) run() {
+	defer close(k.stopped)
+	ticker := time.NewTicker(k.sweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-k.done:
+			return
+		case <-ticker.C:
+			if _, err := k.Sweep(); err != nil {
+				k.logger.Printf("failed to sweep expired api keys: %v", err)
+			}
+		}
+	}
+}
+
+func (k *KeyStore) Sweep() (int, error) {
+	now := k.clock.Now()
+	reaped := 0
+	if err := k.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(apiKeysBucketName))
+		if b == nil {
+			return nil
+		}
+		var expired [][]byte
+		if err := b.ForEach(func(apiKey, data []byte) error {
+			key, err := decodeKeyRecord(string(apiKey), data)
+			if err == nil && !now.Before(key.TTL) {
+				expired = append(expired, apiKey)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, apiKey := range expired {
+			if err := b.Delete(apiKey); err != nil {
+				return err
+			}
+		}
+		reaped = len(expired)
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.stats.Sweeps++
+	k.stats.Reaped += reaped
+	k.stats.LastReaped = reaped
+	k.stats.LastSweep = now
+	return reaped, nil
+}
+
+func (k *KeyStore) SweepStats() SweepStats {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.stats
+}
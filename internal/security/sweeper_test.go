@@ -0,0 +1,169 @@
+package security
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/phasecurve/sway_rm/internal/clock/clocktest"
+)
+
+func createSweeperTestKeyStore(t *testing.T, opts ...KeyStoreOption) (*KeyStore, *clocktest.Fake, *bolt.DB) {
+	dbPath := filepath.Join(t.TempDir(), "apiKeys.db")
+	db, err := bolt.Open(dbPath, 0600, nil)
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close()
+	})
+	fakeClock := clocktest.NewFake(time.Date(2025, 3, 1, 20, 0, 0, 0, time.UTC))
+	keyStore, err := NewKeyStore(db, append([]KeyStoreOption{WithClock(fakeClock)}, opts...)...)
+	assert.NoError(t, err)
+	t.Cleanup(keyStore.Close)
+	return keyStore, fakeClock, db
+}
+
+func countStoredKeys(t *testing.T, db *bolt.DB) int {
+	count := 0
+	db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(apiKeysBucketName)); b != nil {
+			count = b.Stats().KeyN
+		}
+		return nil
+	})
+	return count
+}
+
+func TestSweep_DeletesOnlyExpiredKeys(t *testing.T) {
+	keyStore, fakeClock, db := createSweeperTestKeyStore(t)
+	now := fakeClock.Now()
+	keyStore.StoreAPIKey("expired-1", now.Add(-time.Hour), []string{ScopeMedia})
+	keyStore.StoreAPIKey("expired-2", now.Add(-time.Second), []string{ScopeMedia})
+	keyStore.StoreAPIKey("expires-now", now, []string{ScopeMedia})
+	keyStore.StoreAPIKey("valid", now.Add(time.Second), []string{ScopeMedia})
+
+	reaped, err := keyStore.Sweep()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, reaped, "keys expiring at or before now should be reaped")
+	assert.Equal(t, 1, countStoredKeys(t, db))
+	_, err = keyStore.GetAPIKey("valid")
+	assert.NoError(t, err, "unexpired key should survive the sweep")
+}
+
+func TestSweep_AdvancingClock_ReapsNewlyExpiredKeys(t *testing.T) {
+	keyStore, fakeClock, db := createSweeperTestKeyStore(t)
+	keyStore.StoreAPIKey("phone", fakeClock.Now().Add(time.Hour), []string{ScopeMedia})
+
+	reaped, _ := keyStore.Sweep()
+	assert.Equal(t, 0, reaped, "key should survive before its expiry")
+
+	fakeClock.Advance(time.Hour)
+	reaped, _ = keyStore.Sweep()
+
+	assert.Equal(t, 1, reaped, "key should be reaped once the clock passes its expiry")
+	assert.Equal(t, 0, countStoredKeys(t, db))
+}
+
+func TestSweep_RecordsStats(t *testing.T) {
+	keyStore, fakeClock, _ := createSweeperTestKeyStore(t)
+	keyStore.StoreAPIKey("a", fakeClock.Now().Add(-time.Minute), []string{ScopeMedia})
+	keyStore.StoreAPIKey("b", fakeClock.Now().Add(-time.Minute), []string{ScopeMedia})
+	keyStore.Sweep()
+	fakeClock.Advance(time.Minute)
+	keyStore.Sweep()
+
+	stats := keyStore.SweepStats()
+
+	assert.Equal(t, 2, stats.Sweeps)
+	assert.Equal(t, 2, stats.Reaped, "total reaped should accumulate across sweeps")
+	assert.Equal(t, 0, stats.LastReaped)
+	assert.True(t, fakeClock.Now().Equal(stats.LastSweep), "last sweep should use the injected clock")
+}
+
+func TestSweep_EmptyStore_ReapsNothing(t *testing.T) {
+	keyStore, _, _ := createSweeperTestKeyStore(t)
+
+	reaped, err := keyStore.Sweep()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, reaped)
+}
+
+func TestSweep_ClosedDatabase_ReturnsError(t *testing.T) {
+	keyStore, _, db := createSweeperTestKeyStore(t)
+	db.Close()
+
+	_, err := keyStore.Sweep()
+
+	assert.Error(t, err)
+	assert.Equal(t, 0, keyStore.SweepStats().Sweeps, "failed sweeps should not be counted")
+}
+
+func TestStart_SweepsPeriodically(t *testing.T) {
+	keyStore, fakeClock, db := createSweeperTestKeyStore(t, WithSweepInterval(10*time.Millisecond))
+	keyStore.StoreAPIKey("expired", fakeClock.Now().Add(-time.Minute), []string{ScopeMedia})
+
+	keyStore.Start()
+
+	assert.Eventually(t, func() bool { return countStoredKeys(t, db) == 0 }, time.Second, 5*time.Millisecond, "background sweeper should reap expired keys")
+}
+
+func TestClose_StopsSweeper(t *testing.T) {
+	keyStore, fakeClock, _ := createSweeperTestKeyStore(t, WithSweepInterval(5*time.Millisecond))
+	keyStore.Start()
+	assert.Eventually(t, func() bool { return keyStore.SweepStats().Sweeps > 0 }, time.Second, 5*time.Millisecond)
+
+	keyStore.Close()
+	sweeps := keyStore.SweepStats().Sweeps
+	keyStore.StoreAPIKey("expired", fakeClock.Now().Add(-time.Minute), []string{ScopeMedia})
+	time.Sleep(30 * time.Millisecond)
+
+	assert.Equal(t, sweeps, keyStore.SweepStats().Sweeps, "no sweeps should run after Close")
+	keyStore.Close()
+}
+
+type recordingLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *recordingLogger) Printf(format string, v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func (l *recordingLogger) Lines() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.lines...)
+}
+
+func TestStart_SweepFails_LogsError(t *testing.T) {
+	logger := &recordingLogger{}
+	keyStore, _, db := createSweeperTestKeyStore(t,
+		WithSweepInterval(5*time.Millisecond),
+		WithLogger(logger),
+	)
+	db.Close()
+
+	keyStore.Start()
+
+	assert.Eventually(t, func() bool { return len(logger.Lines()) > 0 }, time.Second, 5*time.Millisecond)
+	assert.Contains(t, logger.Lines()[0], "failed to sweep expired api keys")
+	assert.Equal(t, 0, keyStore.SweepStats().Sweeps)
+}
+
+func TestValidateAPIKey_UsesInjectedClock(t *testing.T) {
+	keyStore, fakeClock, _ := createSweeperTestKeyStore(t)
+	keyStore.StoreAPIKey("phone", fakeClock.Now().Add(time.Minute), []string{ScopeMedia})
+
+	assert.True(t, keyStore.ValidateAPIKey("phone"))
+	fakeClock.Advance(time.Minute)
+	assert.False(t, keyStore.ValidateAPIKey("phone"), "key should be invalid exactly at its expiry")
+}
@@ -0,0 +1,213 @@
+package sway
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	magic          = "i3-ipc"
+	headerLength   = len(magic) + 8
+	socketEnvName  = "SWAYSOCK"
+	defaultTimeout = 2 * time.Second
+)
+
+type MessageType uint32
+
+const (
+	MessageRunCommand    MessageType = 0
+	MessageGetWorkspaces MessageType = 1
+	MessageSubscribe     MessageType = 2
+	MessageGetOutputs    MessageType = 3
+	MessageGetTree       MessageType = 4
+	MessageGetVersion    MessageType = 7
+	MessageGetSeats      MessageType = 101
+)
+
+var ErrNoSocket = errors.New("sway socket path not set")
+
+type Controller interface {
+	RunCommand(command string) ([]CommandResult, error)
+	GetWorkspaces() ([]Workspace, error)
+	GetTree() (*Node, error)
+	GetOutputs() ([]Output, error)
+	GetVersion() (*Version, error)
+	GetSeats() ([]Seat, error)
+}
+
+type Client struct {
+	socketPath string
+	timeout    time.Duration
+	mu         sync.Mutex
+	conn       net.Conn
+}
+
+func NewClient(socketPath string) *Client {
+	return &Client{socketPath: socketPath, timeout: defaultTimeout}
+}
+
+func SocketPath() string {
+	return os.Getenv(socketEnvName)
+}
+
+func Quote(argument string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(argument)
+	return `"` + escaped + `"`
+}
+
+func (c *Client) RunCommand(command string) ([]CommandResult, error) {
+	var results []CommandResult
+	if err := c.call(MessageRunCommand, []byte(command), &results); err != nil {
+		return nil, err
+	}
+	var failures []string
+	for _, result := range results {
+		if !result.Success {
+			failures = append(failures, result.Error)
+		}
+	}
+	if len(failures) > 0 {
+		return results, fmt.Errorf("sway command %q failed: %s", command, strings.Join(failures, "; "))
+	}
+	return results, nil
+}
+
+func (c *Client) GetWorkspaces() ([]Workspace, error) {
+	var workspaces []Workspace
+	if err := c.call(MessageGetWorkspaces, nil, &workspaces); err != nil {
+		return nil, err
+	}
+	return workspaces, nil
+}
+
+func (c *Client) GetTree() (*Node, error) {
+	var root Node
+	if err := c.call(MessageGetTree, nil, &root); err != nil {
+		return nil, err
+	}
+	return &root, nil
+}
+
+func (c *Client) GetOutputs() ([]Output, error) {
+	var outputs []Output
+	if err := c.call(MessageGetOutputs, nil, &outputs); err != nil {
+		return nil, err
+	}
+	return outputs, nil
+}
+
+func (c *Client) GetVersion() (*Version, error) {
+	var version Version
+	if err := c.call(MessageGetVersion, nil, &version); err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+func (c *Client) GetSeats() ([]Seat, error) {
+	var seats []Seat
+	if err := c.call(MessageGetSeats, nil, &seats); err != nil {
+		return nil, err
+	}
+	return seats, nil
+}
+
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+func (c *Client) call(messageType MessageType, payload []byte, reply any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		conn, err := dial(c.socketPath)
+		if err != nil {
+			return err
+		}
+		c.conn = conn
+	}
+
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	raw, err := roundTrip(c.conn, messageType, payload)
+	if err != nil {
+		c.conn.Close()
+		c.conn = nil
+		return err
+	}
+
+	if err := json.Unmarshal(raw, reply); err != nil {
+		return fmt.Errorf("failed to decode sway reply: %w", err)
+	}
+	return nil
+}
+
+func dial(socketPath string) (net.Conn, error) {
+	if socketPath == "" {
+		return nil, ErrNoSocket
+	}
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to sway: %w", err)
+	}
+	return conn, nil
+}
+
+func roundTrip(conn net.Conn, messageType MessageType, payload []byte) ([]byte, error) {
+	if err := writeMessage(conn, messageType, payload); err != nil {
+		return nil, err
+	}
+	replyType, raw, err := readMessage(conn)
+	if err != nil {
+		return nil, err
+	}
+	if replyType != messageType {
+		return nil, fmt.Errorf("unexpected sway reply type %d for request %d", replyType, messageType)
+	}
+	return raw, nil
+}
+
+func writeMessage(w io.Writer, messageType MessageType, payload []byte) error {
+	message := make([]byte, headerLength+len(payload))
+	copy(message, magic)
+	binary.NativeEndian.PutUint32(message[len(magic):], uint32(len(payload)))
+	binary.NativeEndian.PutUint32(message[len(magic)+4:], uint32(messageType))
+	copy(message[headerLength:], payload)
+	if _, err := w.Write(message); err != nil {
+		return fmt.Errorf("failed to write sway message: %w", err)
+	}
+	return nil
+}
+
+func readMessage(r io.Reader) (MessageType, []byte, error) {
+	header := make([]byte, headerLength)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, fmt.Errorf("failed to read sway header: %w", err)
+	}
+	if string(header[:len(magic)]) != magic {
+		return 0, nil, fmt.Errorf("invalid sway magic %q", header[:len(magic)])
+	}
+	length := binary.NativeEndian.Uint32(header[len(magic):])
+	messageType := MessageType(binary.NativeEndian.Uint32(header[len(magic)+4:]))
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, fmt.Errorf("failed to read sway payload: %w", err)
+	}
+	return messageType, payload, nil
+}
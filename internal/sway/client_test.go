@@ -0,0 +1,161 @@
+package sway
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phasecurve/sway_rm/internal/sway/swaytest"
+)
+
+func createTestClient(t *testing.T) (*Client, *swaytest.Server) {
+	fake := swaytest.NewServer(t)
+	client := NewClient(fake.SocketPath)
+	t.Cleanup(func() {
+		client.Close()
+	})
+	return client, fake
+}
+
+func TestWriteMessage_EncodesMagicLengthAndType(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := writeMessage(&buf, MessageGetTree, []byte("{}"))
+
+	assert.NoError(t, err)
+	messageType, payload, err := readMessage(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, MessageGetTree, messageType, "type should round trip")
+	assert.Equal(t, "{}", string(payload), "payload should round trip")
+}
+
+func TestReadMessage_InvalidMagic_ReturnsError(t *testing.T) {
+	buf := bytes.NewBufferString("i4-ipc\x00\x00\x00\x00\x00\x00\x00\x00")
+
+	_, _, err := readMessage(buf)
+
+	assert.ErrorContains(t, err, "invalid sway magic")
+}
+
+func TestRunCommand_Success_SendsCommand(t *testing.T) {
+	client, fake := createTestClient(t)
+
+	results, err := client.RunCommand("workspace 2")
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, []string{"workspace 2"}, fake.Commands(), "command payload should reach sway")
+}
+
+func TestRunCommand_Failure_ReturnsError(t *testing.T) {
+	client, fake := createTestClient(t)
+	fake.SetReply(uint32(MessageRunCommand), `[{"success":false,"error":"Unknown command"}]`)
+
+	results, err := client.RunCommand("bogus")
+
+	assert.ErrorContains(t, err, "Unknown command")
+	assert.Len(t, results, 1, "results should still be returned on failure")
+}
+
+func TestGetWorkspaces_DecodesReply(t *testing.T) {
+	client, fake := createTestClient(t)
+	fake.SetReply(uint32(MessageGetWorkspaces), `[
+		{"num":1,"name":"1","visible":true,"focused":true,"urgent":false,"output":"eDP-1"},
+		{"num":2,"name":"2:web","visible":false,"focused":false,"urgent":true,"output":"HDMI-A-1"}
+	]`)
+
+	workspaces, err := client.GetWorkspaces()
+
+	assert.NoError(t, err)
+	assert.Len(t, workspaces, 2)
+	assert.True(t, workspaces[0].Focused, "first workspace should be focused")
+	assert.Equal(t, "2:web", workspaces[1].Name)
+	assert.True(t, workspaces[1].Urgent, "second workspace should be urgent")
+	assert.Equal(t, "HDMI-A-1", workspaces[1].Output)
+}
+
+func TestGetTree_DecodesNestedNodes(t *testing.T) {
+	client, fake := createTestClient(t)
+	fake.SetReply(uint32(MessageGetTree), `{"id":1,"type":"root","nodes":[
+		{"id":2,"type":"output","name":"eDP-1","nodes":[
+			{"id":3,"type":"con","name":"mpv","app_id":"mpv","focused":true}
+		]}
+	]}`)
+
+	tree, err := client.GetTree()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "root", tree.Type)
+	window := tree.Nodes[0].Nodes[0]
+	assert.Equal(t, int64(3), window.ID)
+	assert.Equal(t, "mpv", *window.AppID)
+	assert.True(t, window.Focused)
+}
+
+func TestGetOutputs_DecodesReply(t *testing.T) {
+	client, fake := createTestClient(t)
+	fake.SetReply(uint32(MessageGetOutputs), `[{"name":"eDP-1","active":true,"scale":1.5,"current_workspace":"1"}]`)
+
+	outputs, err := client.GetOutputs()
+
+	assert.NoError(t, err)
+	assert.Len(t, outputs, 1)
+	assert.Equal(t, 1.5, outputs[0].Scale)
+	assert.Equal(t, "1", outputs[0].CurrentWorkspace)
+}
+
+func TestGetVersion_DecodesReply(t *testing.T) {
+	client, fake := createTestClient(t)
+	fake.SetReply(uint32(MessageGetVersion), `{"major":1,"minor":10,"patch":0,"human_readable":"1.10"}`)
+
+	version, err := client.GetVersion()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, version.Major)
+	assert.Equal(t, 10, version.Minor)
+	assert.Equal(t, "1.10", version.HumanReadable)
+}
+
+func TestGetSeats_DecodesReply(t *testing.T) {
+	client, fake := createTestClient(t)
+	fake.SetReply(uint32(MessageGetSeats), `[{"name":"seat0","capabilities":3,"focus":7,"devices":[{"identifier":"1:1:kbd","type":"keyboard"}]}]`)
+
+	seats, err := client.GetSeats()
+
+	assert.NoError(t, err)
+	assert.Len(t, seats, 1)
+	assert.Equal(t, "seat0", seats[0].Name)
+	assert.Equal(t, "keyboard", seats[0].Devices[0].Type)
+}
+
+func TestClient_NoSocketPath_ReturnsErrNoSocket(t *testing.T) {
+	client := NewClient("")
+
+	_, err := client.GetVersion()
+
+	assert.ErrorIs(t, err, ErrNoSocket)
+}
+
+func TestClient_ServerRestarted_Reconnects(t *testing.T) {
+	client, fake := createTestClient(t)
+	_, err := client.GetWorkspaces()
+	assert.NoError(t, err)
+
+	fake.Close()
+	_, err = client.GetWorkspaces()
+	assert.Error(t, err, "call should fail while sway is down")
+
+	restarted := swaytest.NewServer(t)
+	client.socketPath = restarted.SocketPath
+	_, err = client.RunCommand("nop")
+
+	assert.NoError(t, err, "client should redial after a broken connection")
+	assert.Equal(t, []string{"nop"}, restarted.Commands())
+}
+
+func TestQuote_EscapesQuotesAndBackslashes(t *testing.T) {
+	assert.Equal(t, `"2:web"`, Quote("2:web"))
+	assert.Equal(t, `"say \"hi\""`, Quote(`say "hi"`))
+	assert.Equal(t, `"a\\b"`, Quote(`a\b`))
+}
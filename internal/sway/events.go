@@ -0,0 +1,311 @@
+package sway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const eventMask = 1 << 31
+
+type EventType uint32
+
+const (
+	EventWorkspace EventType = eventMask | 0
+	EventOutput    EventType = eventMask | 1
+	EventMode      EventType = eventMask | 2
+	EventWindow    EventType = eventMask | 3
+	EventBinding   EventType = eventMask | 5
+	EventShutdown  EventType = eventMask | 6
+)
+
+var eventNames = map[EventType]string{
+	EventWorkspace: "workspace",
+	EventOutput:    "output",
+	EventMode:      "mode",
+	EventWindow:    "window",
+	EventBinding:   "binding",
+	EventShutdown:  "shutdown",
+}
+
+const (
+	defaultRetryDelay    = 500 * time.Millisecond
+	maxRetryDelay        = 10 * time.Second
+	subscriberBufferSize = 16
+)
+
+type WorkspaceEvent struct {
+	Change  string `json:"change"`
+	Current *Node  `json:"current"`
+	Old     *Node  `json:"old"`
+}
+
+type WindowEvent struct {
+	Change    string `json:"change"`
+	Container Node   `json:"container"`
+}
+
+type OutputEvent struct {
+	Change string `json:"change"`
+}
+
+type ModeEvent struct {
+	Change      string `json:"change"`
+	PangoMarkup bool   `json:"pango_markup"`
+}
+
+type Binding struct {
+	Command        string   `json:"command"`
+	EventStateMask []string `json:"event_state_mask"`
+	InputCode      int      `json:"input_code"`
+	Symbol         *string  `json:"symbol"`
+	InputType      string   `json:"input_type"`
+}
+
+type BindingEvent struct {
+	Change  string  `json:"change"`
+	Binding Binding `json:"binding"`
+}
+
+type ShutdownEvent struct {
+	Change string `json:"change"`
+}
+
+type Event struct {
+	Type      EventType
+	Workspace *WorkspaceEvent
+	Window    *WindowEvent
+	Output    *OutputEvent
+	Mode      *ModeEvent
+	Binding   *BindingEvent
+	Shutdown  *ShutdownEvent
+}
+
+type EventSubscriber interface {
+	Subscribe() (<-chan Event, func())
+}
+
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+type EventStream struct {
+	socketPath  string
+	logger      Logger
+	retryDelay  time.Duration
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+	conn        net.Conn
+	started     bool
+	done        chan struct{}
+	stopped     chan struct{}
+}
+
+func NewEventStream(socketPath string, logger Logger) *EventStream {
+	return &EventStream{
+		socketPath:  socketPath,
+		logger:      logger,
+		retryDelay:  defaultRetryDelay,
+		subscribers: make(map[chan Event]struct{}),
+		done:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+}
+
+func (s *EventStream) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+	go s.run()
+}
+
+func (s *EventStream) Close() {
+	s.mu.Lock()
+	select {
+	case <-s.done:
+		s.mu.Unlock()
+		return
+	default:
+	}
+	close(s.done)
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	started := s.started
+	s.mu.Unlock()
+	if started {
+		<-s.stopped
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.subscribers {
+		delete(s.subscribers, ch)
+		close(ch)
+	}
+}
+
+func (s *EventStream) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBufferSize)
+	s.mu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if _, ok := s.subscribers[ch]; ok {
+				delete(s.subscribers, ch)
+				close(ch)
+			}
+		})
+	}
+	return ch, unsubscribe
+}
+
+func (s *EventStream) run() {
+	defer close(s.stopped)
+	delay := s.retryDelay
+	for {
+		connected, err := s.listen()
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+		if s.logger != nil {
+			s.logger.Printf("sway event stream disconnected: %v", err)
+		}
+		if connected {
+			delay = s.retryDelay
+		}
+
+		select {
+		case <-s.done:
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+func (s *EventStream) listen() (bool, error) {
+	conn, err := dial(s.socketPath)
+	if err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	select {
+	case <-s.done:
+		s.mu.Unlock()
+		conn.Close()
+		return false, nil
+	default:
+	}
+	s.conn = conn
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.conn = nil
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	if err := subscribe(conn); err != nil {
+		return false, err
+	}
+
+	for {
+		messageType, payload, err := readMessage(conn)
+		if err != nil {
+			return true, err
+		}
+		event, err := decodeEvent(EventType(messageType), payload)
+		if err != nil {
+			if s.logger != nil {
+				s.logger.Printf("failed to decode sway event: %v", err)
+			}
+			continue
+		}
+		s.publish(event)
+		if event.Type == EventShutdown {
+			return true, fmt.Errorf("sway is shutting down: %s", event.Shutdown.Change)
+		}
+	}
+}
+
+func (s *EventStream) publish(event Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+func subscribe(conn net.Conn) error {
+	names := make([]string, 0, len(eventNames))
+	for _, name := range eventNames {
+		names = append(names, name)
+	}
+	payload, err := json.Marshal(names)
+	if err != nil {
+		return err
+	}
+
+	conn.SetDeadline(time.Now().Add(defaultTimeout))
+	raw, err := roundTrip(conn, MessageSubscribe, payload)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Time{})
+
+	var reply CommandResult
+	if err := json.Unmarshal(raw, &reply); err != nil {
+		return fmt.Errorf("failed to decode subscribe reply: %w", err)
+	}
+	if !reply.Success {
+		return fmt.Errorf("sway rejected event subscription")
+	}
+	return nil
+}
+
+func decodeEvent(eventType EventType, payload []byte) (Event, error) {
+	event := Event{Type: eventType}
+	var target any
+	switch eventType {
+	case EventWorkspace:
+		event.Workspace = &WorkspaceEvent{}
+		target = event.Workspace
+	case EventWindow:
+		event.Window = &WindowEvent{}
+		target = event.Window
+	case EventOutput:
+		event.Output = &OutputEvent{}
+		target = event.Output
+	case EventMode:
+		event.Mode = &ModeEvent{}
+		target = event.Mode
+	case EventBinding:
+		event.Binding = &BindingEvent{}
+		target = event.Binding
+	case EventShutdown:
+		event.Shutdown = &ShutdownEvent{}
+		target = event.Shutdown
+	default:
+		return event, fmt.Errorf("unknown sway event type %#x", uint32(eventType))
+	}
+	if err := json.Unmarshal(payload, target); err != nil {
+		return event, err
+	}
+	return event, nil
+}
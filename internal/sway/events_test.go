@@ -0,0 +1,131 @@
+package sway
+
+import (
+	"bytes"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/phasecurve/sway_rm/internal/sway/swaytest"
+)
+
+func createTestEventStream(t *testing.T) (*EventStream, *swaytest.Server) {
+	fake := swaytest.NewServer(t)
+	stream := NewEventStream(fake.SocketPath, log.New(&bytes.Buffer{}, "", 0))
+	stream.retryDelay = 10 * time.Millisecond
+	t.Cleanup(stream.Close)
+	return stream, fake
+}
+
+func waitForSubscribers(t *testing.T, fake *swaytest.Server, count int) {
+	assert.Eventually(t, func() bool {
+		return fake.Subscribers() == count
+	}, time.Second, 5*time.Millisecond, "event stream should subscribe to sway")
+}
+
+func receiveEvent(t *testing.T, events <-chan Event) Event {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for sway event")
+		return Event{}
+	}
+}
+
+func TestDecodeEvent_Workspace(t *testing.T) {
+	event, err := decodeEvent(EventWorkspace, []byte(`{"change":"focus","current":{"id":5,"name":"2"},"old":{"id":4,"name":"1"}}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "focus", event.Workspace.Change)
+	assert.Equal(t, "2", event.Workspace.Current.Name)
+	assert.Equal(t, "1", event.Workspace.Old.Name)
+}
+
+func TestDecodeEvent_Window(t *testing.T) {
+	event, err := decodeEvent(EventWindow, []byte(`{"change":"title","container":{"id":9,"name":"new title","app_id":"firefox"}}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "title", event.Window.Change)
+	assert.Equal(t, int64(9), event.Window.Container.ID)
+	assert.Equal(t, "firefox", *event.Window.Container.AppID)
+}
+
+func TestDecodeEvent_OutputModeAndBinding(t *testing.T) {
+	output, err := decodeEvent(EventOutput, []byte(`{"change":"unspecified"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "unspecified", output.Output.Change)
+
+	mode, err := decodeEvent(EventMode, []byte(`{"change":"resize","pango_markup":false}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "resize", mode.Mode.Change)
+
+	binding, err := decodeEvent(EventBinding, []byte(`{"change":"run","binding":{"command":"workspace 1","event_state_mask":["Mod4"],"input_code":0,"symbol":"1","input_type":"keyboard"}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "workspace 1", binding.Binding.Binding.Command)
+	assert.Equal(t, []string{"Mod4"}, binding.Binding.Binding.EventStateMask)
+}
+
+func TestDecodeEvent_UnknownType_ReturnsError(t *testing.T) {
+	_, err := decodeEvent(EventType(eventMask|42), []byte(`{}`))
+
+	assert.ErrorContains(t, err, "unknown sway event type")
+}
+
+func TestEventStream_FansOutToAllSubscribers(t *testing.T) {
+	stream, fake := createTestEventStream(t)
+	first, unsubscribeFirst := stream.Subscribe()
+	defer unsubscribeFirst()
+	second, unsubscribeSecond := stream.Subscribe()
+	defer unsubscribeSecond()
+	stream.Start()
+	waitForSubscribers(t, fake, 1)
+
+	fake.Emit(uint32(EventWorkspace), `{"change":"focus","current":{"name":"3"}}`)
+
+	assert.Equal(t, "3", receiveEvent(t, first).Workspace.Current.Name, "first subscriber should receive event")
+	assert.Equal(t, "3", receiveEvent(t, second).Workspace.Current.Name, "second subscriber should receive event")
+	assert.Equal(t, 1, fake.Subscribers(), "subscribers should share a single sway connection")
+}
+
+func TestEventStream_Unsubscribe_ClosesChannel(t *testing.T) {
+	stream, _ := createTestEventStream(t)
+	events, unsubscribe := stream.Subscribe()
+
+	unsubscribe()
+	unsubscribe()
+
+	_, open := <-events
+	assert.False(t, open, "channel should be closed after unsubscribe")
+}
+
+func TestEventStream_SwayRestarts_Reconnects(t *testing.T) {
+	stream, fake := createTestEventStream(t)
+	events, unsubscribe := stream.Subscribe()
+	defer unsubscribe()
+	stream.Start()
+	waitForSubscribers(t, fake, 1)
+
+	fake.Emit(uint32(EventShutdown), `{"change":"exit"}`)
+	assert.Equal(t, EventShutdown, receiveEvent(t, events).Type, "shutdown should be forwarded")
+
+	assert.NoError(t, fake.Restart())
+	waitForSubscribers(t, fake, 1)
+	fake.Emit(uint32(EventWindow), `{"change":"new","container":{"id":12}}`)
+
+	assert.Equal(t, int64(12), receiveEvent(t, events).Window.Container.ID, "events should flow after reconnect")
+}
+
+func TestEventStream_Close_ClosesSubscriberChannels(t *testing.T) {
+	stream, fake := createTestEventStream(t)
+	events, _ := stream.Subscribe()
+	stream.Start()
+	waitForSubscribers(t, fake, 1)
+
+	stream.Close()
+
+	_, open := <-events
+	assert.False(t, open, "subscriber channels should be closed when the stream stops")
+}
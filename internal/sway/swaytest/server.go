@@ -0,0 +1,195 @@
+package swaytest
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+const magic = "i3-ipc"
+
+const (
+	runCommand = 0
+	subscribe  = 2
+	getTree    = 4
+)
+
+type fakeConn struct {
+	net.Conn
+	writeMu    sync.Mutex
+	subscribed bool
+}
+
+func (c *fakeConn) write(messageType uint32, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return writeMessage(c.Conn, messageType, payload)
+}
+
+type Server struct {
+	SocketPath string
+	listener   net.Listener
+	mu         sync.Mutex
+	replies    map[uint32]string
+	commands   []string
+	conns      []*fakeConn
+}
+
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "swaytest")
+	if err != nil {
+		t.Fatalf("failed to create socket dir: %v", err)
+	}
+	s := &Server{
+		SocketPath: filepath.Join(dir, "sway.sock"),
+		replies: map[uint32]string{
+			runCommand: `[{"success":true}]`,
+			subscribe:  `{"success":true}`,
+			getTree:    `{}`,
+		},
+	}
+	if err := s.listen(); err != nil {
+		t.Fatalf("failed to listen on fake sway socket: %v", err)
+	}
+	t.Cleanup(func() {
+		s.Close()
+		os.RemoveAll(dir)
+	})
+	return s
+}
+
+func (s *Server) SetReply(messageType uint32, payload string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replies[messageType] = payload
+}
+
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+func (s *Server) Subscribers() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, conn := range s.conns {
+		if conn.subscribed {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *Server) Emit(eventType uint32, payload string) {
+	s.mu.Lock()
+	var subscribers []*fakeConn
+	for _, conn := range s.conns {
+		if conn.subscribed {
+			subscribers = append(subscribers, conn)
+		}
+	}
+	s.mu.Unlock()
+	for _, conn := range subscribers {
+		conn.write(eventType, []byte(payload))
+	}
+}
+
+func (s *Server) Restart() error {
+	s.Close()
+	os.Remove(s.SocketPath)
+	return s.listen()
+}
+
+func (s *Server) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener != nil {
+		s.listener.Close()
+		s.listener = nil
+	}
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+func (s *Server) listen() error {
+	listener, err := net.Listen("unix", s.SocketPath)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+	go s.serve(listener)
+	return nil
+}
+
+func (s *Server) serve(listener net.Listener) {
+	for {
+		raw, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		conn := &fakeConn{Conn: raw}
+		s.mu.Lock()
+		s.conns = append(s.conns, conn)
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn *fakeConn) {
+	defer conn.Close()
+	for {
+		messageType, payload, err := readMessage(conn)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		switch messageType {
+		case runCommand:
+			s.commands = append(s.commands, string(payload))
+		case subscribe:
+			conn.subscribed = true
+		}
+		reply, ok := s.replies[messageType]
+		s.mu.Unlock()
+		if !ok {
+			reply = `[]`
+		}
+		if err := conn.write(messageType, []byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func writeMessage(w io.Writer, messageType uint32, payload []byte) error {
+	header := make([]byte, len(magic)+8)
+	copy(header, magic)
+	binary.NativeEndian.PutUint32(header[len(magic):], uint32(len(payload)))
+	binary.NativeEndian.PutUint32(header[len(magic)+4:], messageType)
+	_, err := w.Write(append(header, payload...))
+	return err
+}
+
+func readMessage(r io.Reader) (uint32, []byte, error) {
+	header := make([]byte, len(magic)+8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	length := binary.NativeEndian.Uint32(header[len(magic):])
+	messageType := binary.NativeEndian.Uint32(header[len(magic)+4:])
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return messageType, payload, nil
+}
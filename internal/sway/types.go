@@ -0,0 +1,131 @@
+package sway
+
+type Rect struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+type CommandResult struct {
+	Success    bool   `json:"success"`
+	ParseError bool   `json:"parse_error"`
+	Error      string `json:"error"`
+}
+
+type Workspace struct {
+	ID      int64  `json:"id"`
+	Num     int    `json:"num"`
+	Name    string `json:"name"`
+	Visible bool   `json:"visible"`
+	Focused bool   `json:"focused"`
+	Urgent  bool   `json:"urgent"`
+	Rect    Rect   `json:"rect"`
+	Output  string `json:"output"`
+}
+
+type WindowProperties struct {
+	Title        string `json:"title"`
+	Instance     string `json:"instance"`
+	Class        string `json:"class"`
+	WindowRole   string `json:"window_role"`
+	TransientFor *int64 `json:"transient_for"`
+}
+
+type Node struct {
+	ID                 int64             `json:"id"`
+	Name               string            `json:"name"`
+	Type               string            `json:"type"`
+	Border             string            `json:"border"`
+	CurrentBorderWidth int               `json:"current_border_width"`
+	Layout             string            `json:"layout"`
+	Orientation        string            `json:"orientation"`
+	Percent            *float64          `json:"percent"`
+	Rect               Rect              `json:"rect"`
+	WindowRect         Rect              `json:"window_rect"`
+	DecoRect           Rect              `json:"deco_rect"`
+	Geometry           Rect              `json:"geometry"`
+	Urgent             bool              `json:"urgent"`
+	Sticky             bool              `json:"sticky"`
+	Marks              []string          `json:"marks"`
+	Focused            bool              `json:"focused"`
+	Focus              []int64           `json:"focus"`
+	FullscreenMode     int               `json:"fullscreen_mode"`
+	Nodes              []*Node           `json:"nodes"`
+	FloatingNodes      []*Node           `json:"floating_nodes"`
+	Representation     string            `json:"representation"`
+	AppID              *string           `json:"app_id"`
+	PID                int               `json:"pid"`
+	Visible            *bool             `json:"visible"`
+	Shell              string            `json:"shell"`
+	Window             *int64            `json:"window"`
+	WindowProperties   *WindowProperties `json:"window_properties"`
+	Num                *int              `json:"num"`
+	Output             string            `json:"output"`
+}
+
+type OutputMode struct {
+	Width   int `json:"width"`
+	Height  int `json:"height"`
+	Refresh int `json:"refresh"`
+}
+
+type Output struct {
+	ID               int64        `json:"id"`
+	Name             string       `json:"name"`
+	Make             string       `json:"make"`
+	Model            string       `json:"model"`
+	Serial           string       `json:"serial"`
+	Active           bool         `json:"active"`
+	DPMS             bool         `json:"dpms"`
+	Power            bool         `json:"power"`
+	Primary          bool         `json:"primary"`
+	Scale            float64      `json:"scale"`
+	SubpixelHinting  string       `json:"subpixel_hinting"`
+	Transform        string       `json:"transform"`
+	CurrentWorkspace string       `json:"current_workspace"`
+	Modes            []OutputMode `json:"modes"`
+	CurrentMode      OutputMode   `json:"current_mode"`
+	Rect             Rect         `json:"rect"`
+}
+
+type Version struct {
+	Major                int    `json:"major"`
+	Minor                int    `json:"minor"`
+	Patch                int    `json:"patch"`
+	HumanReadable        string `json:"human_readable"`
+	LoadedConfigFileName string `json:"loaded_config_file_name"`
+}
+
+type InputDevice struct {
+	Identifier string `json:"identifier"`
+	Name       string `json:"name"`
+	Vendor     int    `json:"vendor"`
+	Product    int    `json:"product"`
+	Type       string `json:"type"`
+}
+
+type Seat struct {
+	Name         string        `json:"name"`
+	Capabilities int           `json:"capabilities"`
+	Focus        int64         `json:"focus"`
+	Devices      []InputDevice `json:"devices"`
+}
+
+func (n *Node) IsWindow() bool {
+	return (n.Type == "con" || n.Type == "floating_con") && (n.AppID != nil || n.Window != nil)
+}
+
+func (n *Node) AppName() string {
+	if n.AppID != nil && *n.AppID != "" {
+		return *n.AppID
+	}
+	if n.WindowProperties != nil {
+		return n.WindowProperties.Class
+	}
+	return ""
+}
+
+func (n *Node) Children() []*Node {
+	return append(append([]*Node(nil), n.Nodes...), n.FloatingNodes...)
+}
@@ -0,0 +1,50 @@
+package sway
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNode_IsWindow(t *testing.T) {
+	appID := "foot"
+	window := int64(42)
+	tests := []struct {
+		name     string
+		node     Node
+		expected bool
+	}{
+		{"wayland window", Node{Type: "con", AppID: &appID}, true},
+		{"xwayland window", Node{Type: "con", Window: &window}, true},
+		{"floating window", Node{Type: "floating_con", AppID: &appID}, true},
+		{"split container", Node{Type: "con"}, false},
+		{"workspace", Node{Type: "workspace"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.node.IsWindow())
+		})
+	}
+}
+
+func TestNode_AppName_FallsBackToClass(t *testing.T) {
+	appID := "mpv"
+	wayland := Node{AppID: &appID}
+	xwayland := Node{WindowProperties: &WindowProperties{Class: "Steam"}}
+
+	assert.Equal(t, "mpv", wayland.AppName())
+	assert.Equal(t, "Steam", xwayland.AppName())
+	assert.Equal(t, "", (&Node{}).AppName())
+}
+
+func TestNode_Children_IncludesFloatingNodes(t *testing.T) {
+	node := Node{
+		Nodes:         []*Node{{ID: 1}},
+		FloatingNodes: []*Node{{ID: 2}},
+	}
+
+	children := node.Children()
+
+	assert.Len(t, children, 2)
+	assert.Equal(t, int64(2), children[1].ID)
+}
@@ -1,5 +1,7 @@
 package internal
 
+import "time"
+
 type PairState string
 
 const (
@@ -7,3 +9,25 @@ const (
 	StatePaired   PairState = "paired"
 	StateExpired  PairState = "expired"
 )
+
+type PairRequestStatus string
+
+const (
+	RequestPending  PairRequestStatus = "pending"
+	RequestApproved PairRequestStatus = "approved"
+	RequestDenied   PairRequestStatus = "denied"
+)
+
+// PairRequest is a device waiting for the desktop user to let it pair.
+// CheckCode is shown on both screens so the right device gets approved.
+type PairRequest struct {
+	ID         string
+	CheckCode  string
+	DeviceName string
+	RemoteIP   string
+	UserAgent  string
+	Created    time.Time
+	Expiry     time.Time
+	Status     PairRequestStatus
+	Scopes     []string
+}